@@ -0,0 +1,208 @@
+// Package aabparser reads the metadata of Android App Bundles without bundletool,
+// by decoding the protobuf encoded manifest and resource table of the bundle's base module.
+package aabparser
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+
+	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
+	"github.com/bitrise-io/go-android/v2/metaparser/androidartifact"
+	"github.com/bitrise-io/go-android/v2/metaparser/androidsignature"
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+const (
+	manifestPath  = "base/manifest/AndroidManifest.xml"
+	resourcesPath = "base/resources.pb"
+
+	maxManifestSizeInBytes  = 10 * 1024 * 1024
+	maxResourcesSizeInBytes = 256 * 1024 * 1024
+)
+
+// ErrNotFound is returned when a file of the base module is missing from the bundle.
+var ErrNotFound = errors.New("file not found in the aab")
+
+// Manifest is the app information of the bundle's base module manifest.
+type Manifest struct {
+	PackageName      string
+	VersionCode      string
+	VersionName      string
+	MinSDKVersion    string
+	TargetSDKVersion string
+	// AppName is the application label, resolved from the resources if it is a reference.
+	AppName string
+	// Raw is the manifest in XML format.
+	Raw string
+}
+
+// Parser ...
+type Parser struct {
+	logger      log.Logger
+	fileManager fileutil.FileManager
+}
+
+// New ...
+func New(logger log.Logger, fileManager fileutil.FileManager) *Parser {
+	return &Parser{
+		logger:      logger,
+		fileManager: fileManager,
+	}
+}
+
+// ParseAABData returns the same metadata as the bundletool based androidparser.Parser.
+func (p *Parser) ParseAABData(pth string) (*androidparser.ArtifactMetadata, error) {
+	manifest, err := ReadManifest(pth)
+	if err != nil {
+		p.logger.Warnf("Failed to parse AAB info: %s", err)
+		return nil, err
+	}
+	p.logger.Debugf("Target SDK version of %s: %s", pth, manifest.TargetSDKVersion)
+
+	fileSize, err := p.fileManager.FileSizeInBytes(pth)
+	if err != nil {
+		p.logger.Warnf("Failed to get aab size, error: %s", err)
+	}
+
+	info := androidartifact.ParseArtifactPath(pth)
+
+	signature, err := androidsignature.ReadAABSignature(pth)
+	if err != nil {
+		p.logger.Warnf("Failed to get signature of `%s`: %s", pth, err)
+	}
+
+	return &androidparser.ArtifactMetadata{
+		AppInfo: androidartifact.Info{
+			AppName:           manifest.AppName,
+			PackageName:       manifest.PackageName,
+			VersionCode:       manifest.VersionCode,
+			VersionName:       manifest.VersionName,
+			MinSDKVersion:     manifest.MinSDKVersion,
+			RawPackageContent: manifest.Raw,
+		},
+		FileSizeBytes:  fileSize,
+		Module:         info.Module,
+		ProductFlavour: info.ProductFlavour,
+		BuildType:      info.BuildType,
+		SignedBy:       signature,
+	}, nil
+}
+
+// ReadManifest reads the manifest of the bundle's base module,
+// resource references of the version name and app name are resolved from the base module's resources.
+func ReadManifest(pth string) (Manifest, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to open aab: %w", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	manifestContent, err := readZipFile(&reader.Reader, manifestPath, maxManifestSizeInBytes)
+	if err != nil {
+		return Manifest{}, err
+	}
+	root, err := decodeXMLNode(manifestContent)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to decode %s: %w", manifestPath, err)
+	}
+	if root.name != "manifest" {
+		return Manifest{}, fmt.Errorf("unexpected root element in %s: %s", manifestPath, root.name)
+	}
+
+	manifest := Manifest{Raw: root.render()}
+	if attribute, ok := root.attribute("", "package"); ok {
+		manifest.PackageName = attribute.String()
+	}
+	if attribute, ok := root.attribute(androidNamespaceURI, "versionCode"); ok {
+		manifest.VersionCode = attribute.String()
+	}
+	if usesSDK, ok := root.child("uses-sdk"); ok {
+		if attribute, ok := usesSDK.attribute(androidNamespaceURI, "minSdkVersion"); ok {
+			manifest.MinSDKVersion = attribute.String()
+		}
+		if attribute, ok := usesSDK.attribute(androidNamespaceURI, "targetSdkVersion"); ok {
+			manifest.TargetSDKVersion = attribute.String()
+		}
+	}
+
+	var resources *resourceTable
+	resolve := func(attribute xmlAttribute) (string, error) {
+		ref, ok := attributeRef(attribute)
+		if !ok {
+			return attribute.String(), nil
+		}
+
+		if resources == nil {
+			content, err := readZipFile(&reader.Reader, resourcesPath, maxResourcesSizeInBytes)
+			if err != nil {
+				return "", err
+			}
+			table, err := decodeResourceTable(content)
+			if err != nil {
+				return "", fmt.Errorf("failed to decode %s: %w", resourcesPath, err)
+			}
+			resources = &table
+		}
+
+		return resources.resolve(ref)
+	}
+
+	if attribute, ok := root.attribute(androidNamespaceURI, "versionName"); ok {
+		if manifest.VersionName, err = resolve(attribute); err != nil {
+			return Manifest{}, fmt.Errorf("failed to resolve version name: %w", err)
+		}
+	}
+	if application, ok := root.child("application"); ok {
+		if attribute, ok := application.attribute(androidNamespaceURI, "label"); ok {
+			if manifest.AppName, err = resolve(attribute); err != nil {
+				return Manifest{}, fmt.Errorf("failed to resolve app name: %w", err)
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+// attributeRef returns the resource referenced by the attribute's compiled value, or by its source value.
+func attributeRef(attribute xmlAttribute) (resourceRef, bool) {
+	if len(attribute.item) > 0 {
+		if _, ref, err := itemValue(attribute.item); err == nil && ref != nil {
+			return *ref, true
+		}
+	}
+
+	return parseResourceRef(attribute.value)
+}
+
+func readZipFile(reader *zip.Reader, name string, maxSize int64) ([]byte, error) {
+	for _, file := range reader.File {
+		if file.Name != name {
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(maxSize) {
+			return nil, fmt.Errorf("%s is too large: %d bytes", name, file.UncompressedSize64)
+		}
+
+		content, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer func() {
+			_ = content.Close()
+		}()
+
+		data, err := io.ReadAll(io.LimitReader(content, maxSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+}
@@ -0,0 +1,226 @@
+package aabparser
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/protowire"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	appNameID     = 0x7f010000
+	versionNameID = 0x7f010001
+)
+
+func protoMessageOf(fields ...[]byte) []byte {
+	var message []byte
+	for _, field := range fields {
+		message = append(message, field...)
+	}
+	return message
+}
+
+func protoField(field uint64, content []byte) []byte {
+	return protowire.AppendBytes(nil, field, content)
+}
+
+func protoString(field uint64, value string) []byte {
+	return protowire.AppendBytes(nil, field, []byte(value))
+}
+
+func protoVarint(field, value uint64) []byte {
+	return protowire.AppendVarint(nil, field, value)
+}
+
+func stringItem(value string) []byte {
+	return protoField(itemStr, protoString(stringValue, value))
+}
+
+func refItem(id uint64, name string) []byte {
+	return protoField(itemRef, protoMessageOf(protoVarint(referenceID, id), protoString(referenceName, name)))
+}
+
+func intItem(value uint64) []byte {
+	return protoField(itemPrim, protoVarint(primitiveIntDecimal, value))
+}
+
+// attribute encodes an XmlAttribute, the source value or the compiled item is omitted if empty.
+func attribute(namespaceURI, name, value string, item []byte) []byte {
+	content := protoMessageOf(protoString(xmlAttributeNamespaceURI, namespaceURI), protoString(xmlAttributeName, name))
+	if value != "" {
+		content = append(content, protoString(xmlAttributeValue, value)...)
+	}
+	if item != nil {
+		content = append(content, protoField(xmlAttributeCompiledItem, item)...)
+	}
+	return protoField(xmlElementAttribute, content)
+}
+
+// element encodes an XmlNode of an element with the given attributes and child nodes.
+func element(name string, fields ...[]byte) []byte {
+	return protoField(xmlNodeElement, protoMessageOf(append([][]byte{protoString(xmlElementName, name)}, fields...)...))
+}
+
+func child(node []byte) []byte {
+	return protoField(xmlElementChild, node)
+}
+
+func manifestXML(label, versionName []byte) []byte {
+	return element("manifest",
+		protoField(xmlElementNamespaceDeclaration, protoMessageOf(protoString(xmlNamespacePrefix, "android"), protoString(xmlNamespaceURI, androidNamespaceURI))),
+		attribute("", "package", "io.bitrise.example", nil),
+		attribute(androidNamespaceURI, "versionCode", "42", intItem(42)),
+		versionName,
+		child(protoField(2, []byte("\n  "))),
+		child(element("uses-sdk",
+			attribute(androidNamespaceURI, "minSdkVersion", "21", intItem(21)),
+			attribute(androidNamespaceURI, "targetSdkVersion", "", intItem(34)),
+		)),
+		child(element("application", label)),
+	)
+}
+
+func entry(id uint64, name string, configValues ...[]byte) []byte {
+	fields := [][]byte{protoField(entryID, protoVarint(idValue, id)), protoString(entryName, name)}
+	for _, configValue := range configValues {
+		fields = append(fields, protoField(entryConfigValue, configValue))
+	}
+	return protoField(typeEntry, protoMessageOf(fields...))
+}
+
+func configValue(locale string, item []byte) []byte {
+	var config []byte
+	if locale != "" {
+		config = protoString(configurationLocale, locale)
+	}
+	return protoMessageOf(protoField(configValueConfig, config), protoField(configValueValue, protoField(valueItem, item)))
+}
+
+func resourcesPB() []byte {
+	stringType := protoMessageOf(
+		protoField(typeID, protoVarint(idValue, 1)),
+		protoString(typeName, "string"),
+		entry(0, "app_name", configValue("de", stringItem("Beispiel")), configValue("", stringItem("Example"))),
+		entry(1, "version_name", configValue("", refItem(0, "io.bitrise.example:string/version_name_base"))),
+		entry(2, "version_name_base", configValue("", stringItem("1.2.3"))),
+		entry(3, "loop", configValue("", refItem(0, "string/loop"))),
+	)
+	pkg := protoMessageOf(
+		protoField(packageID, protoVarint(idValue, 0x7f)),
+		protoString(2, "io.bitrise.example"),
+		protoField(packageType, stringType),
+	)
+	return protoField(resourceTablePackage, pkg)
+}
+
+func writeAAB(t *testing.T, name string, files map[string][]byte) string {
+	pth := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(pth)
+	require.NoError(t, err)
+
+	writer := zip.NewWriter(file)
+	for fileName, content := range files {
+		fileWriter, err := writer.Create(fileName)
+		require.NoError(t, err)
+		_, err = fileWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+
+	return pth
+}
+
+func TestReadManifest(t *testing.T) {
+	pth := writeAAB(t, "app-release.aab", map[string][]byte{
+		manifestPath: manifestXML(
+			attribute(androidNamespaceURI, "label", "@string/app_name", refItem(appNameID, "string/app_name")),
+			attribute(androidNamespaceURI, "versionName", "", refItem(versionNameID, "")),
+		),
+		resourcesPath:           resourcesPB(),
+		"base/dex/classes.dex":  []byte("dex"),
+		"BundleConfig.pb":       nil,
+		"base/res/values/x.xml": nil,
+	})
+
+	manifest, err := ReadManifest(pth)
+	require.NoError(t, err)
+	require.Equal(t, "io.bitrise.example", manifest.PackageName)
+	require.Equal(t, "42", manifest.VersionCode)
+	require.Equal(t, "1.2.3", manifest.VersionName)
+	require.Equal(t, "21", manifest.MinSDKVersion)
+	require.Equal(t, "34", manifest.TargetSDKVersion)
+	require.Equal(t, "Example", manifest.AppName)
+	require.Equal(t, `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="io.bitrise.example" android:versionCode="42" android:versionName="@0x7f010001">
+  <uses-sdk android:minSdkVersion="21" android:targetSdkVersion="34"/>
+  <application android:label="@string/app_name"/>
+</manifest>
+`, manifest.Raw)
+}
+
+func TestReadManifest_literalValues(t *testing.T) {
+	pth := writeAAB(t, "app-release.aab", map[string][]byte{
+		manifestPath: manifestXML(
+			attribute(androidNamespaceURI, "label", "", stringItem("Tom & Jerry")),
+			attribute(androidNamespaceURI, "versionName", "2.0", stringItem("2.0")),
+		),
+	})
+
+	manifest, err := ReadManifest(pth)
+	require.NoError(t, err)
+	require.Equal(t, "Tom & Jerry", manifest.AppName)
+	require.Equal(t, "2.0", manifest.VersionName)
+	require.Contains(t, manifest.Raw, `android:label="Tom &amp; Jerry"`)
+}
+
+func TestReadManifest_invalid(t *testing.T) {
+	_, err := ReadManifest(writeAAB(t, "app.aab", map[string][]byte{"base/dex/classes.dex": []byte("dex")}))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = ReadManifest(writeAAB(t, "app.aab", map[string][]byte{manifestPath: manifestXML(nil, nil)[:20]}))
+	require.ErrorIs(t, err, protowire.ErrTruncated)
+
+	_, err = ReadManifest(writeAAB(t, "app.aab", map[string][]byte{manifestPath: element("resources")}))
+	require.ErrorContains(t, err, "unexpected root element")
+
+	_, err = ReadManifest(writeAAB(t, "app.aab", map[string][]byte{
+		manifestPath: manifestXML(attribute(androidNamespaceURI, "label", "@string/app_name", nil), nil),
+	}))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = ReadManifest(writeAAB(t, "app.aab", map[string][]byte{
+		manifestPath:  manifestXML(attribute(androidNamespaceURI, "label", "@string/loop", nil), nil),
+		resourcesPath: resourcesPB(),
+	}))
+	require.ErrorContains(t, err, "nested deeper than")
+}
+
+func TestParser_ParseAABData(t *testing.T) {
+	pth := writeAAB(t, "app-demo-release.aab", map[string][]byte{
+		manifestPath: manifestXML(
+			attribute(androidNamespaceURI, "label", "@string/app_name", refItem(appNameID, "string/app_name")),
+			attribute(androidNamespaceURI, "versionName", "1.2.3", nil),
+		),
+		resourcesPath: resourcesPB(),
+	})
+
+	metadata, err := New(log.NewLogger(), fileutil.NewFileManager()).ParseAABData(pth)
+	require.NoError(t, err)
+	require.Equal(t, "Example", metadata.AppInfo.AppName)
+	require.Equal(t, "io.bitrise.example", metadata.AppInfo.PackageName)
+	require.Equal(t, "42", metadata.AppInfo.VersionCode)
+	require.Equal(t, "1.2.3", metadata.AppInfo.VersionName)
+	require.Equal(t, "21", metadata.AppInfo.MinSDKVersion)
+	require.Equal(t, "app", metadata.Module)
+	require.Equal(t, "demo", metadata.ProductFlavour)
+	require.Equal(t, "release", metadata.BuildType)
+
+	info, err := os.Stat(pth)
+	require.NoError(t, err)
+	require.Equal(t, info.Size(), metadata.FileSizeBytes)
+}
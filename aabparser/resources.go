@@ -0,0 +1,214 @@
+package aabparser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/protowire"
+)
+
+// Field numbers of the aapt2 ResourceTable message (frameworks/base/tools/aapt2/Resources.proto),
+// the format of the resources.pb files in app bundles.
+const (
+	resourceTablePackage = 2
+
+	packageID   = 1
+	packageType = 3
+
+	typeID    = 1
+	typeName  = 2
+	typeEntry = 3
+
+	entryID          = 1
+	entryName        = 2
+	entryConfigValue = 6
+
+	// idValue is the id field of the PackageId, TypeId and EntryId messages.
+	idValue = 1
+
+	configValueConfig = 1
+	configValueValue  = 2
+
+	configurationLocale = 3
+
+	valueItem = 4
+)
+
+// maxReferenceDepth limits the chain of resource references followed when resolving a value.
+const maxReferenceDepth = 8
+
+// resourceRef is a reference to a resource by its ID (0xPPTTEEEE) or name (type/entry).
+type resourceRef struct {
+	id   uint32
+	name string
+}
+
+func (r resourceRef) String() string {
+	if r.name != "" {
+		return "@" + r.name
+	}
+
+	return fmt.Sprintf("@0x%08x", r.id)
+}
+
+// parseResourceRef parses a reference written in the manifest source, like @string/app_name.
+func parseResourceRef(value string) (resourceRef, bool) {
+	if !strings.HasPrefix(value, "@") || !strings.Contains(value, "/") {
+		return resourceRef{}, false
+	}
+
+	return resourceRef{name: value[1:]}, true
+}
+
+// localName returns the type/entry name of the reference, without the package
+// of references like @android:string/ok or @io.bitrise.app:string/app_name.
+func (r resourceRef) localName() string {
+	if i := strings.Index(r.name, ":"); i >= 0 && i < strings.Index(r.name, "/") {
+		return r.name[i+1:]
+	}
+
+	return r.name
+}
+
+type resourceEntry struct {
+	// items are the compiled Items of the entry by the locale of their configuration.
+	items map[string][]byte
+}
+
+type resourceTable struct {
+	entriesByID   map[uint32]resourceEntry
+	entriesByName map[string]resourceEntry
+}
+
+// decodeResourceTable decodes the entries of a protobuf encoded ResourceTable.
+func decodeResourceTable(content []byte) (resourceTable, error) {
+	table := resourceTable{
+		entriesByID:   map[uint32]resourceEntry{},
+		entriesByName: map[string]resourceEntry{},
+	}
+
+	resourceTableMessage, err := protowire.Decode(content)
+	if err != nil {
+		return resourceTable{}, err
+	}
+
+	for _, packageContent := range resourceTableMessage.Messages(resourceTablePackage) {
+		pkg, err := protowire.Decode(packageContent)
+		if err != nil {
+			return resourceTable{}, err
+		}
+		pkgID, err := decodeID(pkg.Message(packageID))
+		if err != nil {
+			return resourceTable{}, err
+		}
+
+		for _, typeContent := range pkg.Messages(packageType) {
+			resourceType, err := protowire.Decode(typeContent)
+			if err != nil {
+				return resourceTable{}, err
+			}
+			resourceTypeID, err := decodeID(resourceType.Message(typeID))
+			if err != nil {
+				return resourceTable{}, err
+			}
+
+			for _, entryContent := range resourceType.Messages(typeEntry) {
+				entryMessage, err := protowire.Decode(entryContent)
+				if err != nil {
+					return resourceTable{}, err
+				}
+				entry, err := decodeEntry(entryMessage)
+				if err != nil {
+					return resourceTable{}, err
+				}
+				resourceEntryID, err := decodeID(entryMessage.Message(entryID))
+				if err != nil {
+					return resourceTable{}, err
+				}
+
+				table.entriesByID[pkgID<<24|resourceTypeID<<16|resourceEntryID] = entry
+				table.entriesByName[resourceType.String(typeName)+"/"+entryMessage.String(entryName)] = entry
+			}
+		}
+	}
+
+	return table, nil
+}
+
+func decodeID(content []byte) (uint32, error) {
+	id, err := protowire.Decode(content)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(id.Varint(idValue)), nil
+}
+
+func decodeEntry(entry protowire.Message) (resourceEntry, error) {
+	decoded := resourceEntry{items: map[string][]byte{}}
+	for _, configValueContent := range entry.Messages(entryConfigValue) {
+		configValue, err := protowire.Decode(configValueContent)
+		if err != nil {
+			return resourceEntry{}, err
+		}
+		config, err := protowire.Decode(configValue.Message(configValueConfig))
+		if err != nil {
+			return resourceEntry{}, err
+		}
+		value, err := protowire.Decode(configValue.Message(configValueValue))
+		if err != nil {
+			return resourceEntry{}, err
+		}
+		if !value.Has(valueItem) {
+			continue
+		}
+
+		locale := config.String(configurationLocale)
+		if _, ok := decoded.items[locale]; !ok {
+			decoded.items[locale] = value.Message(valueItem)
+		}
+	}
+
+	return decoded, nil
+}
+
+// resolve returns the value of the referenced resource, following references to other resources.
+// The value of the default configuration is preferred, like on devices without a matching locale.
+func (t resourceTable) resolve(ref resourceRef) (string, error) {
+	for depth := 0; depth < maxReferenceDepth; depth++ {
+		entry, ok := t.entriesByID[ref.id]
+		if ref.id == 0 || !ok {
+			entry, ok = t.entriesByName[ref.localName()]
+		}
+		if !ok {
+			return "", fmt.Errorf("resource not found: %s", ref)
+		}
+
+		item, ok := entry.items[""]
+		if !ok {
+			locales := make([]string, 0, len(entry.items))
+			for locale := range entry.items {
+				locales = append(locales, locale)
+			}
+			sort.Strings(locales)
+			if len(locales) > 0 {
+				item = entry.items[locales[0]]
+			}
+		}
+		if item == nil {
+			return "", fmt.Errorf("resource has no value: %s", ref)
+		}
+
+		value, nextRef, err := itemValue(item)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode resource %s: %w", ref, err)
+		}
+		if nextRef == nil {
+			return value, nil
+		}
+		ref = *nextRef
+	}
+
+	return "", fmt.Errorf("resource references are nested deeper than %d levels: %s", maxReferenceDepth, ref)
+}
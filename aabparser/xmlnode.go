@@ -0,0 +1,285 @@
+package aabparser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/protowire"
+)
+
+// Field numbers of the aapt2 XmlNode message (frameworks/base/tools/aapt2/Resources.proto),
+// the format of the AndroidManifest.xml files in app bundles.
+const (
+	xmlNodeElement = 1
+
+	xmlElementNamespaceDeclaration = 1
+	xmlElementNamespaceURI         = 2
+	xmlElementName                 = 3
+	xmlElementAttribute            = 4
+	xmlElementChild                = 5
+
+	xmlNamespacePrefix = 1
+	xmlNamespaceURI    = 2
+
+	xmlAttributeNamespaceURI = 1
+	xmlAttributeName         = 2
+	xmlAttributeValue        = 3
+	xmlAttributeCompiledItem = 6
+)
+
+// Field numbers of the aapt2 Item message and its values, shared by the manifest attributes and the resource table.
+const (
+	itemRef       = 1
+	itemStr       = 2
+	itemRawStr    = 3
+	itemStyledStr = 4
+	itemPrim      = 7
+
+	referenceID   = 2
+	referenceName = 3
+
+	stringValue = 1
+
+	primitiveIntDecimal = 6
+	primitiveIntHex     = 7
+	primitiveBoolean    = 8
+)
+
+const androidNamespaceURI = "http://schemas.android.com/apk/res/android"
+
+// maxXMLDepth limits the nesting of the decoded elements, manifests are only a few levels deep.
+const maxXMLDepth = 32
+
+type xmlNamespace struct {
+	prefix string
+	uri    string
+}
+
+type xmlAttribute struct {
+	namespaceURI string
+	name         string
+	value        string
+	item         []byte
+}
+
+type xmlElement struct {
+	namespaces   []xmlNamespace
+	namespaceURI string
+	name         string
+	attributes   []xmlAttribute
+	children     []xmlElement
+}
+
+// decodeXMLNode decodes the root element of a protobuf encoded XmlNode.
+func decodeXMLNode(content []byte) (xmlElement, error) {
+	node, err := protowire.Decode(content)
+	if err != nil {
+		return xmlElement{}, err
+	}
+	if !node.Has(xmlNodeElement) {
+		return xmlElement{}, errors.New("no root element")
+	}
+
+	return decodeXMLElement(node.Message(xmlNodeElement), 0)
+}
+
+func decodeXMLElement(content []byte, depth int) (xmlElement, error) {
+	if depth > maxXMLDepth {
+		return xmlElement{}, fmt.Errorf("elements are nested deeper than %d levels", maxXMLDepth)
+	}
+
+	element, err := protowire.Decode(content)
+	if err != nil {
+		return xmlElement{}, err
+	}
+
+	decoded := xmlElement{
+		namespaceURI: element.String(xmlElementNamespaceURI),
+		name:         element.String(xmlElementName),
+	}
+
+	for _, namespaceContent := range element.Messages(xmlElementNamespaceDeclaration) {
+		namespace, err := protowire.Decode(namespaceContent)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		decoded.namespaces = append(decoded.namespaces, xmlNamespace{
+			prefix: namespace.String(xmlNamespacePrefix),
+			uri:    namespace.String(xmlNamespaceURI),
+		})
+	}
+
+	for _, attributeContent := range element.Messages(xmlElementAttribute) {
+		attribute, err := protowire.Decode(attributeContent)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		decoded.attributes = append(decoded.attributes, xmlAttribute{
+			namespaceURI: attribute.String(xmlAttributeNamespaceURI),
+			name:         attribute.String(xmlAttributeName),
+			value:        attribute.String(xmlAttributeValue),
+			item:         attribute.Message(xmlAttributeCompiledItem),
+		})
+	}
+
+	for _, childContent := range element.Messages(xmlElementChild) {
+		child, err := protowire.Decode(childContent)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		// Text nodes of the manifest are not used.
+		if !child.Has(xmlNodeElement) {
+			continue
+		}
+
+		childElement, err := decodeXMLElement(child.Message(xmlNodeElement), depth+1)
+		if err != nil {
+			return xmlElement{}, err
+		}
+		decoded.children = append(decoded.children, childElement)
+	}
+
+	return decoded, nil
+}
+
+// child returns the first child element with the given name.
+func (e xmlElement) child(name string) (xmlElement, bool) {
+	for _, child := range e.children {
+		if child.name == name {
+			return child, true
+		}
+	}
+
+	return xmlElement{}, false
+}
+
+// attribute returns the attribute with the given namespace and name.
+func (e xmlElement) attribute(namespaceURI, name string) (xmlAttribute, bool) {
+	for _, attribute := range e.attributes {
+		if attribute.namespaceURI == namespaceURI && attribute.name == name {
+			return attribute, true
+		}
+	}
+
+	return xmlAttribute{}, false
+}
+
+// String returns the attribute value as written in the source manifest,
+// falling back to the compiled value if the source value was not kept.
+func (a xmlAttribute) String() string {
+	if a.value != "" {
+		return a.value
+	}
+
+	value, _, err := itemValue(a.item)
+	if err != nil {
+		return ""
+	}
+
+	return value
+}
+
+// itemValue returns the string form of a compiled Item, or the referenced resource if the item is a reference.
+func itemValue(content []byte) (string, *resourceRef, error) {
+	item, err := protowire.Decode(content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch {
+	case item.Has(itemRef):
+		ref, err := protowire.Decode(item.Message(itemRef))
+		if err != nil {
+			return "", nil, err
+		}
+		resource := &resourceRef{id: uint32(ref.Varint(referenceID)), name: ref.String(referenceName)}
+		return resource.String(), resource, nil
+	case item.Has(itemStr):
+		return messageString(item.Message(itemStr))
+	case item.Has(itemRawStr):
+		return messageString(item.Message(itemRawStr))
+	case item.Has(itemStyledStr):
+		return messageString(item.Message(itemStyledStr))
+	case item.Has(itemPrim):
+		primitive, err := protowire.Decode(item.Message(itemPrim))
+		if err != nil {
+			return "", nil, err
+		}
+		switch {
+		case primitive.Has(primitiveIntDecimal):
+			return strconv.FormatInt(int64(int32(primitive.Varint(primitiveIntDecimal))), 10), nil, nil
+		case primitive.Has(primitiveIntHex):
+			return fmt.Sprintf("0x%08x", uint32(primitive.Varint(primitiveIntHex))), nil, nil
+		case primitive.Has(primitiveBoolean):
+			return strconv.FormatBool(primitive.Bool(primitiveBoolean)), nil, nil
+		}
+	}
+
+	return "", nil, nil
+}
+
+func messageString(content []byte) (string, *resourceRef, error) {
+	message, err := protowire.Decode(content)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return message.String(stringValue), nil, nil
+}
+
+// render returns the element in XML format, similar to the output of `bundletool dump manifest`.
+func (e xmlElement) render() string {
+	var builder strings.Builder
+	e.renderTo(&builder, map[string]string{}, 0)
+	return builder.String()
+}
+
+func (e xmlElement) renderTo(builder *strings.Builder, prefixes map[string]string, depth int) {
+	if len(e.namespaces) > 0 {
+		scoped := make(map[string]string, len(prefixes)+len(e.namespaces))
+		for uri, prefix := range prefixes {
+			scoped[uri] = prefix
+		}
+		for _, namespace := range e.namespaces {
+			scoped[namespace.uri] = namespace.prefix
+		}
+		prefixes = scoped
+	}
+
+	indent := strings.Repeat("  ", depth)
+	builder.WriteString(indent + "<" + qualifiedName(prefixes, e.namespaceURI, e.name))
+	for _, namespace := range e.namespaces {
+		writeXMLAttribute(builder, "xmlns:"+namespace.prefix, namespace.uri)
+	}
+	for _, attribute := range e.attributes {
+		writeXMLAttribute(builder, qualifiedName(prefixes, attribute.namespaceURI, attribute.name), attribute.String())
+	}
+
+	if len(e.children) == 0 {
+		builder.WriteString("/>\n")
+		return
+	}
+
+	builder.WriteString(">\n")
+	for _, child := range e.children {
+		child.renderTo(builder, prefixes, depth+1)
+	}
+	builder.WriteString(indent + "</" + qualifiedName(prefixes, e.namespaceURI, e.name) + ">\n")
+}
+
+func writeXMLAttribute(builder *strings.Builder, name, value string) {
+	builder.WriteString(" " + name + "=\"")
+	_ = xml.EscapeText(builder, []byte(value))
+	builder.WriteString("\"")
+}
+
+func qualifiedName(prefixes map[string]string, namespaceURI, name string) string {
+	if prefix, ok := prefixes[namespaceURI]; ok && prefix != "" {
+		return prefix + ":" + name
+	}
+
+	return name
+}
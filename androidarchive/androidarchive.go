@@ -0,0 +1,202 @@
+// Package androidarchive reads the APK sets produced by `bundletool build-apks` (.apks)
+// and the sideload distribution archives (.xapk).
+package androidarchive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive extensions.
+const (
+	APKSExt = ".apks"
+	XAPKExt = ".xapk"
+)
+
+const (
+	baseModuleName = "base"
+	universalAPK   = "universal.apk"
+
+	maxTOCSizeInBytes = 16 * 1024 * 1024
+)
+
+var (
+	// based on: https://developer.android.com/ndk/guides/abis.html#sa
+	abis = []string{"armeabi-v7a", "arm64-v8a", "x86_64", "x86", "armeabi", "mips64", "mips", "riscv64"}
+	// based on: https://developer.android.com/guide/topics/resources/providing-resources#DensityQualifier
+	screenDensities = []string{"xxxhdpi", "xxhdpi", "xhdpi", "hdpi", "tvdpi", "mdpi", "ldpi", "nodpi"}
+)
+
+// ErrNoBaseAPK is returned when the archive doesn't contain the base APK of the app.
+var ErrNoBaseAPK = errors.New("no base APK found")
+
+// Split is an APK of the archive, installed together with the base APK on the devices it targets.
+type Split struct {
+	Path            string   `json:"path"`
+	Module          string   `json:"module"`
+	ID              string   `json:"id"`
+	Master          bool     `json:"master"`
+	ABIs            []string `json:"abis,omitempty"`
+	ScreenDensities []string `json:"screen_densities,omitempty"`
+	Languages       []string `json:"languages,omitempty"`
+}
+
+// Archive describes the APKs of an .apks or .xapk archive, the paths are relative to the archive's root.
+type Archive struct {
+	PackageName  string
+	BaseAPK      string
+	Splits       []Split
+	UniversalAPK string
+	Standalones  []string
+}
+
+// IsArchive reports whether the path is an .apks or .xapk archive.
+func IsArchive(pth string) bool {
+	ext := strings.ToLower(filepath.Ext(pth))
+	return ext == APKSExt || ext == XAPKExt
+}
+
+// Read reads the table of contents of the archive.
+func Read(pth string) (Archive, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return Archive{}, fmt.Errorf("failed to open %s: %w", pth, err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	var archive Archive
+	switch strings.ToLower(filepath.Ext(pth)) {
+	case APKSExt:
+		archive, err = readAPKS(&reader.Reader)
+	case XAPKExt:
+		archive, err = readXAPK(&reader.Reader)
+	default:
+		return Archive{}, fmt.Errorf("%s is not an .apks or .xapk archive", pth)
+	}
+	if err != nil {
+		return Archive{}, fmt.Errorf("failed to read %s: %w", pth, err)
+	}
+
+	if archive.BaseAPK == "" {
+		archive.BaseAPK = archive.UniversalAPK
+	}
+	if archive.BaseAPK == "" && len(archive.Standalones) > 0 {
+		archive.BaseAPK = archive.Standalones[0]
+	}
+	if archive.BaseAPK == "" {
+		return Archive{}, fmt.Errorf("failed to read %s: %w", pth, ErrNoBaseAPK)
+	}
+
+	return archive, nil
+}
+
+// SplitPaths returns the paths of the split APKs, including the universal APK.
+func (a Archive) SplitPaths() []string {
+	var paths []string
+	for _, split := range a.Splits {
+		paths = append(paths, split.Path)
+	}
+	if a.UniversalAPK != "" {
+		paths = append(paths, a.UniversalAPK)
+	}
+
+	return paths
+}
+
+// ExtractAPK writes the APK at the given path of the archive to the target path.
+func ExtractAPK(archivePth, apkPth, targetPth string) error {
+	reader, err := zip.OpenReader(archivePth)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", archivePth, err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	file := findFile(&reader.Reader, apkPth)
+	if file == nil {
+		return fmt.Errorf("%s not found in %s", apkPth, archivePth)
+	}
+
+	source, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", apkPth, err)
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	target, err := os.Create(targetPth)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		_ = target.Close()
+		return fmt.Errorf("failed to extract %s: %w", apkPth, err)
+	}
+
+	return target.Close()
+}
+
+func findFile(reader *zip.Reader, name string) *zip.File {
+	for _, file := range reader.File {
+		if file.Name == name {
+			return file
+		}
+	}
+
+	return nil
+}
+
+func readFile(reader *zip.Reader, name string) ([]byte, error) {
+	file := findFile(reader, name)
+	if file == nil {
+		return nil, os.ErrNotExist
+	}
+	if file.UncompressedSize64 > maxTOCSizeInBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxTOCSizeInBytes)
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = content.Close()
+	}()
+
+	return io.ReadAll(io.LimitReader(content, maxTOCSizeInBytes))
+}
+
+// targetingFromSplitID returns the ABIs and screen densities encoded in split IDs, like config.arm64_v8a or base-xxhdpi.
+func targetingFromSplitID(id string) (abiList []string, densityList []string) {
+	normalized := strings.ReplaceAll(strings.ToLower(id), "_", "-")
+	for _, part := range strings.FieldsFunc(normalized, func(r rune) bool { return r == '.' }) {
+		for _, abi := range abis {
+			if normalizedABI := strings.ReplaceAll(abi, "_", "-"); part == normalizedABI || strings.HasSuffix(part, "-"+normalizedABI) {
+				abiList = append(abiList, abi)
+				break
+			}
+		}
+		for _, density := range screenDensities {
+			if part == density || strings.HasSuffix(part, "-"+density) {
+				densityList = append(densityList, density)
+				break
+			}
+		}
+	}
+
+	return abiList, densityList
+}
+
+func isAPK(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".apk")
+}
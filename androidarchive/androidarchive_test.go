@@ -0,0 +1,238 @@
+package androidarchive
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/protowire"
+	"github.com/stretchr/testify/require"
+)
+
+// protoField encodes a length-delimited protobuf field.
+func protoField(field uint64, content []byte) []byte {
+	return protowire.AppendBytes(nil, field, content)
+}
+
+// protoVarint encodes a varint protobuf field.
+func protoVarint(field, value uint64) []byte {
+	return protowire.AppendVarint(nil, field, value)
+}
+
+func protoMessageOf(fields ...[]byte) []byte {
+	var message []byte
+	for _, field := range fields {
+		message = append(message, field...)
+	}
+	return message
+}
+
+func splitDescription(pth, splitID string, master bool, targeting []byte) []byte {
+	var isMaster uint64
+	if master {
+		isMaster = 1
+	}
+
+	return protoField(apkSetApkDescription, protoMessageOf(
+		protoField(apkDescriptionTargeting, targeting),
+		protoField(apkDescriptionPath, []byte(pth)),
+		protoField(apkDescriptionSplit, protoMessageOf(
+			protoField(splitApkMetadataSplitID, []byte(splitID)),
+			protoVarint(splitApkMetadataIsMaster, isMaster),
+		)),
+	))
+}
+
+func testTOC() []byte {
+	abiTargeting := protoField(apkTargetingABI, protoField(targetingValue, protoVarint(abiAlias, 3)))
+	densityTargeting := protoField(apkTargetingScreenDensity, protoMessageOf(
+		protoField(targetingValue, protoVarint(screenDensityAlias, 7)),
+		protoField(targetingValue, protoVarint(screenDensityDPI, 420)),
+	))
+	languageTargeting := protoField(apkTargetingLanguage, protoField(targetingValue, []byte("de")))
+
+	baseSet := protoField(variantApkSet, protoMessageOf(
+		protoField(apkSetModuleMetadata, protoField(moduleMetadataName, []byte("base"))),
+		splitDescription("splits/base-master.apk", "", true, nil),
+		splitDescription("splits/base-arm64_v8a.apk", "config.arm64_v8a", false, abiTargeting),
+		splitDescription("splits/base-xxhdpi.apk", "config.xxhdpi", false, densityTargeting),
+		splitDescription("splits/base-de.apk", "config.de", false, languageTargeting),
+	))
+	featureSet := protoField(variantApkSet, protoMessageOf(
+		protoField(apkSetModuleMetadata, protoField(moduleMetadataName, []byte("feature"))),
+		splitDescription("splits/feature-master.apk", "feature", true, nil),
+	))
+	standaloneVariant := protoField(buildApksResultVariant, protoField(variantApkSet, protoMessageOf(
+		protoField(apkSetModuleMetadata, protoField(moduleMetadataName, []byte("base"))),
+		protoField(apkSetApkDescription, protoMessageOf(
+			protoField(apkDescriptionPath, []byte("standalones/standalone-arm64_v8a_xxhdpi.apk")),
+			protoField(apkDescriptionStandalone, nil),
+		)),
+	)))
+
+	return protoMessageOf(
+		protoField(buildApksResultVariant, protoMessageOf(baseSet, featureSet)),
+		standaloneVariant,
+		protoField(buildApksResultPackageName, []byte("io.bitrise.example")),
+	)
+}
+
+func writeTestArchive(t *testing.T, name string, files map[string][]byte) string {
+	pth := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(pth)
+	require.NoError(t, err)
+
+	writer := zip.NewWriter(file)
+	for fileName, content := range files {
+		fileWriter, err := writer.Create(fileName)
+		require.NoError(t, err)
+		_, err = fileWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+
+	return pth
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  Archive
+	}{
+		{
+			name: "APK set with table of contents",
+			files: map[string][]byte{
+				"toc.pb":                 testTOC(),
+				"splits/base-master.apk": []byte("base"),
+			},
+			want: Archive{
+				PackageName: "io.bitrise.example",
+				BaseAPK:     "splits/base-master.apk",
+				Splits: []Split{
+					{Path: "splits/base-master.apk", Module: "base", Master: true},
+					{Path: "splits/base-arm64_v8a.apk", Module: "base", ID: "config.arm64_v8a", ABIs: []string{"arm64-v8a"}},
+					{Path: "splits/base-xxhdpi.apk", Module: "base", ID: "config.xxhdpi", ScreenDensities: []string{"xxhdpi", "420dpi"}},
+					{Path: "splits/base-de.apk", Module: "base", ID: "config.de", Languages: []string{"de"}},
+					{Path: "splits/feature-master.apk", Module: "feature", ID: "feature", Master: true},
+				},
+				Standalones: []string{"standalones/standalone-arm64_v8a_xxhdpi.apk"},
+			},
+		},
+		{
+			name: "Universal APK set",
+			files: map[string][]byte{
+				"universal.apk": []byte("universal"),
+			},
+			want: Archive{
+				BaseAPK:      "universal.apk",
+				UniversalAPK: "universal.apk",
+			},
+		},
+		{
+			name: "APK set without table of contents",
+			files: map[string][]byte{
+				"splits/base-master.apk":      []byte("base"),
+				"splits/base-x86_64.apk":      []byte("x86_64"),
+				"splits/base-xhdpi.apk":       []byte("xhdpi"),
+				"standalones/standalone.apk":  []byte("standalone"),
+				"splits/base-master.apk.json": []byte("not an apk"),
+			},
+			want: Archive{
+				BaseAPK: "splits/base-master.apk",
+				Splits: []Split{
+					{Path: "splits/base-master.apk", Module: "base", ID: "master", Master: true},
+					{Path: "splits/base-x86_64.apk", Module: "base", ID: "x86_64", ABIs: []string{"x86_64"}},
+					{Path: "splits/base-xhdpi.apk", Module: "base", ID: "xhdpi", ScreenDensities: []string{"xhdpi"}},
+				},
+				Standalones: []string{"standalones/standalone.apk"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(writeTestArchive(t, "app-release.apks", tt.files))
+			require.NoError(t, err)
+			require.Equal(t, tt.want.PackageName, got.PackageName)
+			require.Equal(t, tt.want.BaseAPK, got.BaseAPK)
+			require.ElementsMatch(t, tt.want.Splits, got.Splits)
+			require.Equal(t, tt.want.UniversalAPK, got.UniversalAPK)
+			require.Equal(t, tt.want.Standalones, got.Standalones)
+		})
+	}
+}
+
+func TestRead_xapk(t *testing.T) {
+	manifest := `{
+  "xapk_version": 2,
+  "package_name": "io.bitrise.example",
+  "name": "Example",
+  "version_code": "45",
+  "version_name": "1.2.3",
+  "split_apks": [
+    {"file": "io.bitrise.example.apk", "id": "base"},
+    {"file": "config.arm64_v8a.apk", "id": "config.arm64_v8a"},
+    {"file": "config.xxhdpi.apk", "id": "config.xxhdpi"},
+    {"file": "feature.apk", "id": "feature"},
+    {"file": "feature.config.armeabi_v7a.apk", "id": "feature.config.armeabi_v7a"}
+  ]
+}`
+
+	got, err := Read(writeTestArchive(t, "Example.xapk", map[string][]byte{
+		"manifest.json":          []byte(manifest),
+		"io.bitrise.example.apk": []byte("base"),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, Archive{
+		PackageName: "io.bitrise.example",
+		BaseAPK:     "io.bitrise.example.apk",
+		Splits: []Split{
+			{Path: "io.bitrise.example.apk", Module: "base", ID: "base", Master: true},
+			{Path: "config.arm64_v8a.apk", Module: "base", ID: "config.arm64_v8a", ABIs: []string{"arm64-v8a"}},
+			{Path: "config.xxhdpi.apk", Module: "base", ID: "config.xxhdpi", ScreenDensities: []string{"xxhdpi"}},
+			{Path: "feature.apk", Module: "feature", ID: "feature", Master: true},
+			{Path: "feature.config.armeabi_v7a.apk", Module: "feature", ID: "feature.config.armeabi_v7a", ABIs: []string{"armeabi-v7a"}},
+		},
+	}, got)
+
+	got, err = Read(writeTestArchive(t, "Example.xapk", map[string][]byte{
+		"manifest.json":          []byte(`{"xapk_version": 1, "package_name": "io.bitrise.example"}`),
+		"io.bitrise.example.apk": []byte("base"),
+	}))
+	require.NoError(t, err)
+	require.Equal(t, "io.bitrise.example.apk", got.BaseAPK)
+	require.Empty(t, got.SplitPaths())
+}
+
+func TestRead_invalid(t *testing.T) {
+	_, err := Read(writeTestArchive(t, "Example.xapk", map[string][]byte{"icon.png": []byte("icon")}))
+	require.ErrorContains(t, err, "manifest.json is missing")
+
+	_, err = Read(writeTestArchive(t, "app-release.apks", map[string][]byte{"toc.pb": {0x0a, 0xff}}))
+	require.ErrorContains(t, err, "truncated protobuf message")
+
+	_, err = Read(writeTestArchive(t, "app-release.apks", map[string][]byte{"README.md": []byte("readme")}))
+	require.ErrorIs(t, err, ErrNoBaseAPK)
+}
+
+func TestExtractAPK(t *testing.T) {
+	pth := writeTestArchive(t, "app-release.apks", map[string][]byte{"splits/base-master.apk": []byte("base")})
+	targetPth := filepath.Join(t.TempDir(), "base-master.apk")
+
+	require.NoError(t, ExtractAPK(pth, "splits/base-master.apk", targetPth))
+	content, err := os.ReadFile(targetPth)
+	require.NoError(t, err)
+	require.Equal(t, "base", string(content))
+
+	require.ErrorContains(t, ExtractAPK(pth, "splits/missing.apk", targetPth), "splits/missing.apk not found")
+}
+
+func TestArchive_SplitPaths(t *testing.T) {
+	archive := Archive{
+		Splits:       []Split{{Path: "splits/base-master.apk"}, {Path: "splits/base-arm64_v8a.apk"}},
+		UniversalAPK: "universal.apk",
+	}
+	require.Equal(t, []string{"splits/base-master.apk", "splits/base-arm64_v8a.apk", "universal.apk"}, archive.SplitPaths())
+}
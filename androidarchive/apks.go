@@ -0,0 +1,241 @@
+package androidarchive
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/protowire"
+)
+
+const apksTOCFile = "toc.pb"
+
+// Field numbers of the BuildApksResult message (bundletool's commands.proto) written to toc.pb.
+const (
+	buildApksResultVariant     = 1
+	buildApksResultPackageName = 4
+
+	variantApkSet = 2
+
+	apkSetModuleMetadata = 1
+	apkSetApkDescription = 2
+
+	moduleMetadataName = 1
+
+	apkDescriptionTargeting  = 1
+	apkDescriptionPath       = 2
+	apkDescriptionSplit      = 3
+	apkDescriptionStandalone = 4
+
+	splitApkMetadataSplitID  = 1
+	splitApkMetadataIsMaster = 2
+
+	apkTargetingABI           = 1
+	apkTargetingLanguage      = 3
+	apkTargetingScreenDensity = 4
+
+	targetingValue = 1
+
+	abiAlias = 1
+
+	screenDensityAlias = 1
+	screenDensityDPI   = 2
+)
+
+// abiAliases are the values of bundletool's AbiAlias enum.
+var abiAliases = map[uint64]string{
+	1: "armeabi",
+	2: "armeabi-v7a",
+	3: "arm64-v8a",
+	4: "x86",
+	5: "x86_64",
+	6: "mips",
+	7: "mips64",
+	8: "riscv64",
+}
+
+// densityAliases are the values of bundletool's DensityAlias enum.
+var densityAliases = map[uint64]string{
+	1: "nodpi",
+	2: "ldpi",
+	3: "mdpi",
+	4: "tvdpi",
+	5: "hdpi",
+	6: "xhdpi",
+	7: "xxhdpi",
+	8: "xxxhdpi",
+}
+
+func readAPKS(reader *zip.Reader) (Archive, error) {
+	toc, err := readFile(reader, apksTOCFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return readAPKSFileNames(reader), nil
+	}
+	if err != nil {
+		return Archive{}, err
+	}
+
+	archive, err := parseTOC(toc)
+	if err != nil {
+		return Archive{}, fmt.Errorf("failed to parse %s: %w", apksTOCFile, err)
+	}
+
+	return archive, nil
+}
+
+func parseTOC(toc []byte) (Archive, error) {
+	result, err := protowire.Decode(toc)
+	if err != nil {
+		return Archive{}, err
+	}
+
+	archive := Archive{PackageName: result.String(buildApksResultPackageName)}
+	seen := map[string]bool{}
+	for _, variantContent := range result.Messages(buildApksResultVariant) {
+		variant, err := protowire.Decode(variantContent)
+		if err != nil {
+			return Archive{}, err
+		}
+
+		for _, apkSetContent := range variant.Messages(variantApkSet) {
+			apkSet, err := protowire.Decode(apkSetContent)
+			if err != nil {
+				return Archive{}, err
+			}
+
+			moduleMetadata, err := protowire.Decode(apkSet.Message(apkSetModuleMetadata))
+			if err != nil {
+				return Archive{}, err
+			}
+			module := moduleMetadata.String(moduleMetadataName)
+
+			for _, descriptionContent := range apkSet.Messages(apkSetApkDescription) {
+				description, err := protowire.Decode(descriptionContent)
+				if err != nil {
+					return Archive{}, err
+				}
+
+				pth := description.String(apkDescriptionPath)
+				if pth == "" || seen[pth] {
+					continue
+				}
+				seen[pth] = true
+
+				if path.Base(pth) == universalAPK {
+					archive.UniversalAPK = pth
+					continue
+				}
+				if description.Has(apkDescriptionStandalone) {
+					archive.Standalones = append(archive.Standalones, pth)
+					continue
+				}
+				if !description.Has(apkDescriptionSplit) {
+					continue
+				}
+
+				splitMetadata, err := protowire.Decode(description.Message(apkDescriptionSplit))
+				if err != nil {
+					return Archive{}, err
+				}
+				split := Split{
+					Path:   pth,
+					Module: module,
+					ID:     splitMetadata.String(splitApkMetadataSplitID),
+					Master: splitMetadata.Bool(splitApkMetadataIsMaster),
+				}
+				if err := split.readTargeting(description.Message(apkDescriptionTargeting)); err != nil {
+					return Archive{}, err
+				}
+				if split.Master && module == baseModuleName && archive.BaseAPK == "" {
+					archive.BaseAPK = pth
+				}
+
+				archive.Splits = append(archive.Splits, split)
+			}
+		}
+	}
+
+	return archive, nil
+}
+
+func (s *Split) readTargeting(content []byte) error {
+	targeting, err := protowire.Decode(content)
+	if err != nil {
+		return err
+	}
+
+	abiTargeting, err := protowire.Decode(targeting.Message(apkTargetingABI))
+	if err != nil {
+		return err
+	}
+	for _, abiContent := range abiTargeting.Messages(targetingValue) {
+		abi, err := protowire.Decode(abiContent)
+		if err != nil {
+			return err
+		}
+		if name, ok := abiAliases[abi.Varint(abiAlias)]; ok {
+			s.ABIs = append(s.ABIs, name)
+		}
+	}
+
+	densityTargeting, err := protowire.Decode(targeting.Message(apkTargetingScreenDensity))
+	if err != nil {
+		return err
+	}
+	for _, densityContent := range densityTargeting.Messages(targetingValue) {
+		density, err := protowire.Decode(densityContent)
+		if err != nil {
+			return err
+		}
+		if name, ok := densityAliases[density.Varint(screenDensityAlias)]; ok {
+			s.ScreenDensities = append(s.ScreenDensities, name)
+		} else if density.Has(screenDensityDPI) {
+			s.ScreenDensities = append(s.ScreenDensities, fmt.Sprintf("%ddpi", density.Varint(screenDensityDPI)))
+		}
+	}
+
+	languageTargeting, err := protowire.Decode(targeting.Message(apkTargetingLanguage))
+	if err != nil {
+		return err
+	}
+	s.Languages = languageTargeting.Strings(targetingValue)
+
+	return nil
+}
+
+// readAPKSFileNames lists the APKs of an APK set without a table of contents based on bundletool's file naming:
+// splits/<module>-<split ID>.apk, standalones/standalone-<targeting>.apk and universal.apk.
+func readAPKSFileNames(reader *zip.Reader) Archive {
+	var archive Archive
+	for _, file := range reader.File {
+		if !isAPK(file.Name) {
+			continue
+		}
+
+		switch dir, name := path.Split(file.Name); {
+		case file.Name == universalAPK:
+			archive.UniversalAPK = file.Name
+		case dir == "standalones/":
+			archive.Standalones = append(archive.Standalones, file.Name)
+		case dir == "splits/":
+			module, id, _ := strings.Cut(strings.TrimSuffix(name, path.Ext(name)), "-")
+			split := Split{
+				Path:   file.Name,
+				Module: module,
+				ID:     id,
+				Master: id == "master",
+			}
+			split.ABIs, split.ScreenDensities = targetingFromSplitID(id)
+			if split.Master && module == baseModuleName {
+				archive.BaseAPK = file.Name
+			}
+
+			archive.Splits = append(archive.Splits, split)
+		}
+	}
+
+	return archive
+}
@@ -0,0 +1,66 @@
+package androidarchive
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const xapkManifestFile = "manifest.json"
+
+// xapkManifest is the manifest.json of .xapk archives.
+type xapkManifest struct {
+	PackageName string `json:"package_name"`
+	SplitAPKs   []struct {
+		File string `json:"file"`
+		ID   string `json:"id"`
+	} `json:"split_apks"`
+}
+
+func readXAPK(reader *zip.Reader) (Archive, error) {
+	content, err := readFile(reader, xapkManifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return Archive{}, fmt.Errorf("%s is missing", xapkManifestFile)
+	}
+	if err != nil {
+		return Archive{}, err
+	}
+
+	var manifest xapkManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Archive{}, fmt.Errorf("failed to parse %s: %w", xapkManifestFile, err)
+	}
+
+	archive := Archive{PackageName: manifest.PackageName}
+	for _, splitAPK := range manifest.SplitAPKs {
+		split := Split{
+			Path:   splitAPK.File,
+			Module: baseModuleName,
+			ID:     splitAPK.ID,
+			Master: splitAPK.ID == baseModuleName,
+		}
+		if module, _, ok := strings.Cut(splitAPK.ID, ".config."); ok {
+			// Splits of feature modules are named like <module>.config.<targeting>.
+			split.Module = module
+		} else if !strings.HasPrefix(splitAPK.ID, "config.") && splitAPK.ID != baseModuleName {
+			split.Module = splitAPK.ID
+			split.Master = true
+		}
+		split.ABIs, split.ScreenDensities = targetingFromSplitID(splitAPK.ID)
+
+		if split.Master && split.Module == baseModuleName {
+			archive.BaseAPK = split.Path
+		}
+		archive.Splits = append(archive.Splits, split)
+	}
+
+	// Archives of apps without splits (version 1 of the format) contain a single <package name>.apk.
+	if archive.BaseAPK == "" && manifest.PackageName != "" && findFile(reader, manifest.PackageName+".apk") != nil {
+		archive.BaseAPK = manifest.PackageName + ".apk"
+	}
+
+	return archive, nil
+}
@@ -0,0 +1,193 @@
+// Package bundletoolprovider provides the bundletool jar: from a local path, from a persistent cache
+// or by downloading it, and verifies it against a pinned SHA-256 checksum before it is used.
+package bundletoolprovider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-android/v2/metaparser/bundletool"
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+const jarName = "bundletool-all.jar"
+
+// ErrJavaNotFound is returned when there is no Java runtime to run bundletool with.
+var ErrJavaNotFound = errors.New("java is not installed or not on the PATH: bundletool requires a Java runtime")
+
+// Config describes where the bundletool jar comes from.
+type Config struct {
+	Version string
+	// LocalPath is a bundletool jar on the machine, it is used instead of downloading the Version.
+	LocalPath string
+	// CacheDir is a persistent directory where the downloaded jars are stored by version.
+	CacheDir string
+	// SHA256 is the expected checksum of the jar, it is not verified if empty.
+	SHA256 string
+}
+
+// Provider provides the bundletool jar.
+type Provider struct {
+	config   Config
+	logger   log.Logger
+	lookPath func(file string) (string, error)
+	download func(version string) (bundletool.Path, error)
+}
+
+// New ...
+func New(config Config, logger log.Logger) *Provider {
+	return &Provider{
+		config:   config,
+		logger:   logger,
+		lookPath: exec.LookPath,
+		download: bundletool.New,
+	}
+}
+
+// Provide returns the path of the verified bundletool jar.
+func (p *Provider) Provide() (bundletool.Path, error) {
+	if _, err := p.lookPath("java"); err != nil {
+		return "", ErrJavaNotFound
+	}
+
+	if p.config.LocalPath != "" {
+		pth := strings.TrimPrefix(p.config.LocalPath, "file://")
+		if _, err := os.Stat(pth); err != nil {
+			return "", fmt.Errorf("bundletool jar not found: %w", err)
+		}
+		if err := p.verify(pth); err != nil {
+			return "", err
+		}
+
+		p.logger.Printf("Using bundletool from %s", pth)
+		return bundletool.Path(pth), nil
+	}
+
+	if p.config.CacheDir != "" {
+		if pth, ok := p.cached(); ok {
+			p.logger.Printf("Using bundletool %s from the cache: %s", p.config.Version, pth)
+			return bundletool.Path(pth), nil
+		}
+	}
+
+	p.logger.Printf("Downloading bundletool %s", p.config.Version)
+	downloaded, err := p.download(p.config.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to download bundletool %s: %w", p.config.Version, err)
+	}
+	if err := p.verify(string(downloaded)); err != nil {
+		return "", err
+	}
+
+	if p.config.CacheDir != "" {
+		pth, err := p.store(string(downloaded))
+		if err != nil {
+			p.logger.Warnf("Failed to cache bundletool: %s", err)
+			return downloaded, nil
+		}
+		return bundletool.Path(pth), nil
+	}
+
+	return downloaded, nil
+}
+
+func (p *Provider) cachePath() string {
+	return filepath.Join(p.config.CacheDir, p.config.Version, jarName)
+}
+
+// cached returns the jar of the version from the cache, a cached jar which doesn't match the checksum is removed.
+func (p *Provider) cached() (string, bool) {
+	pth := p.cachePath()
+	if _, err := os.Stat(pth); err != nil {
+		return "", false
+	}
+
+	if err := p.verify(pth); err != nil {
+		p.logger.Warnf("Removing cached bundletool: %s", err)
+		if err := os.Remove(pth); err != nil {
+			p.logger.Warnf("Failed to remove cached bundletool: %s", err)
+		}
+		return "", false
+	}
+
+	return pth, true
+}
+
+// store copies the downloaded jar into the cache, the jar is renamed into place so that
+// other runs sharing the cache never see a partially written jar.
+func (p *Provider) store(downloadedPth string) (string, error) {
+	pth := p.cachePath()
+	if err := os.MkdirAll(filepath.Dir(pth), 0755); err != nil {
+		return "", err
+	}
+
+	source, err := os.Open(downloadedPth)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = source.Close()
+	}()
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(pth), jarName+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := io.Copy(tmpFile, source); err != nil {
+		_ = tmpFile.Close()
+		return "", err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmpFile.Name(), pth); err != nil {
+		return "", err
+	}
+
+	return pth, nil
+}
+
+// verify checks the jar against the pinned checksum.
+func (p *Provider) verify(pth string) error {
+	if p.config.SHA256 == "" {
+		return nil
+	}
+
+	checksum, err := fileSHA256(pth)
+	if err != nil {
+		return fmt.Errorf("failed to calculate the checksum of %s: %w", pth, err)
+	}
+	if !strings.EqualFold(checksum, strings.TrimSpace(p.config.SHA256)) {
+		return fmt.Errorf("bundletool jar checksum mismatch (%s): expected SHA-256 %s, got %s", pth, p.config.SHA256, checksum)
+	}
+
+	return nil
+}
+
+func fileSHA256(pth string) (string, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,104 @@
+package bundletoolprovider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-android/v2/metaparser/bundletool"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/require"
+)
+
+const jarContent = "bundletool jar"
+
+func jarChecksum() string {
+	checksum := sha256.Sum256([]byte(jarContent))
+	return hex.EncodeToString(checksum[:])
+}
+
+func writeJar(t *testing.T, content string) string {
+	pth := filepath.Join(t.TempDir(), jarName)
+	require.NoError(t, os.WriteFile(pth, []byte(content), 0644))
+	return pth
+}
+
+// newProvider returns a Provider with java installed, which counts the downloads of the jar.
+func newProvider(t *testing.T, config Config, downloads *int) *Provider {
+	provider := New(config, log.NewLogger())
+	provider.lookPath = func(string) (string, error) { return "/usr/bin/java", nil }
+	provider.download = func(version string) (bundletool.Path, error) {
+		*downloads++
+		return bundletool.Path(writeJar(t, jarContent)), nil
+	}
+	return provider
+}
+
+func TestProvider_Provide_localPath(t *testing.T) {
+	var downloads int
+	localPth := writeJar(t, jarContent)
+
+	pth, err := newProvider(t, Config{Version: "1.15.0", LocalPath: "file://" + localPth, SHA256: jarChecksum()}, &downloads).Provide()
+	require.NoError(t, err)
+	require.Equal(t, bundletool.Path(localPth), pth)
+	require.Zero(t, downloads)
+
+	_, err = newProvider(t, Config{LocalPath: localPth, SHA256: "deadbeef"}, &downloads).Provide()
+	require.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = newProvider(t, Config{LocalPath: filepath.Join(t.TempDir(), jarName)}, &downloads).Provide()
+	require.ErrorContains(t, err, "bundletool jar not found")
+}
+
+func TestProvider_Provide_cache(t *testing.T) {
+	var downloads int
+	config := Config{Version: "1.15.0", CacheDir: t.TempDir(), SHA256: jarChecksum()}
+	cachedPth := filepath.Join(config.CacheDir, "1.15.0", jarName)
+
+	pth, err := newProvider(t, config, &downloads).Provide()
+	require.NoError(t, err)
+	require.Equal(t, bundletool.Path(cachedPth), pth)
+	require.Equal(t, 1, downloads)
+
+	pth, err = newProvider(t, config, &downloads).Provide()
+	require.NoError(t, err)
+	require.Equal(t, bundletool.Path(cachedPth), pth)
+	require.Equal(t, 1, downloads)
+
+	// A corrupt cached jar is downloaded again.
+	require.NoError(t, os.WriteFile(cachedPth, []byte("truncated"), 0644))
+	pth, err = newProvider(t, config, &downloads).Provide()
+	require.NoError(t, err)
+	require.Equal(t, bundletool.Path(cachedPth), pth)
+	require.Equal(t, 2, downloads)
+
+	content, err := os.ReadFile(cachedPth)
+	require.NoError(t, err)
+	require.Equal(t, jarContent, string(content))
+}
+
+func TestProvider_Provide_download(t *testing.T) {
+	var downloads int
+	_, err := newProvider(t, Config{Version: "1.15.0", SHA256: "DEADBEEF"}, &downloads).Provide()
+	require.ErrorContains(t, err, "expected SHA-256 DEADBEEF, got "+jarChecksum())
+
+	provider := newProvider(t, Config{Version: "1.15.0"}, &downloads)
+	provider.download = func(version string) (bundletool.Path, error) {
+		return "", errors.New("connection refused")
+	}
+	_, err = provider.Provide()
+	require.EqualError(t, err, "failed to download bundletool 1.15.0: connection refused")
+}
+
+func TestProvider_Provide_javaNotFound(t *testing.T) {
+	var downloads int
+	provider := newProvider(t, Config{Version: "1.15.0"}, &downloads)
+	provider.lookPath = func(string) (string, error) { return "", errors.New("executable file not found in $PATH") }
+
+	_, err := provider.Provide()
+	require.ErrorIs(t, err, ErrJavaNotFound)
+	require.Zero(t, downloads)
+}
@@ -0,0 +1,249 @@
+// Package debugsymbols detects and validates the debug symbols of app artifacts:
+// dSYMs of Apple apps, and ProGuard/R8 mapping files and native debug symbols of Android apps.
+package debugsymbols
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Type is the kind of debug symbols.
+type Type string
+
+// Debug symbol types.
+const (
+	DSYM            Type = "dsym"
+	ProguardMapping Type = "proguard-mapping"
+	NativeSymbols   Type = "native-symbols"
+)
+
+const (
+	dsymSuffix          = ".dsym.zip"
+	mappingName         = "mapping.txt"
+	mappingSuffix       = "-" + mappingName
+	nativeSymbolsSuffix = "native-debug-symbols.zip"
+)
+
+var (
+	appleArtifactExts   = []string{".xcarchive.zip", ".app.zip", ".ipa", ".pkg", ".dmg"}
+	androidArtifactExts = []string{".apks", ".xapk", ".apk", ".aab"}
+	nativeLibraryExts   = []string{".so", ".so.dbg", ".so.sym"}
+)
+
+// Metadata describes a debug symbols file, it is sent as the artifact info of the uploaded symbols.
+type Metadata struct {
+	SymbolsType      Type   `json:"symbols_type"`
+	ParentArtifactID string `json:"parent_artifact_id,omitempty"`
+	ParentFileName   string `json:"parent_file_name,omitempty"`
+	FileSizeBytes    int64  `json:"file_size_bytes"`
+	// UUIDs are the build UUIDs of the binaries described by a dSYM.
+	UUIDs []UUID `json:"uuids,omitempty"`
+	// Libraries are the native libraries (<ABI>/<library>) of the native debug symbols.
+	Libraries []string `json:"libraries,omitempty"`
+}
+
+// UUID is the build UUID of a single architecture of a binary.
+type UUID struct {
+	UUID   string `json:"uuid"`
+	Arch   string `json:"arch"`
+	Binary string `json:"binary"`
+}
+
+// Detect returns the type of the debug symbols file based on its name:
+// *.dSYM.zip, *native-debug-symbols.zip, mapping.txt or <artifact>-mapping.txt,
+// where <artifact> is the name of one of the APKs or AABs without its extension.
+func Detect(pth string, artifactPths []string) (Type, bool) {
+	name := strings.ToLower(filepath.Base(pth))
+	switch {
+	case strings.HasSuffix(name, dsymSuffix):
+		return DSYM, true
+	case name == mappingName || isArtifactMapping(name, artifactPths):
+		return ProguardMapping, true
+	case strings.HasSuffix(name, nativeSymbolsSuffix):
+		return NativeSymbols, true
+	default:
+		return "", false
+	}
+}
+
+// DetectByContent returns the type of an explicitly listed debug symbols file,
+// falling back to its content if the name is not a known debug symbols file name.
+func DetectByContent(pth string) (Type, error) {
+	if symbolsType, ok := Detect(pth, nil); ok {
+		return symbolsType, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(pth)) {
+	case ".txt":
+		return ProguardMapping, nil
+	case ".zip":
+		reader, err := zip.OpenReader(pth)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+
+		for _, file := range reader.File {
+			if isDWARFFile(file.Name) {
+				return DSYM, nil
+			}
+		}
+		return NativeSymbols, nil
+	default:
+		return "", fmt.Errorf("unknown debug symbols file: %s", filepath.Base(pth))
+	}
+}
+
+// Read validates the debug symbols file and returns its metadata.
+func Read(pth string, symbolsType Type) (Metadata, error) {
+	metadata := Metadata{SymbolsType: symbolsType}
+
+	var err error
+	switch symbolsType {
+	case DSYM:
+		metadata.UUIDs, err = readDSYMUUIDs(pth)
+	case ProguardMapping:
+		err = validateMapping(pth)
+	case NativeSymbols:
+		metadata.Libraries, err = readNativeLibraries(pth)
+	default:
+		err = fmt.Errorf("unknown debug symbols type: %s", symbolsType)
+	}
+	if err != nil {
+		return Metadata{}, fmt.Errorf("invalid %s file (%s): %w", symbolsType, pth, err)
+	}
+
+	return metadata, nil
+}
+
+// FindParent returns the artifact the debug symbols belong to:
+// the artifact of the symbols' platform with the same name, or the only artifact of the platform.
+func FindParent(pth string, symbolsType Type, artifactPths []string) (string, bool) {
+	artifactExts := androidArtifactExts
+	if symbolsType == DSYM {
+		artifactExts = appleArtifactExts
+	}
+
+	var candidates []string
+	for _, artifactPth := range artifactPths {
+		if _, ok := trimExt(filepath.Base(artifactPth), artifactExts); ok {
+			candidates = append(candidates, artifactPth)
+		}
+	}
+
+	if stem := symbolsStem(filepath.Base(pth)); stem != "" {
+		for _, candidate := range candidates {
+			if artifactStem, _ := trimExt(filepath.Base(candidate), artifactExts); artifactStem == stem {
+				return candidate, true
+			}
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	return "", false
+}
+
+// symbolsStem returns the symbols file name without its known suffix,
+// for example MyApp for MyApp.app.dSYM.zip and app-release for app-release-mapping.txt.
+func symbolsStem(name string) string {
+	lowerName := strings.ToLower(name)
+	for _, suffix := range []string{dsymSuffix, mappingSuffix, mappingName, nativeSymbolsSuffix} {
+		if strings.HasSuffix(lowerName, suffix) {
+			name = name[:len(name)-len(suffix)]
+			break
+		}
+	}
+	name = strings.TrimSuffix(name, ".app")
+
+	return strings.TrimRight(name, "-_.")
+}
+
+// isArtifactMapping reports whether the lowercase file name is the <artifact>-mapping.txt of one of the Android artifacts,
+// other files ending with mapping.txt (like url-mapping.txt) are not mapping files.
+func isArtifactMapping(name string, artifactPths []string) bool {
+	stem, ok := strings.CutSuffix(name, mappingSuffix)
+	if !ok || stem == "" {
+		return false
+	}
+
+	for _, artifactPth := range artifactPths {
+		if artifactStem, ok := trimExt(filepath.Base(artifactPth), androidArtifactExts); ok && strings.EqualFold(artifactStem, stem) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func trimExt(name string, exts []string) (string, bool) {
+	lowerName := strings.ToLower(name)
+	for _, ext := range exts {
+		if strings.HasSuffix(lowerName, ext) {
+			return name[:len(name)-len(ext)], true
+		}
+	}
+
+	return name, false
+}
+
+func validateMapping(pth string) error {
+	file, err := os.Open(pth)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	reader := bufio.NewReader(file)
+	for {
+		r, _, err := reader.ReadRune()
+		if errors.Is(err, io.EOF) {
+			return errors.New("mapping file is empty")
+		}
+		if err != nil {
+			return err
+		}
+		if !unicode.IsSpace(r) {
+			return nil
+		}
+	}
+}
+
+func readNativeLibraries(pth string) ([]string, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	var libraries []string
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if _, ok := trimExt(path.Base(file.Name), nativeLibraryExts); ok {
+			libraries = append(libraries, file.Name)
+		}
+	}
+	if len(libraries) == 0 {
+		return nil, errors.New("no native libraries found")
+	}
+
+	return libraries, nil
+}
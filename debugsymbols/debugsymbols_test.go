@@ -0,0 +1,217 @@
+package debugsymbols
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	arm64UUID  = []byte{0x1f, 0x2e, 0x3d, 0x4c, 0x5b, 0x6a, 0x79, 0x88, 0x97, 0xa6, 0xb5, 0xc4, 0xd3, 0xe2, 0xf1, 0x00}
+	x86_64UUID = []byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+)
+
+// machOFile returns a 64-bit Mach-O file with a segment and an LC_UUID load command.
+func machOFile(cpuType, fileType uint32, uuid []byte) []byte {
+	segment := make([]byte, 72)
+	binary.LittleEndian.PutUint32(segment[0:], 0x19)
+	binary.LittleEndian.PutUint32(segment[4:], uint32(len(segment)))
+	copy(segment[8:], "__DWARF")
+
+	uuidCommand := make([]byte, 24)
+	binary.LittleEndian.PutUint32(uuidCommand[0:], lcUUID)
+	binary.LittleEndian.PutUint32(uuidCommand[4:], uint32(len(uuidCommand)))
+	copy(uuidCommand[8:], uuid)
+
+	header := make([]byte, 32)
+	binary.LittleEndian.PutUint32(header[0:], machOMagic64)
+	binary.LittleEndian.PutUint32(header[4:], cpuType)
+	binary.LittleEndian.PutUint32(header[12:], fileType)
+	binary.LittleEndian.PutUint32(header[16:], 2)
+	binary.LittleEndian.PutUint32(header[20:], uint32(len(segment)+len(uuidCommand)))
+
+	file := append(header, segment...)
+	file = append(file, uuidCommand...)
+	return append(file, []byte("DWARF debug info")...)
+}
+
+// fatFile returns a fat Mach-O file of the given architecture slices.
+func fatFile(cpuTypes []uint32, slices [][]byte) []byte {
+	const align = 64
+	header := make([]byte, 8+20*len(slices))
+	binary.BigEndian.PutUint32(header[0:], fatMagic)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(slices)))
+
+	var content []byte
+	for i, slice := range slices {
+		for (len(header)+len(content))%align != 0 {
+			content = append(content, 0)
+		}
+		arch := header[8+20*i:]
+		binary.BigEndian.PutUint32(arch[0:], cpuTypes[i])
+		binary.BigEndian.PutUint32(arch[8:], uint32(len(header)+len(content)))
+		binary.BigEndian.PutUint32(arch[12:], uint32(len(slice)))
+		content = append(content, slice...)
+	}
+
+	return append(header, content...)
+}
+
+func writeZip(t *testing.T, name string, files map[string][]byte) string {
+	pth := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(pth)
+	require.NoError(t, err)
+
+	writer := zip.NewWriter(file)
+	for fileName, content := range files {
+		fileWriter, err := writer.Create(fileName)
+		require.NoError(t, err)
+		_, err = fileWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+
+	return pth
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	pth := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(pth, []byte(content), 0644))
+	return pth
+}
+
+func TestDetect(t *testing.T) {
+	artifacts := []string{"/deploy/app-release.aab", "/deploy/app-debug.apk"}
+
+	tests := []struct {
+		pth    string
+		want   Type
+		wantOk bool
+	}{
+		{pth: "/deploy/MyApp.app.dSYM.zip", want: DSYM, wantOk: true},
+		{pth: "/deploy/MyApp.dsym.zip", want: DSYM, wantOk: true},
+		{pth: "/deploy/mapping.txt", want: ProguardMapping, wantOk: true},
+		{pth: "/deploy/app-release-mapping.txt", want: ProguardMapping, wantOk: true},
+		{pth: "/deploy/App-Debug-Mapping.txt", want: ProguardMapping, wantOk: true},
+		{pth: "/deploy/native-debug-symbols.zip", want: NativeSymbols, wantOk: true},
+		{pth: "/deploy/url-mapping.txt"},
+		{pth: "/deploy/app-staging-mapping.txt"},
+		{pth: "/deploy/-mapping.txt"},
+		{pth: "/deploy/app-release.apk"},
+		{pth: "/deploy/MyApp.zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pth, func(t *testing.T) {
+			got, ok := Detect(tt.pth, artifacts)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectByContent(t *testing.T) {
+	dsymPth := writeZip(t, "symbols.zip", map[string][]byte{
+		"MyApp.app.dSYM/Contents/Resources/DWARF/MyApp": machOFile(0x0100000c, mhDSYM, arm64UUID),
+	})
+	symbolsType, err := DetectByContent(dsymPth)
+	require.NoError(t, err)
+	require.Equal(t, DSYM, symbolsType)
+
+	nativePth := writeZip(t, "symbols.zip", map[string][]byte{"arm64-v8a/libapp.so.dbg": []byte("symbols")})
+	symbolsType, err = DetectByContent(nativePth)
+	require.NoError(t, err)
+	require.Equal(t, NativeSymbols, symbolsType)
+
+	symbolsType, err = DetectByContent(writeFile(t, "r8.txt", "a -> b:"))
+	require.NoError(t, err)
+	require.Equal(t, ProguardMapping, symbolsType)
+
+	_, err = DetectByContent("/deploy/symbols.bin")
+	require.Error(t, err)
+}
+
+func TestRead_dsym(t *testing.T) {
+	pth := writeZip(t, "MyApp.app.dSYM.zip", map[string][]byte{
+		"MyApp.app.dSYM/Contents/Info.plist":                       []byte("plist"),
+		"MyApp.app.dSYM/Contents/Resources/DWARF/MyApp":            fatFile([]uint32{0x01000007, 0x0100000c}, [][]byte{machOFile(0x01000007, mhDSYM, x86_64UUID), machOFile(0x0100000c, mhDSYM, arm64UUID)}),
+		"MyFramework.framework.dSYM/Contents/Resources/DWARF/Core": machOFile(0x0100000c, mhDSYM, x86_64UUID),
+		"__MACOSX/MyApp.app.dSYM/Contents/Resources/DWARF/._MyApp": []byte("resource fork"),
+	})
+
+	got, err := Read(pth, DSYM)
+	require.NoError(t, err)
+	require.Equal(t, DSYM, got.SymbolsType)
+	require.ElementsMatch(t, []UUID{
+		{UUID: "00112233-4455-6677-8899-AABBCCDDEEFF", Arch: "x86_64", Binary: "MyApp"},
+		{UUID: "1F2E3D4C-5B6A-7988-97A6-B5C4D3E2F100", Arch: "arm64", Binary: "MyApp"},
+		{UUID: "00112233-4455-6677-8899-AABBCCDDEEFF", Arch: "arm64", Binary: "Core"},
+	}, got.UUIDs)
+}
+
+func TestRead_invalid(t *testing.T) {
+	_, err := Read(writeZip(t, "MyApp.dSYM.zip", map[string][]byte{"MyApp.dSYM/Contents/Info.plist": []byte("plist")}), DSYM)
+	require.ErrorContains(t, err, "no DWARF files found")
+
+	_, err = Read(writeZip(t, "MyApp.dSYM.zip", map[string][]byte{
+		"MyApp.dSYM/Contents/Resources/DWARF/MyApp": machOFile(0x0100000c, 0x2, arm64UUID),
+	}), DSYM)
+	require.ErrorContains(t, err, "not a dSYM companion file")
+
+	_, err = Read(writeFile(t, "mapping.txt", " \n\t\n"), ProguardMapping)
+	require.ErrorContains(t, err, "mapping file is empty")
+
+	_, err = Read(writeZip(t, "native-debug-symbols.zip", map[string][]byte{"README.md": []byte("readme")}), NativeSymbols)
+	require.ErrorContains(t, err, "no native libraries found")
+}
+
+func TestRead_android(t *testing.T) {
+	got, err := Read(writeFile(t, "mapping.txt", "io.bitrise.App -> a:\n"), ProguardMapping)
+	require.NoError(t, err)
+	require.Equal(t, Metadata{SymbolsType: ProguardMapping}, got)
+
+	got, err = Read(writeZip(t, "native-debug-symbols.zip", map[string][]byte{
+		"arm64-v8a/libapp.so":       []byte("symbols"),
+		"x86_64/libapp.so.dbg":      []byte("symbols"),
+		"armeabi-v7a/libapp.so.sym": []byte("symbols"),
+		"arm64-v8a/":                nil,
+	}), NativeSymbols)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"arm64-v8a/libapp.so", "x86_64/libapp.so.dbg", "armeabi-v7a/libapp.so.sym"}, got.Libraries)
+}
+
+func TestFindParent(t *testing.T) {
+	artifacts := []string{
+		"/deploy/app-release.aab",
+		"/deploy/app-debug.apk",
+		"/deploy/MyApp.ipa",
+		"/deploy/README.md",
+	}
+
+	tests := []struct {
+		name        string
+		pth         string
+		symbolsType Type
+		artifacts   []string
+		want        string
+		wantOk      bool
+	}{
+		{name: "mapping matched by name", pth: "/deploy/app-release-mapping.txt", symbolsType: ProguardMapping, artifacts: artifacts, want: "/deploy/app-release.aab", wantOk: true},
+		{name: "mapping of ambiguous variant", pth: "/deploy/mapping.txt", symbolsType: ProguardMapping, artifacts: artifacts},
+		{name: "native symbols of the only Android artifact", pth: "/deploy/native-debug-symbols.zip", symbolsType: NativeSymbols, artifacts: []string{"/deploy/app-release.aab", "/deploy/MyApp.ipa"}, want: "/deploy/app-release.aab", wantOk: true},
+		{name: "dSYM matched by name", pth: "/deploy/MyApp.app.dSYM.zip", symbolsType: DSYM, artifacts: append(artifacts, "/deploy/Other.ipa"), want: "/deploy/MyApp.ipa", wantOk: true},
+		{name: "dSYM of the only Apple artifact", pth: "/deploy/Runner.app.dSYM.zip", symbolsType: DSYM, artifacts: artifacts, want: "/deploy/MyApp.ipa", wantOk: true},
+		{name: "dSYM without Apple artifacts", pth: "/deploy/MyApp.app.dSYM.zip", symbolsType: DSYM, artifacts: []string{"/deploy/app-release.aab"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindParent(tt.pth, tt.symbolsType, tt.artifacts)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package debugsymbols
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Mach-O constants, based on: https://github.com/apple-oss-distributions/xnu/blob/main/EXTERNAL_HEADERS/mach-o/loader.h
+const (
+	machOMagic32 = 0xfeedface
+	machOMagic64 = 0xfeedfacf
+	fatMagic     = 0xcafebabe
+	fatMagic64   = 0xcafebabf
+
+	// mhDSYM is the file type of dSYM companion files.
+	mhDSYM = 0xa
+	lcUUID = 0x1b
+
+	maxFatArchs          = 64
+	maxLoadCommandsBytes = 16 * 1024 * 1024
+)
+
+const dwarfDir = ".dSYM/Contents/Resources/DWARF/"
+
+var cpuTypeNames = map[uint32]string{
+	7:          "i386",
+	0x01000007: "x86_64",
+	12:         "arm",
+	0x0100000c: "arm64",
+	0x0200000c: "arm64_32",
+}
+
+func isDWARFFile(name string) bool {
+	return strings.Contains(name, dwarfDir) && !strings.HasSuffix(name, "/") && !strings.HasPrefix(name, "__MACOSX/")
+}
+
+// readDSYMUUIDs returns the UUIDs of the DWARF files of every dSYM in the zip.
+func readDSYMUUIDs(pth string) ([]UUID, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	var uuids []UUID
+	for _, file := range reader.File {
+		if !isDWARFFile(file.Name) {
+			continue
+		}
+
+		binaryUUIDs, err := readZipFileUUIDs(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		uuids = append(uuids, binaryUUIDs...)
+	}
+	if len(uuids) == 0 {
+		return nil, errors.New("no DWARF files found")
+	}
+
+	return uuids, nil
+}
+
+func readZipFileUUIDs(file *zip.File) ([]UUID, error) {
+	content, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = content.Close()
+	}()
+
+	uuids, err := machOUUIDs(content)
+	if err != nil {
+		return nil, err
+	}
+	for i := range uuids {
+		uuids[i].Binary = path.Base(file.Name)
+	}
+
+	return uuids, nil
+}
+
+// streamReader reads a Mach-O file sequentially, as zip entries can not be read at random offsets,
+// and DWARF files are too large to be read into memory.
+type streamReader struct {
+	reader io.Reader
+	offset int64
+}
+
+func (r *streamReader) read(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		return nil, err
+	}
+	r.offset += int64(n)
+
+	return buf, nil
+}
+
+func (r *streamReader) skipTo(offset int64) error {
+	if offset < r.offset {
+		return fmt.Errorf("overlapping architectures at offset %d", offset)
+	}
+
+	n, err := io.CopyN(io.Discard, r.reader, offset-r.offset)
+	r.offset += n
+
+	return err
+}
+
+// machOUUIDs returns the UUID of each architecture of a thin or fat (universal) Mach-O dSYM companion file.
+func machOUUIDs(reader io.Reader) ([]UUID, error) {
+	stream := &streamReader{reader: reader}
+	magic, err := stream.read(4)
+	if err != nil {
+		return nil, err
+	}
+
+	fatMagicValue := binary.BigEndian.Uint32(magic)
+	if fatMagicValue != fatMagic && fatMagicValue != fatMagic64 {
+		uuid, err := machOUUID(stream, magic)
+		if err != nil {
+			return nil, err
+		}
+		return []UUID{uuid}, nil
+	}
+
+	header, err := stream.read(4)
+	if err != nil {
+		return nil, err
+	}
+	archCount := binary.BigEndian.Uint32(header)
+	if archCount > maxFatArchs {
+		return nil, fmt.Errorf("too many architectures: %d", archCount)
+	}
+
+	archSize := 20
+	if fatMagicValue == fatMagic64 {
+		archSize = 32
+	}
+
+	var offsets []int64
+	for i := uint32(0); i < archCount; i++ {
+		arch, err := stream.read(archSize)
+		if err != nil {
+			return nil, err
+		}
+		if fatMagicValue == fatMagic64 {
+			offsets = append(offsets, int64(binary.BigEndian.Uint64(arch[8:])))
+		} else {
+			offsets = append(offsets, int64(binary.BigEndian.Uint32(arch[8:])))
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	var uuids []UUID
+	for _, offset := range offsets {
+		if err := stream.skipTo(offset); err != nil {
+			return nil, err
+		}
+		magic, err := stream.read(4)
+		if err != nil {
+			return nil, err
+		}
+		uuid, err := machOUUID(stream, magic)
+		if err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, uuid)
+	}
+
+	return uuids, nil
+}
+
+// machOUUID reads the LC_UUID load command of a thin Mach-O file, the stream is positioned after the magic.
+func machOUUID(stream *streamReader, magic []byte) (UUID, error) {
+	var order binary.ByteOrder
+	headerSize := 24
+	switch {
+	case binary.LittleEndian.Uint32(magic) == machOMagic32:
+		order = binary.LittleEndian
+	case binary.LittleEndian.Uint32(magic) == machOMagic64:
+		order = binary.LittleEndian
+		headerSize = 28
+	case binary.BigEndian.Uint32(magic) == machOMagic32:
+		order = binary.BigEndian
+	case binary.BigEndian.Uint32(magic) == machOMagic64:
+		order = binary.BigEndian
+		headerSize = 28
+	default:
+		return UUID{}, errors.New("not a Mach-O file")
+	}
+
+	header, err := stream.read(headerSize)
+	if err != nil {
+		return UUID{}, err
+	}
+	cpuType := order.Uint32(header[0:])
+	fileType := order.Uint32(header[8:])
+	commandCount := order.Uint32(header[12:])
+	commandsSize := order.Uint32(header[16:])
+
+	if fileType != mhDSYM {
+		return UUID{}, fmt.Errorf("not a dSYM companion file, file type: %#x", fileType)
+	}
+	if commandsSize > maxLoadCommandsBytes {
+		return UUID{}, fmt.Errorf("load commands are too large: %d bytes", commandsSize)
+	}
+
+	commands, err := stream.read(int(commandsSize))
+	if err != nil {
+		return UUID{}, err
+	}
+	for i := uint32(0); i < commandCount && len(commands) >= 8; i++ {
+		command := order.Uint32(commands[0:])
+		size := order.Uint32(commands[4:])
+		if size < 8 || int(size) > len(commands) {
+			return UUID{}, fmt.Errorf("malformed load command at index %d", i)
+		}
+		if command == lcUUID && size >= 24 {
+			return UUID{UUID: formatUUID(commands[8:24]), Arch: cpuTypeName(cpuType)}, nil
+		}
+		commands = commands[size:]
+	}
+
+	return UUID{}, errors.New("LC_UUID load command not found")
+}
+
+func formatUUID(uuid []byte) string {
+	return fmt.Sprintf("%X-%X-%X-%X-%X", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+}
+
+func cpuTypeName(cpuType uint32) string {
+	if name, ok := cpuTypeNames[cpuType]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("cputype(%d)", cpuType)
+}
@@ -25,6 +25,13 @@ type DeployableItem struct {
 	Path                 string
 	ArchiveAsArtifact    bool
 	IntermediateFileMeta *IntermediateFileMetaData
+
+	// SourcePath is the directory which was compressed into the file at Path, it is empty if the file is deployed as is.
+	SourcePath string
+	// GeneratedFrom is the path of the artifact the file was generated from by the Step, like the AAB of a universal APK.
+	GeneratedFrom string
+	// CustomMetadata is read from the item's metadata sidecar file, see AttachCustomMetadata.
+	CustomMetadata map[string]interface{}
 }
 
 func (d *DeployableItem) IsIntermediateFile() bool {
@@ -213,6 +220,7 @@ func (c Collector) zipDirectories(items []DeployableItem) ([]DeployableItem, err
 				return nil, err
 			}
 
+			items[i].SourcePath = item.Path
 			items[i].Path = path
 		}
 	}
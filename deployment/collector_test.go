@@ -70,21 +70,24 @@ func Test_GivenIntermediateFiles_WhenProcessing_ThenConvertsCorrectly(t *testing
 			list: "/output_folder:OUTPUT_FOLDER" + "\n" + "./local/build:BUILD_DIRECTORY" + "\n" + "folder:JUST_A_FOLDER",
 			want: []DeployableItem{
 				{
-					Path: filepath.Join(tempDir, "output_folder.zip"),
+					Path:       filepath.Join(tempDir, "output_folder.zip"),
+					SourcePath: "/output_folder",
 					IntermediateFileMeta: &IntermediateFileMetaData{
 						EnvKey: "OUTPUT_FOLDER",
 						IsDir:  true,
 					},
 				},
 				{
-					Path: filepath.Join(tempDir, "build.zip"),
+					Path:       filepath.Join(tempDir, "build.zip"),
+					SourcePath: filepath.Join(currentDir, "local/build"),
 					IntermediateFileMeta: &IntermediateFileMetaData{
 						EnvKey: "BUILD_DIRECTORY",
 						IsDir:  true,
 					},
 				},
 				{
-					Path: filepath.Join(tempDir, "folder.zip"),
+					Path:       filepath.Join(tempDir, "folder.zip"),
+					SourcePath: filepath.Join(currentDir, "folder"),
 					IntermediateFileMeta: &IntermediateFileMetaData{
 						EnvKey: "JUST_A_FOLDER",
 						IsDir:  true,
@@ -328,7 +331,8 @@ func Test_GivenDeployDirectories_WhenIntermediateDirectoriesSpecified_ThenMerges
 			intermediateFiles: "/dir:DIR_PATH",
 			want: []DeployableItem{
 				{
-					Path: filepath.Join(tempDir, "dir.zip"),
+					Path:       filepath.Join(tempDir, "dir.zip"),
+					SourcePath: "/dir",
 					IntermediateFileMeta: &IntermediateFileMetaData{
 						EnvKey: "DIR_PATH",
 						IsDir:  true,
@@ -0,0 +1,96 @@
+package deployment
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+)
+
+const (
+	// MetadataSidecarSuffix is the suffix of the files which hold the custom metadata of the file next to them,
+	// for example app.apk.meta.json holds the metadata of app.apk.
+	MetadataSidecarSuffix = ".meta.json"
+
+	maxCustomMetadataSizeInBytes = 64 * 1024
+)
+
+// AttachCustomMetadata reads the metadata sidecar file of every item, and removes the sidecar files from the items,
+// so that they are not deployed as standalone Build Artifacts.
+func AttachCustomMetadata(items []DeployableItem, logger log.Logger) ([]DeployableItem, error) {
+	var result []DeployableItem
+	for _, item := range items {
+		if isMetadataSidecar(item.Path) || (item.SourcePath != "" && isMetadataSidecar(item.SourcePath)) {
+			if item.IntermediateFileMeta == nil {
+				logger.Printf("Skipping metadata file: %s", item.Path)
+				continue
+			}
+
+			// Explicitly listed Pipeline intermediate files are kept, but not as Build Artifacts.
+			item.ArchiveAsArtifact = false
+		}
+
+		sidecarPath := item.Path + MetadataSidecarSuffix
+		if item.SourcePath != "" {
+			sidecarPath = item.SourcePath + MetadataSidecarSuffix
+		}
+
+		metadata, err := readCustomMetadata(sidecarPath)
+		if err != nil {
+			return nil, err
+		}
+		item.CustomMetadata = metadata
+
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// isMetadataSidecar reports whether the path is the metadata sidecar file of an existing file.
+func isMetadataSidecar(pth string) bool {
+	original, ok := strings.CutSuffix(pth, MetadataSidecarSuffix)
+	if !ok || original == "" {
+		return false
+	}
+
+	_, err := os.Stat(original)
+	return err == nil
+}
+
+// readCustomMetadata reads and validates a metadata sidecar file, it returns nil if the file doesn't exist.
+func readCustomMetadata(pth string) (map[string]interface{}, error) {
+	info, err := os.Stat(pth)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check metadata file (%s): %w", pth, err)
+	}
+	if info.Size() > maxCustomMetadataSizeInBytes {
+		return nil, fmt.Errorf("metadata file (%s) is larger than %d bytes", pth, maxCustomMetadataSizeInBytes)
+	}
+
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file (%s): %w", pth, err)
+	}
+
+	var metadata map[string]interface{}
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return nil, fmt.Errorf("metadata file (%s) is not a JSON object: %w", pth, err)
+	}
+	for key := range metadata {
+		if strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("metadata file (%s) contains an empty key", pth)
+		}
+	}
+
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,72 @@
+package deployment
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachCustomMetadata(t *testing.T) {
+	dir := t.TempDir()
+	apkPath := filepath.Join(dir, "app.apk")
+	ipaPath := filepath.Join(dir, "app.ipa")
+	sidecarPath := apkPath + MetadataSidecarSuffix
+	orphanSidecarPath := filepath.Join(dir, "missing.aab"+MetadataSidecarSuffix)
+	for _, pth := range []string{apkPath, ipaPath} {
+		require.NoError(t, os.WriteFile(pth, []byte("app"), 0600))
+	}
+	require.NoError(t, os.WriteFile(sidecarPath, []byte(`{"release_channel": "beta", "qa_ticket": 42}`), 0600))
+	require.NoError(t, os.WriteFile(orphanSidecarPath, []byte(`{}`), 0600))
+
+	items := ConvertPaths([]string{apkPath, sidecarPath, ipaPath, orphanSidecarPath})
+	got, err := AttachCustomMetadata(items, log.NewLogger())
+	require.NoError(t, err)
+
+	require.Equal(t, []DeployableItem{
+		{
+			Path:              apkPath,
+			ArchiveAsArtifact: true,
+			CustomMetadata:    map[string]interface{}{"release_channel": "beta", "qa_ticket": float64(42)},
+		},
+		{Path: ipaPath, ArchiveAsArtifact: true},
+		{Path: orphanSidecarPath, ArchiveAsArtifact: true},
+	}, got)
+}
+
+func TestAttachCustomMetadata_intermediateSidecar(t *testing.T) {
+	dir := t.TempDir()
+	apkPath := filepath.Join(dir, "app.apk")
+	sidecarPath := apkPath + MetadataSidecarSuffix
+	require.NoError(t, os.WriteFile(apkPath, []byte("app"), 0600))
+	require.NoError(t, os.WriteFile(sidecarPath, []byte(`{"channel": "beta"}`), 0600))
+
+	meta := &IntermediateFileMetaData{EnvKey: "APK_META"}
+	got, err := AttachCustomMetadata([]DeployableItem{{Path: sidecarPath, ArchiveAsArtifact: true, IntermediateFileMeta: meta}}, log.NewLogger())
+	require.NoError(t, err)
+	require.Equal(t, []DeployableItem{{Path: sidecarPath, ArchiveAsArtifact: false, IntermediateFileMeta: meta}}, got)
+}
+
+func TestAttachCustomMetadata_invalidMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not JSON", content: "channel=beta", wantErr: "is not a JSON object"},
+		{name: "JSON array", content: `["beta"]`, wantErr: "is not a JSON object"},
+		{name: "empty key", content: `{" ": "beta"}`, wantErr: "contains an empty key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apkPath := filepath.Join(t.TempDir(), "app.apk")
+			require.NoError(t, os.WriteFile(apkPath, []byte("app"), 0600))
+			require.NoError(t, os.WriteFile(apkPath+MetadataSidecarSuffix, []byte(tt.content), 0600))
+
+			_, err := AttachCustomMetadata(ConvertPaths([]string{apkPath}), log.NewLogger())
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package deployrules overrides the notification and public install page settings of the deployed artifacts,
+// based on their file name or artifact type.
+package deployrules
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are the notification and public install page settings of an artifact.
+type Settings struct {
+	NotifyUserGroups       string
+	AlwaysNotifyUserGroups string
+	NotifyEmails           string
+	IsEnablePublicPage     bool
+}
+
+// Rule overrides the settings of the artifacts matching both its pattern and artifact type,
+// the fields which are not set keep the default settings.
+type Rule struct {
+	// Pattern is a glob pattern matched against the file name. A relative pattern with a path separator is matched against
+	// the same number of trailing path elements, an absolute pattern against the whole path.
+	Pattern                string  `yaml:"pattern"`
+	ArtifactType           string  `yaml:"artifact_type"`
+	NotifyUserGroups       *string `yaml:"notify_user_groups"`
+	AlwaysNotifyUserGroups *string `yaml:"always_notify_user_groups"`
+	NotifyEmailList        *string `yaml:"notify_email_list"`
+	IsEnablePublicPage     *bool   `yaml:"is_enable_public_page"`
+}
+
+// Parse parses the YAML list of rules, artifactTypes are the artifact types the rules can match.
+func Parse(content string, artifactTypes []string) ([]Rule, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	decoder.KnownFields(true)
+
+	var rules []Rule
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+
+	for i, rule := range rules {
+		if err := rule.validate(artifactTypes); err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i+1, err)
+		}
+	}
+
+	return rules, nil
+}
+
+func (r Rule) validate(artifactTypes []string) error {
+	if r.Pattern == "" && r.ArtifactType == "" {
+		return errors.New("pattern or artifact_type is required")
+	}
+	if r.Pattern != "" {
+		if _, err := filepath.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern (%s): %w", r.Pattern, err)
+		}
+	}
+	if r.ArtifactType != "" && !slices.Contains(artifactTypes, r.ArtifactType) {
+		return fmt.Errorf("invalid artifact_type (%s), valid values: %s", r.ArtifactType, strings.Join(artifactTypes, ", "))
+	}
+
+	return nil
+}
+
+// Matches reports whether the rule applies to the artifact.
+func (r Rule) Matches(pth, artifactType string) bool {
+	if r.ArtifactType != "" && r.ArtifactType != artifactType {
+		return false
+	}
+	if r.Pattern == "" {
+		return true
+	}
+
+	pattern := filepath.Clean(r.Pattern)
+	name := filepath.Base(pth)
+	if filepath.IsAbs(pattern) {
+		name = filepath.Clean(pth)
+	} else if strings.ContainsRune(pattern, filepath.Separator) {
+		name = pathSuffix(filepath.Clean(pth), strings.Count(pattern, string(filepath.Separator))+1)
+	}
+	matched, err := filepath.Match(pattern, name)
+
+	return err == nil && matched
+}
+
+// pathSuffix returns the last n elements of the path, or the whole path if it has fewer elements.
+func pathSuffix(pth string, n int) string {
+	elements := strings.Split(pth, string(filepath.Separator))
+	if len(elements) <= n {
+		return pth
+	}
+	return filepath.Join(elements[len(elements)-n:]...)
+}
+
+// Resolve returns the settings of the artifact: the defaults overridden by the first matching rule,
+// and the index of the matching rule, or -1 if no rule matches.
+func Resolve(rules []Rule, defaults Settings, pth, artifactType string) (Settings, int) {
+	for i, rule := range rules {
+		if !rule.Matches(pth, artifactType) {
+			continue
+		}
+
+		settings := defaults
+		if rule.NotifyUserGroups != nil {
+			settings.NotifyUserGroups = *rule.NotifyUserGroups
+		}
+		if rule.AlwaysNotifyUserGroups != nil {
+			settings.AlwaysNotifyUserGroups = *rule.AlwaysNotifyUserGroups
+		}
+		if rule.NotifyEmailList != nil {
+			settings.NotifyEmails = *rule.NotifyEmailList
+		}
+		if rule.IsEnablePublicPage != nil {
+			settings.IsEnablePublicPage = *rule.IsEnablePublicPage
+		}
+
+		return settings, i
+	}
+
+	return defaults, -1
+}
@@ -0,0 +1,127 @@
+package deployrules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var artifactTypes = []string{"apk", "ipa"}
+
+func TestParse(t *testing.T) {
+	rules, err := Parse(`
+- pattern: "*-qa.*"
+  notify_user_groups: testers
+  notify_email_list: qa@example.com
+  is_enable_public_page: true
+- artifact_type: ipa
+  is_enable_public_page: false
+`, artifactTypes)
+	require.NoError(t, err)
+
+	testers, email, enabled, disabled := "testers", "qa@example.com", true, false
+	require.Equal(t, []Rule{
+		{Pattern: "*-qa.*", NotifyUserGroups: &testers, NotifyEmailList: &email, IsEnablePublicPage: &enabled},
+		{ArtifactType: "ipa", IsEnablePublicPage: &disabled},
+	}, rules)
+
+	rules, err = Parse("  \n", artifactTypes)
+	require.NoError(t, err)
+	require.Empty(t, rules)
+}
+
+func TestParse_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "not a list", content: "pattern: '*.apk'", wantErr: "failed to parse rules"},
+		{name: "unknown field", content: "- pattern: '*.apk'\n  notify: testers", wantErr: "field notify not found"},
+		{name: "no matcher", content: "- notify_user_groups: testers", wantErr: "rule #1: pattern or artifact_type is required"},
+		{name: "invalid pattern", content: "- pattern: '[*.apk'", wantErr: "rule #1: invalid pattern"},
+		{name: "invalid artifact type", content: "- pattern: '*.apk'\n- artifact_type: aab", wantErr: "rule #2: invalid artifact_type (aab), valid values: apk, ipa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.content, artifactTypes)
+			require.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRule_Matches(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		pth     string
+		want    bool
+	}{
+		{name: "file name", pattern: "*.apk", pth: "/bitrise/deploy/qa/app.apk", want: true},
+		{name: "relative path", pattern: "qa/*.apk", pth: "/bitrise/deploy/qa/app.apk", want: true},
+		{name: "relative path with dot", pattern: "./qa/*.apk", pth: "/bitrise/deploy/qa/app.apk", want: true},
+		{name: "relative path of another directory", pattern: "qa/*.apk", pth: "/bitrise/deploy/release/app.apk", want: false},
+		{name: "relative path longer than the path", pattern: "deploy/qa/*.apk", pth: "qa/app.apk", want: false},
+		{name: "absolute path", pattern: "/bitrise/deploy/qa/*.apk", pth: "/bitrise/deploy/qa/app.apk", want: true},
+		{name: "absolute path of another directory", pattern: "/deploy/qa/*.apk", pth: "/bitrise/deploy/qa/app.apk", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Rule{Pattern: tt.pattern}.Matches(tt.pth, "apk"))
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	testers, none, email, enabled, disabled := "testers", "none", "qa@example.com", true, false
+	rules := []Rule{
+		{Pattern: "*-qa.*", NotifyUserGroups: &testers, NotifyEmailList: &email, IsEnablePublicPage: &enabled},
+		{Pattern: "/bitrise/deploy/production/*", NotifyUserGroups: &none},
+		{ArtifactType: "ipa", IsEnablePublicPage: &disabled},
+	}
+	defaults := Settings{NotifyUserGroups: "everyone", AlwaysNotifyUserGroups: "admins", IsEnablePublicPage: false}
+
+	tests := []struct {
+		name         string
+		pth          string
+		artifactType string
+		want         Settings
+		wantRule     int
+	}{
+		{
+			name:         "matched by file name",
+			pth:          "/bitrise/deploy/app-qa.apk",
+			artifactType: "apk",
+			want:         Settings{NotifyUserGroups: "testers", AlwaysNotifyUserGroups: "admins", NotifyEmails: "qa@example.com", IsEnablePublicPage: true},
+			wantRule:     0,
+		},
+		{
+			name:         "matched by path",
+			pth:          "/bitrise/deploy/production/app-release.apk",
+			artifactType: "apk",
+			want:         Settings{NotifyUserGroups: "none", AlwaysNotifyUserGroups: "admins"},
+			wantRule:     1,
+		},
+		{
+			name:         "matched by artifact type",
+			pth:          "/bitrise/deploy/app.ipa",
+			artifactType: "ipa",
+			want:         defaults,
+			wantRule:     2,
+		},
+		{
+			name:         "no matching rule",
+			pth:          "/bitrise/deploy/app-release.apk",
+			artifactType: "apk",
+			want:         defaults,
+			wantRule:     -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rule := Resolve(rules, defaults, tt.pth, tt.artifactType)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantRule, rule)
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Package failure categorizes the errors of the deployment, so that the Step can report why it failed
+// and decide per deployment phase whether a failure fails the Step.
+package failure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+)
+
+// Reason is the category of a failure.
+type Reason string
+
+// Failure reasons.
+const (
+	// Auth failures are rejected credentials, like an invalid or expired build API token (401 and 403 responses).
+	Auth Reason = "auth"
+	// Quota failures are exceeded limits, like storage quota, file size limits and rate limiting (402, 413, 429 and 507 responses).
+	Quota Reason = "quota"
+	// Network failures are connection errors: DNS, TLS, refused or reset connections and truncated responses.
+	Network Reason = "network"
+	// Timeout failures are uploads which did not finish before the deploy_timeout.
+	Timeout Reason = "timeout"
+	// Server failures are 5xx responses which persisted after the retries.
+	Server Reason = "server"
+	// Request failures are other 4xx responses and invalid responses of the API.
+	Request Reason = "request"
+	// Parse failures are deployable items, test results or reports which could not be parsed.
+	Parse Reason = "parse"
+	// File failures are local file system errors, like missing files or files modified during the upload.
+	File Reason = "file"
+	// Unknown is the reason of the errors which don't belong to any other category.
+	Unknown Reason = "unknown"
+)
+
+// Phase is a part of the deployment with its own failure policy.
+type Phase string
+
+// Deployment phases.
+const (
+	Artifacts         Phase = "artifacts"
+	IntermediateFiles Phase = "intermediate_files"
+	TestResults       Phase = "test_results"
+	HTMLReports       Phase = "html_reports"
+)
+
+// Phases are the deployment phases in the order they are deployed.
+var Phases = []Phase{Artifacts, IntermediateFiles, TestResults, HTMLReports}
+
+// Policy decides whether the failures of a phase fail the Step.
+type Policy string
+
+// Failure policies.
+const (
+	Fail Policy = "fail"
+	Warn Policy = "warn"
+)
+
+// Reasoner is implemented by errors which know their failure reason.
+type Reasoner interface {
+	FailureReason() Reason
+}
+
+// Error is an error with an explicit failure reason.
+type Error struct {
+	Reason Reason
+	Err    error
+}
+
+// New returns the error with the given failure reason, or nil if err is nil.
+func New(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &Error{Reason: reason, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// FailureReason ...
+func (e *Error) FailureReason() Reason {
+	return e.Reason
+}
+
+// Classify returns the reason of the error: the explicit reason of the error chain if there is one,
+// otherwise the reason is derived from the HTTP status code, or the network or file system error in the chain.
+func Classify(err error) Reason {
+	if err == nil {
+		return ""
+	}
+
+	var reasoner Reasoner
+	if errors.As(err, &reasoner) {
+		return reasoner.FailureReason()
+	}
+
+	var httpErr *retrypolicy.HTTPError
+	if errors.As(err, &httpErr) {
+		return StatusReason(httpErr.StatusCode)
+	}
+
+	// File system errors are checked before the network errors, as *fs.PathError also implements net.Error.
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return Timeout
+	case errors.As(err, &pathErr):
+		return File
+	case isNetworkError(err):
+		return Network
+	default:
+		return Unknown
+	}
+}
+
+// StatusReason returns the failure reason of an unsuccessful HTTP response status code.
+func StatusReason(statusCode int) Reason {
+	switch {
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return Auth
+	case statusCode == http.StatusPaymentRequired, statusCode == http.StatusRequestEntityTooLarge,
+		statusCode == http.StatusTooManyRequests, statusCode == http.StatusInsufficientStorage:
+		return Quota
+	case statusCode >= 500:
+		return Server
+	case statusCode >= 400:
+		return Request
+	default:
+		return Unknown
+	}
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	var urlErr *url.Error
+
+	return errors.As(err, &netErr) ||
+		errors.As(err, &urlErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,58 @@
+package failure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/stretchr/testify/require"
+)
+
+func httpError(statusCode int) error {
+	return retrypolicy.NewHTTPError(&http.Response{StatusCode: statusCode, Header: http.Header{}}, fmt.Errorf("status code: %d", statusCode))
+}
+
+func TestClassify(t *testing.T) {
+	_, notExistErr := os.Open("/not/existing/file")
+
+	tests := []struct {
+		name string
+		err  error
+		want Reason
+	}{
+		{name: "no error", err: nil, want: ""},
+		{name: "explicit reason", err: fmt.Errorf("failed to parse deployment info: %w", New(Parse, errors.New("invalid zip"))), want: Parse},
+		{name: "explicit reason wins over the status code", err: New(Request, httpError(http.StatusUnauthorized)), want: Request},
+		{name: "unauthorized", err: fmt.Errorf("failed to create artifact: %w", httpError(http.StatusUnauthorized)), want: Auth},
+		{name: "forbidden", err: httpError(http.StatusForbidden), want: Auth},
+		{name: "too large", err: httpError(http.StatusRequestEntityTooLarge), want: Quota},
+		{name: "rate limited", err: httpError(http.StatusTooManyRequests), want: Quota},
+		{name: "server error", err: httpError(http.StatusBadGateway), want: Server},
+		{name: "bad request", err: httpError(http.StatusUnprocessableEntity), want: Request},
+		{name: "deadline", err: fmt.Errorf("app.apk was not deployed: %w", context.DeadlineExceeded), want: Timeout},
+		{name: "request deadline", err: &url.Error{Op: "Put", URL: "https://storage", Err: context.DeadlineExceeded}, want: Timeout},
+		{name: "connection refused", err: &url.Error{Op: "Post", URL: "https://api", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: Network},
+		{name: "missing file", err: fmt.Errorf("failed to open artifact: %w", notExistErr), want: File},
+		{name: "unknown", err: errors.New("no upload task received"), want: Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Classify(tt.err))
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	require.NoError(t, New(Parse, nil))
+
+	cause := errors.New("invalid manifest")
+	err := New(Parse, cause)
+	require.ErrorIs(t, err, cause)
+	require.EqualError(t, err, "invalid manifest")
+}
@@ -1,9 +1,13 @@
 package fileredactor
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"sync"
 
 	"github.com/bitrise-io/go-utils/v2/fileutil"
 	"github.com/bitrise-io/go-utils/v2/log"
@@ -14,6 +18,8 @@ import (
 // process the specified files to redact secrets from them.
 type FileRedactor interface {
 	RedactFiles([]string, []string) error
+	// PreviewRedaction returns the files which contain any of the secrets, without modifying them.
+	PreviewRedaction([]string, []string) ([]string, error)
 }
 
 type fileRedactor struct {
@@ -38,6 +44,51 @@ func (f fileRedactor) RedactFiles(filePaths []string, secrets []string) error {
 	return nil
 }
 
+func (f fileRedactor) PreviewRedaction(filePaths []string, secrets []string) ([]string, error) {
+	logger := log.NewLogger()
+	var filesToRedact []string
+	for _, path := range filePaths {
+		// The redacted content is compared to the original one by their hashes, so the file is not written.
+		original := sha256.New()
+		if err := f.copyFile(path, original, logger); err != nil {
+			return nil, err
+		}
+
+		redacted := &lockedWriter{writer: sha256.New()}
+		redactWriter := redactwriter.New(secrets, redacted, logger)
+		if err := f.copyFile(path, redactWriter, logger); err != nil {
+			return nil, err
+		}
+		if err := redactWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close redact writer: %w", err)
+		}
+
+		if !bytes.Equal(original.Sum(nil), redacted.sum()) {
+			filesToRedact = append(filesToRedact, path)
+		}
+	}
+
+	return filesToRedact, nil
+}
+
+func (f fileRedactor) copyFile(path string, destination io.Writer, logger log.Logger) error {
+	source, err := f.fileManager.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file for redaction (%s): %w", path, err)
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			logger.Warnf("Failed to close file: %s", err)
+		}
+	}()
+
+	if _, err := io.Copy(destination, source); err != nil {
+		return fmt.Errorf("failed to read file for redaction (%s): %w", path, err)
+	}
+
+	return nil
+}
+
 func (f fileRedactor) redactFile(path string, secrets []string, logger log.Logger) error {
 	source, err := f.fileManager.Open(path)
 	if err != nil {
@@ -77,3 +128,23 @@ func (f fileRedactor) redactFile(path string, secrets []string, logger log.Logge
 
 	return nil
 }
+
+// lockedWriter guards the hash, as the redact writer flushes its remaining bytes from a timer goroutine.
+type lockedWriter struct {
+	lock   sync.Mutex
+	writer hash.Hash
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.writer.Write(p)
+}
+
+func (w *lockedWriter) sum() []byte {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.writer.Sum(nil)
+}
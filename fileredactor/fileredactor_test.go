@@ -35,3 +35,27 @@ func Test_RedactFiles(t *testing.T) {
 
 	assert.Equal(t, want, got)
 }
+
+func Test_PreviewRedaction(t *testing.T) {
+	secrets := []string{
+		"SUPER_SECRET_WORD",
+		"ANOTHER_SECRET_WORD",
+	}
+	content, err := os.ReadFile("testdata/before_redaction.txt")
+	require.NoError(t, err)
+
+	fileManager := fileutil.NewFileManager()
+	filePath := path.Join(t.TempDir(), "step-output.txt")
+	require.NoError(t, fileManager.WriteBytes(filePath, content))
+	cleanFilePath := path.Join(t.TempDir(), "clean-output.txt")
+	require.NoError(t, fileManager.WriteBytes(cleanFilePath, []byte("nothing to hide")))
+
+	fileRedactor := NewFileRedactor(fileManager)
+	got, err := fileRedactor.PreviewRedaction([]string{filePath, cleanFilePath}, secrets)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filePath}, got)
+
+	unchanged, err := os.ReadFile(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, unchanged)
+}
@@ -0,0 +1,185 @@
+// Package httpclient provides the HTTP client shared by the artifact API, the file storage, the test addon API
+// and the html report calls, so that every upload of the Step reuses the same pool of keep-alive connections.
+package httpclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/ratelimit"
+)
+
+const (
+	// DefaultTimeout ...
+	DefaultTimeout = 10 * time.Minute
+	// DefaultDialTimeout ...
+	DefaultDialTimeout = 30 * time.Second
+	// DefaultKeepAlive ...
+	DefaultKeepAlive = 30 * time.Second
+	// DefaultTLSHandshakeTimeout ...
+	DefaultTLSHandshakeTimeout = 15 * time.Second
+	// DefaultResponseHeaderTimeout ...
+	DefaultResponseHeaderTimeout = 2 * time.Minute
+	// DefaultIdleConnTimeout ...
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultMaxIdleConnsPerHost is above the maximum upload concurrency, so that concurrent uploads
+	// to the same storage host don't close and re-open (and re-handshake) their connections.
+	DefaultMaxIdleConnsPerHost = 64
+)
+
+// Config describes the transport of the shared client, zero durations disable the given timeout.
+type Config struct {
+	// Timeout is the stall timeout of a request: the request fails if it doesn't send or receive any data for this duration.
+	// It doesn't limit the whole request, so the upload of a large file with a limited bandwidth doesn't time out.
+	Timeout               time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+	MaxIdleConnsPerHost   int
+	// TLSMinVersion is the minimum TLS version, like tls.VersionTLS12.
+	TLSMinVersion uint16
+	// DisableHTTP2 forces HTTP/1.1, HTTP/2 is negotiated over TLS otherwise.
+	DisableHTTP2 bool
+	// ProxyURL is the proxy of every request (http, https or socks5 scheme),
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used if it is empty.
+	ProxyURL string
+	// NoProxy is a comma separated list of hosts, domains, IP addresses and CIDR ranges which are reached without a proxy,
+	// it replaces the NO_PROXY environment variable if ProxyURL is set and is added to it otherwise.
+	NoProxy string
+	// CABundlePath is a PEM file of certificates which are trusted in addition to the system root certificates,
+	// like the certificate of a TLS-intercepting proxy.
+	CABundlePath string
+	// UploadRate limits the bandwidth of all request bodies together, in bytes per second, 0 means no limit.
+	UploadRate int64
+	// UploadRatePerConnection limits the bandwidth of each request body, in bytes per second, 0 means no limit.
+	UploadRatePerConnection int64
+}
+
+// DefaultConfig ...
+func DefaultConfig() Config {
+	return Config{
+		Timeout:               DefaultTimeout,
+		DialTimeout:           DefaultDialTimeout,
+		KeepAlive:             DefaultKeepAlive,
+		TLSHandshakeTimeout:   DefaultTLSHandshakeTimeout,
+		ResponseHeaderTimeout: DefaultResponseHeaderTimeout,
+		IdleConnTimeout:       DefaultIdleConnTimeout,
+		MaxIdleConnsPerHost:   DefaultMaxIdleConnsPerHost,
+		TLSMinVersion:         tls.VersionTLS12,
+	}
+}
+
+// New returns a client with the transport of the config.
+func New(config Config) (*http.Client, error) {
+	transport, err := NewTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if config.UploadRate > 0 || config.UploadRatePerConnection > 0 {
+		roundTripper = &limitedTransport{
+			base:                    transport,
+			limiter:                 ratelimit.NewLimiter(config.UploadRate),
+			uploadRatePerConnection: config.UploadRatePerConnection,
+		}
+	}
+
+	if config.Timeout > 0 {
+		roundTripper = &stallTransport{base: roundTripper, timeout: config.Timeout}
+	}
+
+	return &http.Client{
+		Transport: roundTripper,
+	}, nil
+}
+
+// limitedTransport limits the bandwidth of the request bodies: by the limiter shared by all requests of the client,
+// and by the per connection rate of each request.
+type limitedTransport struct {
+	base                    http.RoundTripper
+	limiter                 *ratelimit.Limiter
+	uploadRatePerConnection int64
+}
+
+func (t *limitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body == nil || request.Body == http.NoBody {
+		return t.base.RoundTrip(request)
+	}
+
+	limited := request.Clone(request.Context())
+	limited.Body = &limitedBody{
+		Reader: ratelimit.NewReader(request.Context(), request.Body, t.limiter, ratelimit.NewLimiter(t.uploadRatePerConnection)),
+		Closer: request.Body,
+	}
+
+	return t.base.RoundTrip(limited)
+}
+
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// NewTransport returns a pooling transport, which keeps the idle connections of every host alive between the requests,
+// and connects through the configured proxy.
+func NewTransport(config Config) (*http.Transport, error) {
+	if config.TLSMinVersion != 0 && config.TLSMinVersion != tls.VersionTLS12 && config.TLSMinVersion != tls.VersionTLS13 {
+		return nil, fmt.Errorf("unsupported minimum TLS version: %s", tls.VersionName(config.TLSMinVersion))
+	}
+
+	proxy, err := proxyFunc(config.ProxyURL, config.NoProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: config.TLSMinVersion,
+	}
+	if config.CABundlePath != "" {
+		if tlsConfig.RootCAs, err = certPool(config.CABundlePath); err != nil {
+			return nil, err
+		}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   config.DialTimeout,
+		KeepAlive: config.KeepAlive,
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !config.DisableHTTP2,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   config.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+	if config.DisableHTTP2 {
+		// A non-nil, empty TLSNextProto disables the HTTP/2 upgrade of the TLS connections.
+		transport.TLSNextProto = map[string]func(authority string, c *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport, nil
+}
+
+// ParseTLSVersion parses a TLS version like 1.2 or 1.3, an empty version is TLS 1.2.
+func ParseTLSVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported TLS version: %s", version)
+	}
+}
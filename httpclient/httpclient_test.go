@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer returns a TLS test server which counts the opened connections, and a client of the config trusting the server.
+func newTLSServer(t *testing.T, config Config, enableHTTP2 bool) (*httptest.Server, *http.Client, *int32) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+	server.EnableHTTP2 = enableHTTP2
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	transport, err := NewTransport(config)
+	require.NoError(t, err)
+	transport.TLSClientConfig.RootCAs = server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	return server, &http.Client{Transport: transport, Timeout: config.Timeout}, &connections
+}
+
+func get(t *testing.T, client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body), nil
+}
+
+func TestNewTransport_reusesConnections(t *testing.T) {
+	server, client, connections := newTLSServer(t, DefaultConfig(), false)
+
+	for i := 0; i < 5; i++ {
+		proto, err := get(t, client, server.URL)
+		require.NoError(t, err)
+		require.Equal(t, "HTTP/1.1", proto)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(connections))
+}
+
+func TestNewTransport_http2(t *testing.T) {
+	server, client, _ := newTLSServer(t, DefaultConfig(), true)
+	proto, err := get(t, client, server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/2.0", proto)
+
+	config := DefaultConfig()
+	config.DisableHTTP2 = true
+	server, client, _ = newTLSServer(t, config, true)
+	proto, err = get(t, client, server.URL)
+	require.NoError(t, err)
+	require.Equal(t, "HTTP/1.1", proto)
+}
+
+func TestNewTransport_tlsMinVersion(t *testing.T) {
+	config := DefaultConfig()
+	config.TLSMinVersion = tls.VersionTLS13
+	server, client, _ := newTLSServer(t, config, false)
+	server.TLS.MaxVersion = tls.VersionTLS12
+
+	_, err := get(t, client, server.URL)
+	require.ErrorContains(t, err, "protocol version")
+
+	config.TLSMinVersion = tls.VersionTLS10
+	_, err = NewTransport(config)
+	require.EqualError(t, err, "unsupported minimum TLS version: TLS 1.0")
+}
+
+func TestParseTLSVersion(t *testing.T) {
+	version, err := ParseTLSVersion("")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), version)
+
+	version, err = ParseTLSVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	_, err = ParseTLSVersion("1.1")
+	require.EqualError(t, err, "unsupported TLS version: 1.1")
+}
+
+func TestNew_uploadRate(t *testing.T) {
+	var received []byte
+	var contentLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		received, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig()
+	config.UploadRate = 10 * 1024 * 1024
+	config.UploadRatePerConnection = 5 * 1024 * 1024
+	client, err := New(config)
+	require.NoError(t, err)
+	require.IsType(t, &limitedTransport{}, client.Transport.(*stallTransport).base)
+
+	content := strings.Repeat("content", 10000)
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader(content))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, int64(len(content)), contentLength)
+	require.Equal(t, content, string(received))
+}
@@ -0,0 +1,70 @@
+package httpclient
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyFunc returns the proxy of the requests: the proxy URL for every host not on the no-proxy list,
+// or the proxy of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables if the proxy URL is empty,
+// in which case the no-proxy list is added to NO_PROXY.
+func proxyFunc(proxyURL, noProxy string) (func(*http.Request) (*url.URL, error), error) {
+	config := httpproxy.FromEnvironment()
+	if proxyURL == "" {
+		config.NoProxy = strings.Join(nonEmpty(config.NoProxy, noProxy), ",")
+	} else {
+		proxy, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme: %s (supported: http, https, socks5)", proxy.Scheme)
+		}
+		if proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s: missing host", proxyURL)
+		}
+
+		config.HTTPProxy, config.HTTPSProxy, config.NoProxy = proxyURL, proxyURL, noProxy
+	}
+
+	proxy := config.ProxyFunc()
+	return func(request *http.Request) (*url.URL, error) {
+		return proxy(request.URL)
+	}, nil
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// certPool returns the system root certificates extended with the certificates of the PEM encoded CA bundle.
+func certPool(caBundlePath string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(caBundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no PEM encoded certificates found in the CA bundle: %s", caBundlePath)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,254 @@
+package httpclient
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCABundle writes the certificate of the TLS test servers into a PEM file.
+func writeCABundle(t *testing.T, server *httptest.Server) string {
+	pth := filepath.Join(t.TempDir(), "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(pth, content, 0644))
+	return pth
+}
+
+// newInterceptingProxy is a stand-in of a TLS-intercepting corporate proxy: it accepts the CONNECT requests,
+// terminates the TLS connection with its own certificate and answers the request itself.
+func newInterceptingProxy(t *testing.T, certificates []tls.Certificate) (*httptest.Server, *int32) {
+	var connects int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&connects, 1)
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+		if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			return
+		}
+
+		tlsConn := tls.Server(conn, &tls.Config{Certificates: certificates})
+		request, err := http.ReadRequest(bufio.NewReader(tlsConn))
+		if err != nil {
+			return
+		}
+		body := "intercepted " + request.Host
+		_, _ = io.WriteString(tlsConn, "HTTP/1.1 200 OK\r\nContent-Length: "+strconv.Itoa(len(body))+"\r\nConnection: close\r\n\r\n"+body)
+	}))
+	t.Cleanup(proxy.Close)
+
+	return proxy, &connects
+}
+
+// newSOCKS5Proxy is a minimal SOCKS5 proxy without authentication, which tunnels every connection to the target address,
+// as the loopback address of the test servers is never proxied.
+func newSOCKS5Proxy(t *testing.T, target string) (string, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	var connects int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() {
+					_ = conn.Close()
+				}()
+
+				// Greeting: version, number of methods and the methods, answered with the no authentication method.
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+					return
+				}
+				if _, err := conn.Write([]byte{5, 0}); err != nil {
+					return
+				}
+
+				// Connect request: version, command, reserved, address type, address and port.
+				request := make([]byte, 4)
+				if _, err := io.ReadFull(conn, request); err != nil {
+					return
+				}
+				switch request[3] {
+				case 1:
+					if _, err := io.ReadFull(conn, make([]byte, 4)); err != nil {
+						return
+					}
+				case 3:
+					length := make([]byte, 1)
+					if _, err := io.ReadFull(conn, length); err != nil {
+						return
+					}
+					if _, err := io.ReadFull(conn, make([]byte, length[0])); err != nil {
+						return
+					}
+				default:
+					return
+				}
+				if _, err := io.ReadFull(conn, make([]byte, 2)); err != nil {
+					return
+				}
+
+				target, err := net.Dial("tcp", target)
+				if err != nil {
+					_, _ = conn.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				defer func() {
+					_ = target.Close()
+				}()
+				atomic.AddInt32(&connects, 1)
+				if _, err := conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+					return
+				}
+
+				go func() {
+					_, _ = io.Copy(target, conn)
+				}()
+				_, _ = io.Copy(conn, target)
+			}()
+		}
+	}()
+
+	return "socks5://" + listener.Addr().String(), &connects
+}
+
+func newClient(t *testing.T, config Config) *http.Client {
+	client, err := New(config)
+	require.NoError(t, err)
+	return client
+}
+
+func TestNew_interceptingProxy(t *testing.T) {
+	// The certificate of the TLS test servers stands in for the certificate of the corporate CA.
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	proxy, connects := newInterceptingProxy(t, server.TLS.Certificates)
+
+	config := DefaultConfig()
+	config.ProxyURL = proxy.URL
+
+	_, err := newClient(t, config).Get("https://example.com/upload")
+	require.ErrorContains(t, err, "certificate")
+
+	config.CABundlePath = writeCABundle(t, server)
+	body, err := get(t, newClient(t, config), "https://example.com/upload")
+	require.NoError(t, err)
+	require.Equal(t, "intercepted example.com", body)
+	require.Equal(t, int32(2), atomic.LoadInt32(connects))
+}
+
+func TestNew_socks5Proxy(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "tunnelled")
+	}))
+	t.Cleanup(server.Close)
+	proxyURL, connects := newSOCKS5Proxy(t, server.Listener.Addr().String())
+
+	config := DefaultConfig()
+	config.ProxyURL = proxyURL
+	config.CABundlePath = writeCABundle(t, server)
+
+	body, err := get(t, newClient(t, config), "https://example.com/upload")
+	require.NoError(t, err)
+	require.Equal(t, "tunnelled", body)
+	require.Equal(t, int32(1), atomic.LoadInt32(connects))
+}
+
+func TestNew_invalidProxyConfig(t *testing.T) {
+	config := DefaultConfig()
+	config.ProxyURL = "ftp://proxy.example.com"
+	_, err := New(config)
+	require.EqualError(t, err, "unsupported proxy scheme: ftp (supported: http, https, socks5)")
+
+	config.ProxyURL = "http://"
+	_, err = New(config)
+	require.EqualError(t, err, "invalid proxy URL: http://: missing host")
+
+	config = DefaultConfig()
+	config.CABundlePath = filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(config.CABundlePath, []byte("not a certificate"), 0644))
+	_, err = New(config)
+	require.ErrorContains(t, err, "no PEM encoded certificates found in the CA bundle")
+}
+
+func Test_proxyFunc(t *testing.T) {
+	const (
+		inputProxy = "http://proxy.example.com:3128"
+		envProxy   = "http://env-proxy.example.com:3128"
+	)
+
+	tests := []struct {
+		name     string
+		proxyURL string
+		noProxy  string
+		envProxy string
+		envNo    string
+		url      string
+		want     string
+	}{
+		{name: "proxy URL", proxyURL: inputProxy, url: "https://app.bitrise.io", want: inputProxy},
+		{name: "domain on the no-proxy list", proxyURL: inputProxy, noProxy: " .internal.example.com, *.corp", url: "https://api.internal.example.com/x"},
+		{name: "wildcard domain on the no-proxy list", proxyURL: inputProxy, noProxy: " .internal.example.com, *.corp", url: "https://build.corp"},
+		{name: "host with another port", proxyURL: inputProxy, noProxy: "storage.example.com:8443", url: "https://storage.example.com/file", want: inputProxy},
+		{name: "host with the listed port", proxyURL: inputProxy, noProxy: "storage.example.com:8443", url: "https://storage.example.com:8443/file"},
+		{name: "CIDR range", proxyURL: inputProxy, noProxy: "10.0.0.0/8", url: "http://10.1.2.3:8080"},
+		{name: "every host", proxyURL: inputProxy, noProxy: "*", url: "https://app.bitrise.io"},
+		{name: "proxy URL ignores the environment", proxyURL: inputProxy, envProxy: envProxy, envNo: "app.bitrise.io", url: "https://app.bitrise.io", want: inputProxy},
+		{name: "environment proxy", envProxy: envProxy, url: "https://app.bitrise.io", want: envProxy},
+		{name: "no-proxy list with the environment proxy", envProxy: envProxy, noProxy: "app.bitrise.io", url: "https://app.bitrise.io"},
+		{name: "environment no-proxy list", envProxy: envProxy, envNo: "storage.example.com", noProxy: "app.bitrise.io", url: "https://storage.example.com"},
+		{name: "no proxy", noProxy: "app.bitrise.io", url: "https://storage.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+				t.Setenv(key, "")
+			}
+			t.Setenv("HTTPS_PROXY", tt.envProxy)
+			t.Setenv("NO_PROXY", tt.envNo)
+
+			proxy, err := proxyFunc(tt.proxyURL, tt.noProxy)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			require.NoError(t, err)
+			got, err := proxy(request)
+			require.NoError(t, err)
+			if tt.want == "" {
+				require.Nil(t, got)
+			} else {
+				require.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}
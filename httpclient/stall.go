@@ -0,0 +1,129 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// StallError is returned when a request didn't send or receive any data for the stall timeout.
+// It is a timeout net.Error, so the request is retried like the other network timeouts.
+type StallError struct {
+	Duration time.Duration
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("no data was sent or received for %s", e.Duration)
+}
+
+// Timeout ...
+func (e *StallError) Timeout() bool { return true }
+
+// Temporary ...
+func (e *StallError) Temporary() bool { return true }
+
+// stallTransport cancels the requests which stop making progress. Unlike http.Client.Timeout, it doesn't limit the duration
+// of the whole request: the timer is restarted by every read of the request and the response body,
+// so a large file uploaded with a limited bandwidth doesn't time out as long as it is being sent.
+type stallTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *stallTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(request.Context())
+	watchdog := newWatchdog(t.timeout, cancel)
+
+	watched := request.Clone(ctx)
+	if request.Body != nil && request.Body != http.NoBody {
+		watched.Body = watchdog.watch(request.Body, nil)
+		if request.GetBody != nil {
+			watched.GetBody = func() (io.ReadCloser, error) {
+				body, err := request.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				return watchdog.watch(body, nil), nil
+			}
+		}
+	}
+
+	response, err := t.base.RoundTrip(watched)
+	if err != nil {
+		watchdog.stop()
+		cancel()
+		return nil, watchdog.wrap(err)
+	}
+
+	response.Body = watchdog.watch(response.Body, cancel)
+	return response, nil
+}
+
+// watchdog cancels the request once it is not reset for the timeout.
+type watchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	fired   atomic.Bool
+}
+
+func newWatchdog(timeout time.Duration, cancel context.CancelFunc) *watchdog {
+	w := &watchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.fired.Store(true)
+		cancel()
+	})
+	return w
+}
+
+func (w *watchdog) reset() {
+	if !w.fired.Load() {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+func (w *watchdog) stop() {
+	w.timer.Stop()
+}
+
+// wrap replaces the error caused by the cancelled request with a StallError.
+func (w *watchdog) wrap(err error) error {
+	if err == nil || errors.Is(err, io.EOF) || !w.fired.Load() {
+		return err
+	}
+	return &StallError{Duration: w.timeout}
+}
+
+// watch returns the body which resets the watchdog on every read, onClose is called when the body is closed.
+func (w *watchdog) watch(body io.ReadCloser, onClose func()) io.ReadCloser {
+	return &watchedBody{body: body, watchdog: w, onClose: onClose}
+}
+
+type watchedBody struct {
+	body     io.ReadCloser
+	watchdog *watchdog
+	onClose  func()
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if errors.Is(err, io.EOF) && b.onClose != nil {
+		// The response is read, the request is done.
+		b.watchdog.stop()
+	} else if n > 0 {
+		b.watchdog.reset()
+	}
+	return n, b.watchdog.wrap(err)
+}
+
+func (b *watchedBody) Close() error {
+	err := b.body.Close()
+	if b.onClose != nil {
+		b.watchdog.stop()
+		b.onClose()
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_rateLimitedUploadLongerThanTimeout(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultConfig()
+	config.Timeout = 500 * time.Millisecond
+	config.UploadRatePerConnection = 128 * 1024
+	client, err := New(config)
+	require.NoError(t, err)
+
+	// The file is 4 times larger than the bytes sent during the timeout.
+	content := strings.Repeat("a", int(4*config.UploadRatePerConnection*int64(config.Timeout)/int64(time.Second)))
+	start := time.Now()
+	resp, err := client.Post(server.URL, "text/plain", strings.NewReader(content))
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Greater(t, time.Since(start), config.Timeout)
+	require.Equal(t, len(content), received)
+}
+
+func TestNew_stalledRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "No response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = io.ReadAll(r.Body)
+				<-r.Context().Done()
+			},
+		},
+		{
+			name: "Stalled response body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = io.WriteString(w, "partial")
+				w.(http.Flusher).Flush()
+				<-r.Context().Done()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			t.Cleanup(server.Close)
+
+			config := DefaultConfig()
+			config.Timeout = 100 * time.Millisecond
+			client, err := New(config)
+			require.NoError(t, err)
+
+			resp, err := client.Post(server.URL, "text/plain", strings.NewReader("content"))
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				require.NoError(t, resp.Body.Close())
+			}
+
+			var stallErr *StallError
+			require.True(t, errors.As(err, &stallErr), err)
+			var netErr net.Error
+			require.True(t, errors.As(err, &netErr) && netErr.Timeout())
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package macosparser
+
+import (
+	"bytes"
+	"crypto/x509"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/fullsailor/pkcs7"
+)
+
+const (
+	loadCmdCodeSignature = 0x1d
+
+	superBlobMagic     = 0xfade0cc0
+	cmsBlobMagic       = 0xfade0b01
+	signatureSlotIndex = 0x10000
+)
+
+// machOSigningInfo returns the signing identity of a thin or universal Mach-O executable,
+// an empty SigningInfo is returned for unsigned and ad-hoc signed executables.
+func machOSigningInfo(executable []byte) (SigningInfo, error) {
+	reader := bytes.NewReader(executable)
+
+	sliceOffset := uint32(0)
+	file, err := macho.NewFile(reader)
+	if err != nil {
+		fatFile, fatErr := macho.NewFatFile(reader)
+		if fatErr != nil {
+			return SigningInfo{}, fmt.Errorf("failed to parse Mach-O executable: %w", err)
+		}
+		if len(fatFile.Arches) == 0 {
+			return SigningInfo{}, errors.New("universal executable has no architectures")
+		}
+		// Every architecture is signed with the same identity.
+		file = fatFile.Arches[0].File
+		sliceOffset = fatFile.Arches[0].Offset
+	}
+
+	for _, load := range file.Loads {
+		raw := load.Raw()
+		if len(raw) < 16 || file.ByteOrder.Uint32(raw[0:4]) != loadCmdCodeSignature {
+			continue
+		}
+
+		dataOffset := uint64(sliceOffset) + uint64(file.ByteOrder.Uint32(raw[8:12]))
+		dataSize := uint64(file.ByteOrder.Uint32(raw[12:16]))
+		if dataOffset+dataSize > uint64(len(executable)) {
+			return SigningInfo{}, errors.New("code signature is out of the executable's bounds")
+		}
+
+		return codeSignatureSigningInfo(executable[dataOffset : dataOffset+dataSize])
+	}
+
+	return SigningInfo{}, nil
+}
+
+// codeSignatureSigningInfo reads the signing identity from the CMS blob of a code signature SuperBlob.
+func codeSignatureSigningInfo(superBlob []byte) (SigningInfo, error) {
+	if len(superBlob) < 12 || binary.BigEndian.Uint32(superBlob[0:4]) != superBlobMagic {
+		return SigningInfo{}, errors.New("invalid code signature")
+	}
+
+	count := binary.BigEndian.Uint32(superBlob[8:12])
+	for i := uint32(0); i < count; i++ {
+		indexOffset := 12 + uint64(i)*8
+		if indexOffset+8 > uint64(len(superBlob)) {
+			return SigningInfo{}, errors.New("invalid code signature index")
+		}
+
+		slotType := binary.BigEndian.Uint32(superBlob[indexOffset : indexOffset+4])
+		blobOffset := uint64(binary.BigEndian.Uint32(superBlob[indexOffset+4 : indexOffset+8]))
+		if slotType != signatureSlotIndex {
+			continue
+		}
+
+		if blobOffset+8 > uint64(len(superBlob)) || binary.BigEndian.Uint32(superBlob[blobOffset:blobOffset+4]) != cmsBlobMagic {
+			return SigningInfo{}, errors.New("invalid code signature CMS blob")
+		}
+		blobLength := uint64(binary.BigEndian.Uint32(superBlob[blobOffset+4 : blobOffset+8]))
+		if blobLength < 8 || blobOffset+blobLength > uint64(len(superBlob)) {
+			return SigningInfo{}, errors.New("invalid code signature CMS blob length")
+		}
+		if blobLength == 8 {
+			// Ad-hoc signatures have an empty CMS blob.
+			return SigningInfo{}, nil
+		}
+
+		return cmsSigningInfo(superBlob[blobOffset+8 : blobOffset+blobLength])
+	}
+
+	return SigningInfo{}, nil
+}
+
+func cmsSigningInfo(content []byte) (SigningInfo, error) {
+	signedData, err := pkcs7.Parse(content)
+	if err != nil {
+		return SigningInfo{}, fmt.Errorf("failed to parse code signature CMS: %w", err)
+	}
+
+	signer := signedData.GetOnlySigner()
+	if signer == nil {
+		return SigningInfo{}, errors.New("code signature has no signer certificate")
+	}
+
+	return certificateSigningInfo(signer), nil
+}
+
+func certificateSigningInfo(certificate *x509.Certificate) SigningInfo {
+	info := SigningInfo{SigningIdentity: certificate.Subject.CommonName}
+	if len(certificate.Subject.OrganizationalUnit) > 0 {
+		info.TeamID = certificate.Subject.OrganizationalUnit[0]
+	}
+
+	return info
+}
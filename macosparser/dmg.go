@@ -0,0 +1,124 @@
+package macosparser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	kolyMagic       = 0x6b6f6c79 // "koly"
+	kolyTrailerSize = 512
+
+	kolyCodeSignatureOffset = 296
+	kolyCodeSignatureLength = 304
+
+	maxDMGCodeSignatureInBytes = 16 * 1024 * 1024
+)
+
+// errMountNotSupported is returned on platforms where disk images can't be mounted to read the app inside them.
+var errMountNotSupported = errors.New("mounting disk images is only supported on macOS")
+
+// ParseDMGData parses the metadata of a .dmg disk image.
+// The signing info is read from the disk image's own code signature on every platform,
+// the app info is read from the app on the disk image, which requires mounting it, so it is only available on macOS.
+func (m *Parser) ParseDMGData(pth string) (*ArtifactMetadata, error) {
+	signingInfo, err := readDMGSigningInfo(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+
+	info, appSigningInfo, err := m.mountedDMGAppInfo(pth)
+	if err != nil {
+		m.logger.Warnf("Failed to read the app info of %s: %s", pth, err)
+	}
+	if signingInfo.SigningIdentity == "" {
+		signingInfo = appSigningInfo
+	}
+
+	return &ArtifactMetadata{
+		AppInfo:       info.appInfo(),
+		FileSizeBytes: m.fileSize(pth),
+		SigningInfo:   signingInfo,
+	}, nil
+}
+
+// readDMGSigningInfo reads the code signature referenced by the UDIF trailer at the end of the disk image.
+func readDMGSigningInfo(pth string) (SigningInfo, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return SigningInfo{}, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return SigningInfo{}, err
+	}
+	if stat.Size() < kolyTrailerSize {
+		return SigningInfo{}, errors.New("not a disk image")
+	}
+
+	trailer := make([]byte, kolyTrailerSize)
+	if _, err := file.ReadAt(trailer, stat.Size()-kolyTrailerSize); err != nil {
+		return SigningInfo{}, fmt.Errorf("failed to read disk image trailer: %w", err)
+	}
+	if binary.BigEndian.Uint32(trailer[0:4]) != kolyMagic {
+		return SigningInfo{}, errors.New("not a disk image")
+	}
+
+	offset := binary.BigEndian.Uint64(trailer[kolyCodeSignatureOffset : kolyCodeSignatureOffset+8])
+	length := binary.BigEndian.Uint64(trailer[kolyCodeSignatureLength : kolyCodeSignatureLength+8])
+	if length == 0 {
+		return SigningInfo{}, nil
+	}
+	if length > maxDMGCodeSignatureInBytes || offset+length > uint64(stat.Size()) {
+		return SigningInfo{}, errors.New("invalid disk image code signature")
+	}
+
+	superBlob := make([]byte, length)
+	if _, err := io.ReadFull(io.NewSectionReader(file, int64(offset), int64(length)), superBlob); err != nil {
+		return SigningInfo{}, fmt.Errorf("failed to read disk image code signature: %w", err)
+	}
+
+	return codeSignatureSigningInfo(superBlob)
+}
+
+// readAppDir reads the metadata of the first .app bundle in the directory.
+func readAppDir(dir string) (infoPlist, SigningInfo, error) {
+	apps, err := filepath.Glob(filepath.Join(dir, "*.app"))
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, err
+	}
+	if len(apps) == 0 {
+		return infoPlist{}, SigningInfo{}, ErrNotFound
+	}
+
+	content, err := os.ReadFile(filepath.Join(apps[0], "Contents", "Info.plist"))
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, fmt.Errorf("failed to read Info.plist: %w", err)
+	}
+	info, err := parseInfoPlist(content)
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, err
+	}
+	if info.BundleExecutable == "" {
+		return info, SigningInfo{}, nil
+	}
+
+	executable, err := os.ReadFile(filepath.Join(apps[0], "Contents", "MacOS", info.BundleExecutable))
+	if err != nil {
+		return info, SigningInfo{}, fmt.Errorf("failed to read executable: %w", err)
+	}
+	signingInfo, err := machOSigningInfo(executable)
+	if err != nil {
+		return info, SigningInfo{}, err
+	}
+
+	return info, signingInfo, nil
+}
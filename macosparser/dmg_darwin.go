@@ -0,0 +1,36 @@
+package macosparser
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+)
+
+// mountedDMGAppInfo attaches the disk image read-only to read the app on it.
+func (m *Parser) mountedDMGAppInfo(pth string) (infoPlist, SigningInfo, error) {
+	mountPoint, err := os.MkdirTemp("", "dmg-mount")
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, err
+	}
+	defer func() {
+		if err := os.Remove(mountPoint); err != nil {
+			m.logger.Warnf("%s", err)
+		}
+	}()
+
+	factory := command.NewFactory(env.NewRepository())
+	attach := factory.Create("hdiutil", []string{"attach", "-nobrowse", "-noautoopen", "-readonly", "-mountpoint", mountPoint, pth}, nil)
+	if out, err := attach.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return infoPlist{}, SigningInfo{}, fmt.Errorf("failed to attach disk image: %s: %w", out, err)
+	}
+	defer func() {
+		detach := factory.Create("hdiutil", []string{"detach", "-force", mountPoint}, nil)
+		if out, err := detach.RunAndReturnTrimmedCombinedOutput(); err != nil {
+			m.logger.Warnf("Failed to detach disk image: %s: %s", out, err)
+		}
+	}()
+
+	return readAppDir(mountPoint)
+}
@@ -0,0 +1,7 @@
+//go:build !darwin
+
+package macosparser
+
+func (m *Parser) mountedDMGAppInfo(_ string) (infoPlist, SigningInfo, error) {
+	return infoPlist{}, SigningInfo{}, errMountNotSupported
+}
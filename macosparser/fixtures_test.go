@@ -0,0 +1,226 @@
+package macosparser
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fullsailor/pkcs7"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSigningIdentity = "Developer ID Application: Example Inc. (TEAM123456)"
+	testTeamID          = "TEAM123456"
+)
+
+const testInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleName</key>
+	<string>Example</string>
+	<key>CFBundleIdentifier</key>
+	<string>io.bitrise.example</string>
+	<key>CFBundleShortVersionString</key>
+	<string>1.2.3</string>
+	<key>CFBundleVersion</key>
+	<string>45</string>
+	<key>LSMinimumSystemVersion</key>
+	<string>12.0</string>
+	<key>CFBundleExecutable</key>
+	<string>Example</string>
+</dict>
+</plist>`
+
+const testArchiveInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>SchemeName</key>
+	<string>Example</string>
+	<key>ApplicationProperties</key>
+	<dict>
+		<key>ApplicationPath</key>
+		<string>Applications/Example.app</string>
+		<key>SigningIdentity</key>
+		<string>Apple Development: John Doe (DEV1234567)</string>
+		<key>Team</key>
+		<string>DEV1234567</string>
+	</dict>
+</dict>
+</plist>`
+
+func testCertificate(t *testing.T) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName:         testSigningIdentity,
+			OrganizationalUnit: []string{testTeamID},
+			Organization:       []string{"Example Inc."},
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return certificate, key
+}
+
+// testCodeSignature returns a code signature SuperBlob with a CMS blob signed by the test certificate,
+// or an ad-hoc signature if signed is false.
+func testCodeSignature(t *testing.T, signed bool) []byte {
+	var cms []byte
+	if signed {
+		certificate, key := testCertificate(t)
+		signedData, err := pkcs7.NewSignedData([]byte("code directory hash"))
+		require.NoError(t, err)
+		require.NoError(t, signedData.AddSigner(certificate, key, pkcs7.SignerInfoConfig{}))
+		signedData.Detach()
+		cms, err = signedData.Finish()
+		require.NoError(t, err)
+	}
+
+	const blobOffset = 20
+	blob := make([]byte, 0, blobOffset+8+len(cms))
+	blob = binary.BigEndian.AppendUint32(blob, superBlobMagic)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(blobOffset+8+len(cms)))
+	blob = binary.BigEndian.AppendUint32(blob, 1)
+	blob = binary.BigEndian.AppendUint32(blob, signatureSlotIndex)
+	blob = binary.BigEndian.AppendUint32(blob, blobOffset)
+	blob = binary.BigEndian.AppendUint32(blob, cmsBlobMagic)
+	blob = binary.BigEndian.AppendUint32(blob, uint32(8+len(cms)))
+
+	return append(blob, cms...)
+}
+
+// testMachO returns a minimal 64-bit Mach-O executable with the code signature, or an unsigned one if it is nil.
+func testMachO(codeSignature []byte) []byte {
+	const headerSize, loadCommandSize = 32, 16
+
+	var executable []byte
+	if codeSignature == nil {
+		for _, field := range []uint32{0xfeedfacf, 0x01000007, 3, 2, 0, 0, 0, 0} {
+			executable = binary.LittleEndian.AppendUint32(executable, field)
+		}
+		return executable
+	}
+
+	for _, field := range []uint32{0xfeedfacf, 0x01000007, 3, 2, 1, loadCommandSize, 0, 0} {
+		executable = binary.LittleEndian.AppendUint32(executable, field)
+	}
+	for _, field := range []uint32{loadCmdCodeSignature, loadCommandSize, headerSize + loadCommandSize, uint32(len(codeSignature))} {
+		executable = binary.LittleEndian.AppendUint32(executable, field)
+	}
+
+	return append(executable, codeSignature...)
+}
+
+func writeTestZip(t *testing.T, name string, files map[string][]byte) string {
+	pth := filepath.Join(t.TempDir(), name)
+	file, err := os.Create(pth)
+	require.NoError(t, err)
+
+	writer := zip.NewWriter(file)
+	for fileName, content := range files {
+		fileWriter, err := writer.Create(fileName)
+		require.NoError(t, err)
+		_, err = fileWriter.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+
+	return pth
+}
+
+// writeTestPKG writes a xar archive with the given files zlib compressed in its heap,
+// and the test certificate in its signature if signed is true.
+func writeTestPKG(t *testing.T, signed bool, files map[string]string) string {
+	var heap bytes.Buffer
+	var fileEntries string
+	id := 1
+	for name, content := range files {
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		_, err := writer.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		fileEntries += fmt.Sprintf(`<file id="%d"><name>%s</name><type>file</type><data><length>%d</length><offset>%d</offset><size>%d</size><encoding style="application/x-gzip"/></data></file>`,
+			id, name, compressed.Len(), heap.Len(), len(content))
+		heap.Write(compressed.Bytes())
+		id++
+	}
+
+	var signature string
+	if signed {
+		certificate, _ := testCertificate(t)
+		signature = fmt.Sprintf(`<signature style="RSA"><offset>0</offset><size>256</size><KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#"><X509Data><X509Certificate>%s</X509Certificate></X509Data></KeyInfo></signature>`,
+			base64.StdEncoding.EncodeToString(certificate.Raw))
+	}
+	toc := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?><xar><toc><creation-time>2024-01-01T00:00:00</creation-time>%s%s</toc></xar>`, signature, fileEntries)
+
+	var compressedTOC bytes.Buffer
+	writer := zlib.NewWriter(&compressedTOC)
+	_, err := writer.Write([]byte(toc))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	var archive bytes.Buffer
+	require.NoError(t, binary.Write(&archive, binary.BigEndian, xarHeader{
+		Magic:                 xarMagic,
+		HeaderSize:            xarHeaderSize,
+		Version:               1,
+		TOCLengthCompressed:   uint64(compressedTOC.Len()),
+		TOCLengthUncompressed: uint64(len(toc)),
+		ChecksumAlgorithm:     1,
+	}))
+	archive.Write(compressedTOC.Bytes())
+	archive.Write(heap.Bytes())
+
+	pth := filepath.Join(t.TempDir(), "Example.pkg")
+	require.NoError(t, os.WriteFile(pth, archive.Bytes(), 0600))
+
+	return pth
+}
+
+// writeTestDMG writes a disk image with the code signature referenced from its UDIF trailer.
+func writeTestDMG(t *testing.T, codeSignature []byte) string {
+	image := bytes.Repeat([]byte{0}, 1024)
+	codeSignatureOffset := len(image)
+	image = append(image, codeSignature...)
+
+	trailer := make([]byte, kolyTrailerSize)
+	binary.BigEndian.PutUint32(trailer[0:4], kolyMagic)
+	binary.BigEndian.PutUint32(trailer[4:8], 4)
+	binary.BigEndian.PutUint32(trailer[8:12], kolyTrailerSize)
+	if len(codeSignature) > 0 {
+		binary.BigEndian.PutUint64(trailer[kolyCodeSignatureOffset:], uint64(codeSignatureOffset))
+		binary.BigEndian.PutUint64(trailer[kolyCodeSignatureLength:], uint64(len(codeSignature)))
+	}
+	image = append(image, trailer...)
+
+	pth := filepath.Join(t.TempDir(), "Example.dmg")
+	require.NoError(t, os.WriteFile(pth, image, 0600))
+
+	return pth
+}
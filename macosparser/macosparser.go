@@ -0,0 +1,190 @@
+package macosparser
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"howett.net/plist"
+)
+
+const (
+	// maxExecutableSizeInBytes limits the size of the app executables read into memory to find their code signature.
+	maxExecutableSizeInBytes = 512 * 1024 * 1024
+	maxPlistSizeInBytes      = 10 * 1024 * 1024
+)
+
+// ErrNotFound is returned when the artifact doesn't contain an app.
+var ErrNotFound = errors.New("no app found")
+
+// ArtifactMetadata ...
+type ArtifactMetadata struct {
+	AppInfo       Info        `json:"app_info"`
+	FileSizeBytes int64       `json:"file_size_bytes"`
+	SigningInfo   SigningInfo `json:"signing_info,omitempty"`
+	Scheme        string      `json:"scheme,omitempty"`
+}
+
+// Info ...
+type Info struct {
+	AppTitle     string `json:"app_title"`
+	BundleID     string `json:"bundle_id"`
+	Version      string `json:"version"`
+	BuildNumber  string `json:"build_number"`
+	MinOSVersion string `json:"min_OS_version"`
+}
+
+// SigningInfo ...
+type SigningInfo struct {
+	// SigningIdentity is the common name of the signing certificate, like "Developer ID Application: Example Inc. (TEAMID1234)".
+	SigningIdentity string `json:"signing_identity,omitempty"`
+	TeamID          string `json:"team_id,omitempty"`
+}
+
+// Parser ...
+type Parser struct {
+	logger      log.Logger
+	fileManager fileutil.FileManager
+}
+
+// New ...
+func New(logger log.Logger, fileManager fileutil.FileManager) *Parser {
+	return &Parser{
+		logger:      logger,
+		fileManager: fileManager,
+	}
+}
+
+// infoPlist holds the keys of an app's Contents/Info.plist used for the deployment.
+type infoPlist struct {
+	BundleName         string `plist:"CFBundleName"`
+	BundleDisplayName  string `plist:"CFBundleDisplayName"`
+	BundleIdentifier   string `plist:"CFBundleIdentifier"`
+	ShortVersionString string `plist:"CFBundleShortVersionString"`
+	BundleVersion      string `plist:"CFBundleVersion"`
+	MinimumSystem      string `plist:"LSMinimumSystemVersion"`
+	BundleExecutable   string `plist:"CFBundleExecutable"`
+}
+
+func parseInfoPlist(content []byte) (infoPlist, error) {
+	var info infoPlist
+	if _, err := plist.Unmarshal(content, &info); err != nil {
+		return infoPlist{}, fmt.Errorf("failed to parse Info.plist: %w", err)
+	}
+
+	return info, nil
+}
+
+func (i infoPlist) appInfo() Info {
+	title := i.BundleDisplayName
+	if title == "" {
+		title = i.BundleName
+	}
+
+	return Info{
+		AppTitle:     title,
+		BundleID:     i.BundleIdentifier,
+		Version:      i.ShortVersionString,
+		BuildNumber:  i.BundleVersion,
+		MinOSVersion: i.MinimumSystem,
+	}
+}
+
+func (m *Parser) fileSize(pth string) int64 {
+	fileSize, err := m.fileManager.FileSizeInBytes(pth)
+	if err != nil {
+		m.logger.Warnf("Failed to get file size, error: %s", err)
+	}
+
+	return fileSize
+}
+
+// findZipFile returns the shallowest file of the zip archive matching the pattern, resource forks stored by macOS are skipped.
+func findZipFile(reader *zip.Reader, pattern string) (*zip.File, error) {
+	var found *zip.File
+	for _, file := range reader.File {
+		if strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if match, err := path.Match(pattern, file.Name); err != nil {
+			return nil, err
+		} else if !match {
+			continue
+		}
+		if found == nil || strings.Count(file.Name, "/") < strings.Count(found.Name, "/") {
+			found = file
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: %w", pattern, ErrNotFound)
+	}
+
+	return found, nil
+}
+
+func readZipFile(file *zip.File, limit int64) ([]byte, error) {
+	if file.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, limit)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return io.ReadAll(io.LimitReader(reader, limit))
+}
+
+// readAppBundle reads the metadata of the .app bundle whose Contents directory matches the pattern,
+// the signing info is read from the code signature of the app's executable.
+func (m *Parser) readAppBundle(reader *zip.Reader, contentsPattern string) (infoPlist, SigningInfo, error) {
+	infoPlistFile, err := findZipFile(reader, contentsPattern+"/Info.plist")
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, err
+	}
+
+	content, err := readZipFile(infoPlistFile, maxPlistSizeInBytes)
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, fmt.Errorf("failed to read Info.plist: %w", err)
+	}
+	info, err := parseInfoPlist(content)
+	if err != nil {
+		return infoPlist{}, SigningInfo{}, err
+	}
+
+	var signingInfo SigningInfo
+	if info.BundleExecutable != "" {
+		executablePath := path.Join(path.Dir(infoPlistFile.Name), "MacOS", info.BundleExecutable)
+		signingInfo, err = readExecutableSigningInfo(reader, executablePath)
+		if err != nil {
+			m.logger.Warnf("Failed to read the code signature of %s: %s", executablePath, err)
+		}
+	}
+
+	return info, signingInfo, nil
+}
+
+func readExecutableSigningInfo(reader *zip.Reader, executablePath string) (SigningInfo, error) {
+	for _, file := range reader.File {
+		if file.Name != executablePath {
+			continue
+		}
+
+		executable, err := readZipFile(file, maxExecutableSizeInBytes)
+		if err != nil {
+			return SigningInfo{}, err
+		}
+
+		return machOSigningInfo(executable)
+	}
+
+	return SigningInfo{}, fmt.Errorf("executable: %w", ErrNotFound)
+}
@@ -0,0 +1,191 @@
+package macosparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/stretchr/testify/require"
+)
+
+var testAppInfo = Info{
+	AppTitle:     "Example",
+	BundleID:     "io.bitrise.example",
+	Version:      "1.2.3",
+	BuildNumber:  "45",
+	MinOSVersion: "12.0",
+}
+
+func TestParser_ParseXCArchiveData(t *testing.T) {
+	pth := writeTestZip(t, "Example.xcarchive.zip", map[string][]byte{
+		"Example.xcarchive/Info.plist":                                               []byte(testArchiveInfoPlist),
+		"Example.xcarchive/Products/Applications/Example.app/Contents/Info.plist":    []byte(testInfoPlist),
+		"Example.xcarchive/Products/Applications/Example.app/Contents/MacOS/Example": testMachO(testCodeSignature(t, true)),
+	})
+
+	got, err := newParser().ParseXCArchiveData(pth)
+	require.NoError(t, err)
+	require.Equal(t, testAppInfo, got.AppInfo)
+	require.Equal(t, SigningInfo{SigningIdentity: "Apple Development: John Doe (DEV1234567)", TeamID: "DEV1234567"}, got.SigningInfo)
+	require.Equal(t, "Example", got.Scheme)
+	require.Equal(t, fileSize(t, pth), got.FileSizeBytes)
+}
+
+func TestParser_ParseXCArchiveData_missingApp(t *testing.T) {
+	pth := writeTestZip(t, "Example.xcarchive.zip", map[string][]byte{
+		"Example.xcarchive/Info.plist": []byte(testArchiveInfoPlist),
+	})
+
+	_, err := newParser().ParseXCArchiveData(pth)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestParser_ParseAppZipData(t *testing.T) {
+	tests := []struct {
+		name            string
+		executable      []byte
+		wantSigningInfo SigningInfo
+	}{
+		{
+			name:            "Developer ID signed",
+			executable:      testMachO(testCodeSignature(t, true)),
+			wantSigningInfo: SigningInfo{SigningIdentity: testSigningIdentity, TeamID: testTeamID},
+		},
+		{
+			name:       "Ad-hoc signed",
+			executable: testMachO(testCodeSignature(t, false)),
+		},
+		{
+			name:       "Unsigned",
+			executable: testMachO(nil),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := writeTestZip(t, "Example.app.zip", map[string][]byte{
+				"Example.app/Contents/Info.plist":    []byte(testInfoPlist),
+				"Example.app/Contents/MacOS/Example": tt.executable,
+				// Resource forks added by the Finder are skipped.
+				"__MACOSX/Example.app/Contents/._Info.plist": []byte("resource fork"),
+			})
+
+			got, err := newParser().ParseAppZipData(pth)
+			require.NoError(t, err)
+			require.Equal(t, testAppInfo, got.AppInfo)
+			require.Equal(t, tt.wantSigningInfo, got.SigningInfo)
+			require.Empty(t, got.Scheme)
+		})
+	}
+}
+
+func TestParser_ParsePKGData(t *testing.T) {
+	tests := []struct {
+		name            string
+		signed          bool
+		files           map[string]string
+		wantAppInfo     Info
+		wantSigningInfo SigningInfo
+	}{
+		{
+			name:   "Signed product archive",
+			signed: true,
+			files: map[string]string{
+				"Distribution": `<?xml version="1.0" encoding="utf-8"?>
+<installer-gui-script minSpecVersion="1">
+    <title>Example Installer</title>
+    <product id="io.bitrise.example.pkg" version="1.2.3"/>
+    <allowed-os-versions><os-version min="12.0"/></allowed-os-versions>
+    <pkg-ref id="io.bitrise.example.pkg">
+        <bundle-version>
+            <bundle CFBundleShortVersionString="1.2.3" CFBundleVersion="45" id="io.bitrise.example" path="Example.app"/>
+        </bundle-version>
+    </pkg-ref>
+</installer-gui-script>`,
+			},
+			wantAppInfo:     testAppInfo,
+			wantSigningInfo: SigningInfo{SigningIdentity: testSigningIdentity, TeamID: testTeamID},
+		},
+		{
+			name: "Unsigned component package",
+			files: map[string]string{
+				"PackageInfo": `<?xml version="1.0" encoding="utf-8"?>
+<pkg-info format-version="2" identifier="io.bitrise.example.pkg" version="1.2.3" install-location="/Applications" auth="root">
+    <bundle path="./Example.app" id="io.bitrise.example" CFBundleShortVersionString="1.2.3" CFBundleVersion="45"/>
+</pkg-info>`,
+			},
+			wantAppInfo: Info{AppTitle: "Example", BundleID: "io.bitrise.example", Version: "1.2.3", BuildNumber: "45"},
+		},
+		{
+			name: "Package without app bundle",
+			files: map[string]string{
+				"PackageInfo": `<pkg-info format-version="2" identifier="io.bitrise.example.tool" version="2.0.0"/>`,
+			},
+			wantAppInfo: Info{BundleID: "io.bitrise.example.tool", Version: "2.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := writeTestPKG(t, tt.signed, tt.files)
+
+			got, err := newParser().ParsePKGData(pth)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAppInfo, got.AppInfo)
+			require.Equal(t, tt.wantSigningInfo, got.SigningInfo)
+			require.Equal(t, fileSize(t, pth), got.FileSizeBytes)
+		})
+	}
+}
+
+func TestParser_ParsePKGData_invalid(t *testing.T) {
+	pth := filepath.Join(t.TempDir(), "Example.pkg")
+	require.NoError(t, os.WriteFile(pth, []byte("not a xar archive, but long enough for its header"), 0600))
+
+	_, err := newParser().ParsePKGData(pth)
+	require.ErrorContains(t, err, "not a xar archive")
+
+	_, err = newParser().ParsePKGData(writeTestPKG(t, false, map[string]string{"Bom": "bom"}))
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestParser_ParseDMGData(t *testing.T) {
+	got, err := newParser().ParseDMGData(writeTestDMG(t, testCodeSignature(t, true)))
+	require.NoError(t, err)
+	require.Equal(t, SigningInfo{SigningIdentity: testSigningIdentity, TeamID: testTeamID}, got.SigningInfo)
+
+	got, err = newParser().ParseDMGData(writeTestDMG(t, nil))
+	require.NoError(t, err)
+	require.Equal(t, SigningInfo{}, got.SigningInfo)
+
+	pth := filepath.Join(t.TempDir(), "Example.dmg")
+	require.NoError(t, os.WriteFile(pth, make([]byte, 1024), 0600))
+	_, err = newParser().ParseDMGData(pth)
+	require.ErrorContains(t, err, "not a disk image")
+}
+
+func Test_readAppDir(t *testing.T) {
+	dir := t.TempDir()
+	contentsDir := filepath.Join(dir, "Example.app", "Contents")
+	require.NoError(t, os.MkdirAll(filepath.Join(contentsDir, "MacOS"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(contentsDir, "Info.plist"), []byte(testInfoPlist), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(contentsDir, "MacOS", "Example"), testMachO(testCodeSignature(t, true)), 0600))
+
+	info, signingInfo, err := readAppDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, testAppInfo, info.appInfo())
+	require.Equal(t, SigningInfo{SigningIdentity: testSigningIdentity, TeamID: testTeamID}, signingInfo)
+
+	_, _, err = readAppDir(t.TempDir())
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func newParser() *Parser {
+	return New(log.NewLogger(), fileutil.NewFileManager())
+}
+
+func fileSize(t *testing.T, pth string) int64 {
+	info, err := os.Stat(pth)
+	require.NoError(t, err)
+	return info.Size()
+}
@@ -0,0 +1,308 @@
+package macosparser
+
+import (
+	"compress/bzip2"
+	"compress/zlib"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	xarMagic         = 0x78617221 // "xar!"
+	xarHeaderSize    = 28
+	maxXarTOCInBytes = 16 * 1024 * 1024
+)
+
+// xarHeader is the big-endian header of xar archives, which .pkg installers are.
+type xarHeader struct {
+	Magic                 uint32
+	HeaderSize            uint16
+	Version               uint16
+	TOCLengthCompressed   uint64
+	TOCLengthUncompressed uint64
+	ChecksumAlgorithm     uint32
+}
+
+type xarTOC struct {
+	TOC struct {
+		Signature  *xarSignature `xml:"signature"`
+		XSignature *xarSignature `xml:"x-signature"`
+		Files      []xarFile     `xml:"file"`
+	} `xml:"toc"`
+}
+
+type xarSignature struct {
+	Certificates []string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+type xarFile struct {
+	Name  string    `xml:"name"`
+	Type  string    `xml:"type"`
+	Data  *xarData  `xml:"data"`
+	Files []xarFile `xml:"file"`
+}
+
+type xarData struct {
+	Length   int64 `xml:"length"`
+	Offset   int64 `xml:"offset"`
+	Size     int64 `xml:"size"`
+	Encoding struct {
+		Style string `xml:"style,attr"`
+	} `xml:"encoding"`
+}
+
+// distribution is the Distribution file of product archives, built by productbuild.
+type distribution struct {
+	Title   string `xml:"title"`
+	Product *struct {
+		ID      string `xml:"id,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"product"`
+	AllowedOSVersions     []osVersion `xml:"allowed-os-versions>os-version"`
+	VolumeCheckOSVersions []osVersion `xml:"volume-check>allowed-os-versions>os-version"`
+	PkgRefs               []struct {
+		Bundles []pkgBundle `xml:"bundle-version>bundle"`
+	} `xml:"pkg-ref"`
+}
+
+type osVersion struct {
+	Min string `xml:"min,attr"`
+}
+
+// packageInfo is the PackageInfo file of component packages, built by pkgbuild.
+type packageInfo struct {
+	Identifier string      `xml:"identifier,attr"`
+	Version    string      `xml:"version,attr"`
+	Bundles    []pkgBundle `xml:"bundle"`
+}
+
+type pkgBundle struct {
+	ID                 string `xml:"id,attr"`
+	Path               string `xml:"path,attr"`
+	ShortVersionString string `xml:"CFBundleShortVersionString,attr"`
+	BundleVersion      string `xml:"CFBundleVersion,attr"`
+}
+
+// ParsePKGData parses the metadata of a .pkg installer, either a product archive or a component package.
+func (m *Parser) ParsePKGData(pth string) (*ArtifactMetadata, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", pth, err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			m.logger.Warnf("%s", err)
+		}
+	}()
+
+	archive, err := readXar(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+
+	info, err := archive.appInfo()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+
+	signingInfo, err := archive.signingInfo()
+	if err != nil {
+		m.logger.Warnf("Failed to read the signature of %s: %s", pth, err)
+	}
+
+	return &ArtifactMetadata{
+		AppInfo:       info,
+		FileSizeBytes: m.fileSize(pth),
+		SigningInfo:   signingInfo,
+	}, nil
+}
+
+type xarArchive struct {
+	reader    io.ReaderAt
+	heapStart int64
+	toc       xarTOC
+}
+
+func readXar(reader io.ReaderAt) (xarArchive, error) {
+	var header xarHeader
+	if err := binary.Read(io.NewSectionReader(reader, 0, xarHeaderSize), binary.BigEndian, &header); err != nil {
+		return xarArchive{}, fmt.Errorf("failed to read xar header: %w", err)
+	}
+	if header.Magic != xarMagic {
+		return xarArchive{}, errors.New("not a xar archive")
+	}
+	if header.TOCLengthUncompressed > maxXarTOCInBytes {
+		return xarArchive{}, fmt.Errorf("xar table of contents is larger than %d bytes", maxXarTOCInBytes)
+	}
+
+	tocReader, err := zlib.NewReader(io.NewSectionReader(reader, int64(header.HeaderSize), int64(header.TOCLengthCompressed)))
+	if err != nil {
+		return xarArchive{}, fmt.Errorf("failed to decompress xar table of contents: %w", err)
+	}
+	var toc xarTOC
+	if err := xml.NewDecoder(io.LimitReader(tocReader, maxXarTOCInBytes)).Decode(&toc); err != nil {
+		return xarArchive{}, fmt.Errorf("failed to parse xar table of contents: %w", err)
+	}
+
+	return xarArchive{
+		reader:    reader,
+		heapStart: int64(header.HeaderSize) + int64(header.TOCLengthCompressed),
+		toc:       toc,
+	}, nil
+}
+
+// findFile returns the shallowest file with the given name.
+func (a xarArchive) findFile(name string) *xarFile {
+	files := a.toc.TOC.Files
+	for len(files) > 0 {
+		var nested []xarFile
+		for i := range files {
+			if files[i].Name == name && files[i].Type == "file" && files[i].Data != nil {
+				return &files[i]
+			}
+			nested = append(nested, files[i].Files...)
+		}
+		files = nested
+	}
+
+	return nil
+}
+
+func (a xarArchive) readFile(file *xarFile) ([]byte, error) {
+	if file.Data.Size > maxPlistSizeInBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", file.Name, maxPlistSizeInBytes)
+	}
+
+	var reader io.Reader = io.NewSectionReader(a.reader, a.heapStart+file.Data.Offset, file.Data.Length)
+	switch file.Data.Encoding.Style {
+	case "application/x-gzip":
+		zlibReader, err := zlib.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", file.Name, err)
+		}
+		defer func() {
+			_ = zlibReader.Close()
+		}()
+		reader = zlibReader
+	case "application/x-bzip2":
+		reader = bzip2.NewReader(reader)
+	case "", "application/octet-stream":
+	default:
+		return nil, fmt.Errorf("unsupported encoding of %s: %s", file.Name, file.Data.Encoding.Style)
+	}
+
+	return io.ReadAll(io.LimitReader(reader, maxPlistSizeInBytes))
+}
+
+func (a xarArchive) appInfo() (Info, error) {
+	if file := a.findFile("Distribution"); file != nil {
+		content, err := a.readFile(file)
+		if err != nil {
+			return Info{}, err
+		}
+
+		var dist distribution
+		if err := xml.Unmarshal(content, &dist); err != nil {
+			return Info{}, fmt.Errorf("failed to parse Distribution: %w", err)
+		}
+
+		return dist.appInfo(), nil
+	}
+
+	if file := a.findFile("PackageInfo"); file != nil {
+		content, err := a.readFile(file)
+		if err != nil {
+			return Info{}, err
+		}
+
+		var pkgInfo packageInfo
+		if err := xml.Unmarshal(content, &pkgInfo); err != nil {
+			return Info{}, fmt.Errorf("failed to parse PackageInfo: %w", err)
+		}
+
+		return pkgInfo.appInfo(), nil
+	}
+
+	return Info{}, fmt.Errorf("missing Distribution and PackageInfo: %w", ErrNotFound)
+}
+
+func (a xarArchive) signingInfo() (SigningInfo, error) {
+	signature := a.toc.TOC.Signature
+	if signature == nil {
+		signature = a.toc.TOC.XSignature
+	}
+	if signature == nil || len(signature.Certificates) == 0 {
+		return SigningInfo{}, nil
+	}
+
+	// The first certificate is the signer's, it is followed by its issuers.
+	encoded := strings.Join(strings.Fields(signature.Certificates[0]), "")
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return SigningInfo{}, fmt.Errorf("failed to decode signing certificate: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return SigningInfo{}, fmt.Errorf("failed to parse signing certificate: %w", err)
+	}
+
+	return certificateSigningInfo(certificate), nil
+}
+
+func (d distribution) appInfo() Info {
+	var info Info
+	if d.Product != nil {
+		info.BundleID = d.Product.ID
+		info.Version = d.Product.Version
+	}
+
+	var bundles []pkgBundle
+	for _, pkgRef := range d.PkgRefs {
+		bundles = append(bundles, pkgRef.Bundles...)
+	}
+	info = bundleInfo(info, bundles)
+
+	if info.AppTitle == "" {
+		info.AppTitle = d.Title
+	}
+
+	for _, versions := range [][]osVersion{d.AllowedOSVersions, d.VolumeCheckOSVersions} {
+		if info.MinOSVersion == "" && len(versions) > 0 {
+			info.MinOSVersion = versions[0].Min
+		}
+	}
+
+	return info
+}
+
+func (p packageInfo) appInfo() Info {
+	return bundleInfo(Info{BundleID: p.Identifier, Version: p.Version}, p.Bundles)
+}
+
+// bundleInfo overrides the package's info with the first app bundle installed by it.
+func bundleInfo(info Info, bundles []pkgBundle) Info {
+	for _, bundle := range bundles {
+		if !strings.HasSuffix(bundle.Path, ".app") {
+			continue
+		}
+
+		info.AppTitle = strings.TrimSuffix(path.Base(bundle.Path), ".app")
+		info.BundleID = bundle.ID
+		if bundle.ShortVersionString != "" {
+			info.Version = bundle.ShortVersionString
+		}
+		info.BuildNumber = bundle.BundleVersion
+
+		return info
+	}
+
+	return info
+}
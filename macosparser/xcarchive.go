@@ -0,0 +1,94 @@
+package macosparser
+
+import (
+	"archive/zip"
+	"fmt"
+	"path"
+
+	"howett.net/plist"
+)
+
+const (
+	xcarchiveInfoPlistPattern = "*.xcarchive/Info.plist"
+	appZipContentsPattern     = "*.app/Contents"
+)
+
+// archiveInfoPlist holds the keys of an xcarchive's Info.plist used for the deployment.
+type archiveInfoPlist struct {
+	SchemeName            string `plist:"SchemeName"`
+	ApplicationProperties struct {
+		SigningIdentity string `plist:"SigningIdentity"`
+		Team            string `plist:"Team"`
+	} `plist:"ApplicationProperties"`
+}
+
+// ParseXCArchiveData parses the metadata of a zipped macOS xcarchive.
+func (m *Parser) ParseXCArchiveData(pth string) (*ArtifactMetadata, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", pth, err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			m.logger.Warnf("%s", err)
+		}
+	}()
+
+	archiveInfoFile, err := findZipFile(&reader.Reader, xcarchiveInfoPlistPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+	content, err := readZipFile(archiveInfoFile, maxPlistSizeInBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read xcarchive Info.plist: %w", err)
+	}
+	var archiveInfo archiveInfoPlist
+	if _, err := plist.Unmarshal(content, &archiveInfo); err != nil {
+		return nil, fmt.Errorf("failed to parse xcarchive Info.plist: %w", err)
+	}
+
+	appContentsPattern := path.Join(path.Dir(archiveInfoFile.Name), "Products/Applications/*.app/Contents")
+	info, signingInfo, err := m.readAppBundle(&reader.Reader, appContentsPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+
+	// The archive's Info.plist names the identity even if the executable's signature can't be read.
+	if archiveInfo.ApplicationProperties.SigningIdentity != "" {
+		signingInfo.SigningIdentity = archiveInfo.ApplicationProperties.SigningIdentity
+	}
+	if archiveInfo.ApplicationProperties.Team != "" {
+		signingInfo.TeamID = archiveInfo.ApplicationProperties.Team
+	}
+
+	return &ArtifactMetadata{
+		AppInfo:       info.appInfo(),
+		FileSizeBytes: m.fileSize(pth),
+		SigningInfo:   signingInfo,
+		Scheme:        archiveInfo.SchemeName,
+	}, nil
+}
+
+// ParseAppZipData parses the metadata of a zipped .app bundle.
+func (m *Parser) ParseAppZipData(pth string) (*ArtifactMetadata, error) {
+	reader, err := zip.OpenReader(pth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", pth, err)
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			m.logger.Warnf("%s", err)
+		}
+	}()
+
+	info, signingInfo, err := m.readAppBundle(&reader.Reader, appZipContentsPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+	}
+
+	return &ArtifactMetadata{
+		AppInfo:       info.appInfo(),
+		FileSizeBytes: m.fileSize(pth),
+		SigningInfo:   signingInfo,
+	}, nil
+}
@@ -2,15 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"html/template"
+	"math"
+	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/envman/envman"
@@ -29,10 +33,22 @@ import (
 	pathutil2 "github.com/bitrise-io/go-utils/v2/pathutil"
 	"github.com/bitrise-io/go-utils/ziputil"
 	iosparser "github.com/bitrise-io/go-xcode/v2/metaparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/aabparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/androidarchive"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/bundletoolprovider"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployrules"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/fileredactor"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/httpclient"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/report"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/summary"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/test"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/universalapk"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders"
 )
 
@@ -53,30 +69,57 @@ var fileBaseNamesToSkip = []string{".DS_Store"}
 
 // Config ...
 type Config struct {
-	PipelineIntermediateFiles         string `env:"pipeline_intermediate_files"`
-	BuildURL                          string `env:"build_url,required"`
-	APIToken                          string `env:"build_api_token,required"`
-	IsCompress                        bool   `env:"is_compress,opt[true,false]"`
-	ZipName                           string `env:"zip_name"`
-	DeployPath                        string `env:"deploy_path"`
-	NotifyUserGroups                  string `env:"notify_user_groups"`
-	AlwaysNotifyUserGroups            string `env:"always_notify_user_groups"`
-	NotifyEmailList                   string `env:"notify_email_list"`
-	IsPublicPageEnabled               bool   `env:"is_enable_public_page,opt[true,false]"`
-	PublicInstallPageMapFormat        string `env:"public_install_page_url_map_format,required"`
-	PermanentDownloadURLMapFormat     string `env:"permanent_download_url_map_format,required"`
-	DetailsPageURLMapFormat           string `env:"details_page_url_map_format,required"`
-	BuildSlug                         string `env:"BITRISE_BUILD_SLUG,required"`
-	TestDeployDir                     string `env:"BITRISE_TEST_DEPLOY_DIR,required"`
-	AppSlug                           string `env:"BITRISE_APP_SLUG,required"`
-	AddonAPIBaseURL                   string `env:"addon_api_base_url,required"`
-	AddonAPIToken                     string `env:"addon_api_token"`
-	FilesToRedact                     string `env:"files_to_redact"`
-	DebugMode                         bool   `env:"debug_mode,opt[true,false]"`
-	UseLegacyXCResultExtractionMethod bool   `env:"use_legacy_xcresult_extraction_method,opt[true,false]"`
-	BundletoolVersion                 string `env:"bundletool_version,required"`
-	UploadConcurrency                 string `env:"BITRISE_DEPLOY_UPLOAD_CONCURRENCY"`
-	HTMLReportDir                     string `env:"BITRISE_HTML_REPORT_DIR"`
+	PipelineIntermediateFiles         string          `env:"pipeline_intermediate_files"`
+	BuildURL                          string          `env:"build_url,required"`
+	APIToken                          string          `env:"build_api_token,required"`
+	IsCompress                        bool            `env:"is_compress,opt[true,false]"`
+	ZipName                           string          `env:"zip_name"`
+	DeployPath                        string          `env:"deploy_path"`
+	NotifyUserGroups                  string          `env:"notify_user_groups"`
+	AlwaysNotifyUserGroups            string          `env:"always_notify_user_groups"`
+	NotifyEmailList                   string          `env:"notify_email_list"`
+	IsPublicPageEnabled               bool            `env:"is_enable_public_page,opt[true,false]"`
+	PublicInstallPageMapFormat        string          `env:"public_install_page_url_map_format,required"`
+	PermanentDownloadURLMapFormat     string          `env:"permanent_download_url_map_format,required"`
+	DetailsPageURLMapFormat           string          `env:"details_page_url_map_format,required"`
+	BuildSlug                         string          `env:"BITRISE_BUILD_SLUG,required"`
+	TestDeployDir                     string          `env:"BITRISE_TEST_DEPLOY_DIR,required"`
+	AppSlug                           string          `env:"BITRISE_APP_SLUG,required"`
+	AddonAPIBaseURL                   string          `env:"addon_api_base_url,required"`
+	AddonAPIToken                     string          `env:"addon_api_token"`
+	FilesToRedact                     string          `env:"files_to_redact"`
+	DebugMode                         bool            `env:"debug_mode,opt[true,false]"`
+	UseLegacyXCResultExtractionMethod bool            `env:"use_legacy_xcresult_extraction_method,opt[true,false]"`
+	BundletoolVersion                 string          `env:"bundletool_version,required"`
+	BundletoolPath                    string          `env:"bundletool_path"`
+	BundletoolCacheDir                string          `env:"bundletool_cache_dir"`
+	BundletoolSHA256                  string          `env:"bundletool_sha256"`
+	AABMetadataParser                 string          `env:"aab_metadata_parser,opt[bundletool,native]"`
+	UploadConcurrency                 string          `env:"upload_concurrency"`
+	HTMLReportDir                     string          `env:"BITRISE_HTML_REPORT_DIR"`
+	DeployTimeout                     int             `env:"deploy_timeout,range[0..]"`
+	RetryCount                        int             `env:"retry_count,range[0..10]"`
+	RetryWaitTime                     int             `env:"retry_wait_time,range[1..60]"`
+	HTTPTimeout                       int             `env:"http_timeout,range[0..]"`
+	TLSMinVersion                     string          `env:"tls_min_version,opt[1.2,1.3]"`
+	EnableHTTP2                       bool            `env:"enable_http2,opt[true,false]"`
+	ProxyURL                          stepconf.Secret `env:"proxy_url"`
+	NoProxy                           string          `env:"no_proxy"`
+	CABundlePath                      string          `env:"ca_bundle_path"`
+	UploadBandwidthLimit              string          `env:"upload_bandwidth_limit"`
+	UploadBandwidthLimitPerConnection string          `env:"upload_bandwidth_limit_per_connection"`
+	DryRun                            bool            `env:"dry_run,opt[true,false]"`
+	GenerateUniversalAPK              bool            `env:"generate_universal_apk,opt[true,false]"`
+	UniversalAPKKeystorePath          string          `env:"universal_apk_keystore_path"`
+	UniversalAPKKeystorePassword      stepconf.Secret `env:"universal_apk_keystore_password"`
+	UniversalAPKKeystoreAlias         string          `env:"universal_apk_keystore_alias"`
+	UniversalAPKKeyPassword           stepconf.Secret `env:"universal_apk_key_password"`
+	DebugSymbolsMap                   string          `env:"debug_symbols_map"`
+	DeployRules                       string          `env:"deploy_rules"`
+	ArtifactsFailurePolicy            failure.Policy  `env:"artifacts_failure_policy,opt[fail,warn]"`
+	IntermediateFilesFailurePolicy    failure.Policy  `env:"intermediate_files_failure_policy,opt[fail,warn]"`
+	TestResultsFailurePolicy          failure.Policy  `env:"test_results_failure_policy,opt[fail,warn]"`
+	HTMLReportsFailurePolicy          failure.Policy  `env:"html_reports_failure_policy,opt[fail,warn]"`
 }
 
 // PublicInstallPage ...
@@ -90,9 +133,27 @@ type ArtifactURLCollection struct {
 	PublicInstallPageURLs map[string]string
 	PermanentDownloadURLs map[string]string
 	DetailsPageURLs       map[string]string
+	SHA256Checksums       map[string]string
+	// UniversalAPKs maps the AABs to the universal APKs generated from them, by path,
+	// as the AABs of different modules might have the same file name.
+	UniversalAPKs map[string]string
 }
 
-const zippedXcarchiveExt = ".xcarchive.zip"
+const (
+	zippedXcarchiveExt = ".xcarchive.zip"
+	zippedAppExt       = ".app.zip"
+	deploySummaryFile  = "deploy-summary.json"
+
+	failureReasonOutputKey = "BITRISE_DEPLOY_FAILURE_REASON"
+
+	// bundletoolAABParser is the aab_metadata_parser input value for parsing the aab metadata with bundletool,
+	// otherwise the manifest of the aab is decoded natively by the aabparser package.
+	bundletoolAABParser = "bundletool"
+
+	// adaptiveUploadConcurrency is the upload_concurrency input value for adapting the number of concurrent uploads
+	// to the aggregate upload throughput.
+	adaptiveUploadConcurrency = "adaptive"
+)
 
 func fail(logger loggerV2.Logger, format string, v ...interface{}) {
 	logger.Errorf(format, v...)
@@ -124,6 +185,22 @@ func main() {
 		fail(logger, "public_install_page_url_map_format - %s", err)
 	}
 
+	deployRules, err := deployrules.Parse(config.DeployRules, notifiableArtifactTypes)
+	if err != nil {
+		fail(logger, "deploy_rules - %s", err)
+	}
+	if err := validateDeployRules(deployRules, logger); err != nil {
+		fail(logger, "deploy_rules - %s", err)
+	}
+
+	debugSymbolsMap, err := parseDebugSymbolsMap(config.DebugSymbolsMap)
+	if err != nil {
+		fail(logger, "debug_symbols_map - %s", err)
+	}
+
+	ctx, cancel := newStepContext(config)
+	defer cancel()
+
 	tmpDir, err := pathutil.NormalizedOSTempDirPath("__deploy-to-bitrise-io__")
 	if err != nil {
 		fail(logger, "Failed to create tmp dir, error: %s", err)
@@ -149,9 +226,21 @@ func main() {
 		fileManager := fileutil.NewFileManager()
 		redactor := fileredactor.NewFileRedactor(fileManager)
 		secrets := loadSecrets()
-		err = redactor.RedactFiles(filePaths, secrets)
-		if err != nil {
-			fail(logger, errorutil.FormattedError(fmt.Errorf("failed to redact files: %w", err)))
+		if config.DryRun {
+			filesToRedact, err := redactor.PreviewRedaction(filePaths, secrets)
+			if err != nil {
+				fail(logger, errorutil.FormattedError(fmt.Errorf("failed to preview file redaction: %w", err)))
+			}
+
+			logger.Printf("Files which would be redacted (%d):", len(filesToRedact))
+			for _, path := range filesToRedact {
+				logger.Printf("- %s", path)
+			}
+		} else {
+			err = redactor.RedactFiles(filePaths, secrets)
+			if err != nil {
+				fail(logger, errorutil.FormattedError(fmt.Errorf("failed to redact files: %w", err)))
+			}
 		}
 	} else {
 		logger.Printf("No files to redact...")
@@ -173,6 +262,12 @@ func main() {
 		}
 		clearedFilesToDeploy := clearDeployFiles(filesToDeploy, logger)
 		deployableItems = deployment.ConvertPaths(clearedFilesToDeploy)
+		for i, item := range deployableItems {
+			// The compressed deploy directory is the only item created in the tmp dir.
+			if filepath.Dir(item.Path) == tmpDir {
+				deployableItems[i].SourcePath = absDeployPth
+			}
+		}
 	}
 
 	if strings.TrimSpace(config.PipelineIntermediateFiles) != "" {
@@ -185,6 +280,23 @@ func main() {
 		}
 	}
 
+	deployableItems = addDebugSymbolsItems(deployableItems, debugSymbolsMap)
+
+	deployableItems, err = deployment.AttachCustomMetadata(deployableItems, logger)
+	if err != nil {
+		fail(logger, "%s", err)
+	}
+
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		fail(logger, "Failed to create the HTTP client: %s", err)
+	}
+	uploadScheduler := newUploadScheduler(config)
+	httpClient.Transport = uploadScheduler.Transport(httpClient.Transport)
+
+	summaryRecorder := summary.NewRecorder()
+	failures := phaseFailures{}
+
 	if len(deployableItems) == 0 {
 		logger.Printf("No deployment files were defined. Please check the deploy_path and pipeline_intermediate_files inputs.")
 	} else {
@@ -193,43 +305,153 @@ func main() {
 
 		logger.Println()
 		logger.Infof("Deploying files...")
-		artifactURLCollection, errors := deploy(deployableItems, config, logger)
-		if len(errors) > 0 {
-			logger.Println()
-
-			var errMessage string
-			for _, err := range errors {
-				errMessage += errorutil.FormattedError(err)
-			}
-
-			fail(logger, "%s", errMessage)
+		artifactURLCollection, errors := deploy(ctx, httpClient, uploadScheduler, deployableItems, debugSymbolsMap, deployRules, config, summaryRecorder, logger)
+		for _, err := range errors {
+			failures.add(err)
 		}
 
-		logger.Donef("Success")
-		logger.Printf("You can find the Build Artifact on the Build's page: %s", config.BuildURL)
+		if len(errors) > 0 {
+			logger.Warnf("Failed to deploy %d file(s)", len(errors))
+		} else if config.DryRun {
+			logger.Donef("Dry run finished, no files were uploaded")
+		} else {
+			logger.Donef("Success")
+			logger.Printf("You can find the Build Artifact on the Build's page: %s", config.BuildURL)
+		}
 
 		if err := exportInstallPages(artifactURLCollection, config, logger); err != nil {
 			fail(logger, "%s", err)
 		}
 	}
 
+	testResultsOutcome := summary.UploadOutcome{Status: summary.StatusSkipped}
 	if config.AddonAPIToken != "" {
-		deployTestResults(config, logger)
+		var err error
+		testResultsOutcome, err = deployTestResults(ctx, httpClient, uploadScheduler, config, logger)
+		failures.addToPhase(failure.TestResults, err)
 	}
+	summaryRecorder.SetTestResults(testResultsOutcome)
 
+	htmlReportsOutcome := summary.UploadOutcome{Status: summary.StatusSkipped}
 	if config.HTMLReportDir != "" {
-		deployHTMLReports(config, logger)
+		var errs []error
+		htmlReportsOutcome, errs = deployHTMLReports(ctx, httpClient, uploadScheduler, config, logger)
+		failures.addToPhase(failure.HTMLReports, errs...)
+	}
+	summaryRecorder.SetHTMLReports(htmlReportsOutcome)
+
+	exportDeploySummary(summaryRecorder, tmpDir, logger)
+
+	if reason := failureReason(failures); reason != "" {
+		if err := tools.ExportEnvironmentWithEnvman(failureReasonOutputKey, string(reason)); err != nil {
+			logger.Warnf("Failed to export %s: %s", failureReasonOutputKey, err)
+		}
+	}
+
+	if err := handleFailures(failures, failurePolicies(config), logger); err != nil {
+		fail(logger, "%s", err)
+	}
+}
+
+// exportDeploySummary writes the deploy summary to a JSON file and exports its path.
+// Failing to export the summary doesn't fail the Step, as the deployment itself has already finished.
+func exportDeploySummary(recorder *summary.Recorder, tmpDir string, logger loggerV2.Logger) {
+	pth := filepath.Join(tmpDir, deploySummaryFile)
+	if err := recorder.Write(pth); err != nil {
+		logger.Warnf("%s", err)
+		return
+	}
+
+	if err := tools.ExportEnvironmentWithEnvman("BITRISE_DEPLOY_SUMMARY_PATH", pth); err != nil {
+		logger.Warnf("Failed to export BITRISE_DEPLOY_SUMMARY_PATH: %s", err)
+		return
+	}
+	logger.Printf("The deploy summary is now available in the Environment Variable: BITRISE_DEPLOY_SUMMARY_PATH (value: %s)", pth)
+}
+
+// newStepContext returns the context shared by every upload, which expires after deploy_timeout seconds if the input is set.
+func newStepContext(config Config) (context.Context, context.CancelFunc) {
+	if config.DeployTimeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+
+	return context.WithTimeout(context.Background(), time.Duration(config.DeployTimeout)*time.Second)
+}
+
+// newRetryPolicy returns the retry policy of the backend and storage requests, configured by the retry_count and retry_wait_time inputs.
+func newRetryPolicy(config Config) retrypolicy.Policy {
+	policy := retrypolicy.DefaultPolicy()
+	policy.MaxRetries = uint(config.RetryCount)
+	if config.RetryWaitTime > 0 {
+		policy.BaseDelay = time.Duration(config.RetryWaitTime) * time.Second
+	}
+
+	return policy
+}
+
+// newHTTPClient returns the client shared by all backend and storage requests of the Step, configured by the http_timeout,
+// tls_min_version, enable_http2, the proxy and CA bundle and the upload bandwidth limit inputs.
+func newHTTPClient(config Config) (*http.Client, error) {
+	tlsMinVersion, err := httpclient.ParseTLSVersion(config.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+	uploadRate, err := parseBandwidthLimit(config.UploadBandwidthLimit)
+	if err != nil {
+		return nil, fmt.Errorf("upload_bandwidth_limit - %w", err)
+	}
+	uploadRatePerConnection, err := parseBandwidthLimit(config.UploadBandwidthLimitPerConnection)
+	if err != nil {
+		return nil, fmt.Errorf("upload_bandwidth_limit_per_connection - %w", err)
+	}
+
+	clientConfig := httpclient.DefaultConfig()
+	clientConfig.Timeout = time.Duration(config.HTTPTimeout) * time.Second
+	clientConfig.TLSMinVersion = tlsMinVersion
+	clientConfig.DisableHTTP2 = !config.EnableHTTP2
+	clientConfig.ProxyURL = strings.TrimSpace(string(config.ProxyURL))
+	clientConfig.NoProxy = config.NoProxy
+	clientConfig.CABundlePath = config.CABundlePath
+	clientConfig.UploadRate = uploadRate
+	clientConfig.UploadRatePerConnection = uploadRatePerConnection
+
+	return httpclient.New(clientConfig)
+}
+
+// parseBandwidthLimit parses a bandwidth limit in MB/s (1 MB = 1,000,000 bytes) into bytes per second,
+// an empty or zero limit means no limit.
+func parseBandwidthLimit(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	megabytes, err := strconv.ParseFloat(value, 64)
+	if err != nil || megabytes < 0 || math.IsInf(megabytes, 0) || math.IsNaN(megabytes) {
+		return 0, fmt.Errorf("invalid bandwidth limit: %s, it should be a non-negative number of MB/s", value)
 	}
+
+	return int64(megabytes * 1000 * 1000), nil
 }
 
-func deployHTMLReports(config Config, logger loggerV2.Logger) {
+func deployHTMLReports(ctx context.Context, httpClient *http.Client, uploadScheduler *scheduler.Scheduler, config Config, logger loggerV2.Logger) (summary.UploadOutcome, []error) {
 	logger.Println()
 	logger.Infof("Deploying html reports...")
 
-	concurrency := determineConcurrency(Config{})
-	uploader := report.NewHTMLReportUploader(config.HTMLReportDir, config.BuildURL, config.APIToken, concurrency, logger)
+	uploader := report.NewHTMLReportUploader(config.HTMLReportDir, config.BuildURL, config.APIToken, uploadScheduler, httpClient, newRetryPolicy(config), logger)
+
+	if config.DryRun {
+		if planErrors := uploader.PlanReports(); len(planErrors) > 0 {
+			logger.Errorf("Failed to collect html reports:")
+			for _, err := range planErrors {
+				logger.Errorf("- %s", err)
+			}
+			return summary.NewUploadOutcome(planErrors...), planErrors
+		}
+		return summary.UploadOutcome{Status: summary.StatusSkipped}, nil
+	}
 
-	uploadErrors := uploader.DeployReports()
+	uploadErrors := uploader.DeployReports(ctx)
 	if 0 < len(uploadErrors) {
 		logger.Errorf("Failed to upload html reports:")
 		for _, err := range uploadErrors {
@@ -238,6 +460,8 @@ func deployHTMLReports(config Config, logger loggerV2.Logger) {
 	} else {
 		logger.Donef("Successful html report upload")
 	}
+
+	return summary.NewUploadOutcome(uploadErrors...), uploadErrors
 }
 
 func loadSecrets() []string {
@@ -291,9 +515,38 @@ func exportInstallPages(artifactURLCollection ArtifactURLCollection, config Conf
 		}
 		log.Printf("A map of deployed files and their details page urls is now available in the Environment Variable: BITRISE_ARTIFACT_DETAILS_PAGE_URL_MAP (value: %s)", value)
 	}
+	if len(artifactURLCollection.SHA256Checksums) > 0 {
+		value := fileMapValue(artifactURLCollection.SHA256Checksums)
+		if err := tools.ExportEnvironmentWithEnvman("BITRISE_ARTIFACT_SHA256_MAP", value); err != nil {
+			return fmt.Errorf("failed to export BITRISE_ARTIFACT_SHA256_MAP: %s", err)
+		}
+		logger.Printf("A map of deployed files and their SHA-256 checksums is now available in the Environment Variable: BITRISE_ARTIFACT_SHA256_MAP (value: %s)", value)
+	}
+	if len(artifactURLCollection.UniversalAPKs) > 0 {
+		value := fileMapValue(artifactURLCollection.UniversalAPKs)
+		if err := tools.ExportEnvironmentWithEnvman("BITRISE_UNIVERSAL_APK_MAP", value); err != nil {
+			return fmt.Errorf("failed to export BITRISE_UNIVERSAL_APK_MAP: %s", err)
+		}
+		logger.Printf("A map of deployed AABs and their generated universal APKs is now available in the Environment Variable: BITRISE_UNIVERSAL_APK_MAP (value: %s)", value)
+	}
 	return nil
 }
 
+// fileMapValue formats a map keyed by file names or paths as a file=>value|file=>value list, ordered by the keys.
+func fileMapValue(values map[string]string) string {
+	var files []string
+	for file := range values {
+		files = append(files, file)
+	}
+	slices.Sort(files)
+
+	var entries []string
+	for _, file := range files {
+		entries = append(entries, file+"=>"+values[file])
+	}
+	return strings.Join(entries, "|")
+}
+
 func mapURLsToInstallPages(URLs map[string]string) []PublicInstallPage {
 	var pages []PublicInstallPage
 	for file, url := range URLs {
@@ -453,17 +706,18 @@ func stepNameWithIndex(stepInfo models.TestResultStepInfo) string {
 	return fmt.Sprintf("%d. Step (%s)", stepInfo.Number, name)
 }
 
-func deployTestResults(config Config, logger loggerV2.Logger) {
+func deployTestResults(ctx context.Context, httpClient *http.Client, uploadScheduler *scheduler.Scheduler, config Config, logger loggerV2.Logger) (summary.UploadOutcome, error) {
 	logger.Println()
 	logger.Infof("Collecting test results...")
 	testResults, err := test.ParseTestResults(config.TestDeployDir, config.UseLegacyXCResultExtractionMethod, logger)
 	if err != nil {
-		logger.Warnf("Failed to parse test results: %s", err)
-		return
+		err = fmt.Errorf("failed to parse test results: %w", err)
+		logger.Warnf("%s", err)
+		return summary.NewUploadOutcome(err), err
 	}
 	if len(testResults) == 0 {
 		logger.Printf("No test results found")
-		return
+		return summary.UploadOutcome{Status: summary.StatusSkipped}, nil
 	}
 
 	for i, result := range testResults {
@@ -480,22 +734,33 @@ func deployTestResults(config Config, logger loggerV2.Logger) {
 		}
 	}
 
+	if config.DryRun {
+		logger.Printf("Dry run, the test results are not uploaded")
+		return summary.UploadOutcome{Status: summary.StatusSkipped}, nil
+	}
+
 	logger.Println()
 	logger.Infof("Deploying test results...")
-	if err := testResults.Upload(config.AddonAPIToken, config.AddonAPIBaseURL, config.AppSlug, config.BuildSlug, logger); err != nil {
-		logger.Warnf("Failed to deploy test results: %s", err)
+	err = testResults.Upload(ctx, config.AddonAPIToken, config.AddonAPIBaseURL, config.AppSlug, config.BuildSlug, httpClient, newRetryPolicy(config), uploadScheduler, logger)
+	if err != nil {
+		err = fmt.Errorf("failed to deploy test results: %w", err)
+		logger.Warnf("%s", err)
 	} else {
 		logger.Donef("Success")
 	}
+
+	return summary.NewUploadOutcome(err), err
 }
 
-func findAPKsAndAABs(items []deployment.DeployableItem) (apks []deployment.DeployableItem, aabs []deployment.DeployableItem, others []deployment.DeployableItem) {
+func findAPKsAndAABs(items []deployment.DeployableItem) (apks []deployment.DeployableItem, aabs []deployment.DeployableItem, androidArchives []deployment.DeployableItem, others []deployment.DeployableItem) {
 	for _, item := range items {
 		switch getFileType(item.Path) {
 		case ".apk":
 			apks = append(apks, item)
 		case ".aab":
 			aabs = append(aabs, item)
+		case androidarchive.APKSExt, androidarchive.XAPKExt:
+			androidArchives = append(androidArchives, item)
 		default:
 			others = append(others, item)
 		}
@@ -503,72 +768,382 @@ func findAPKsAndAABs(items []deployment.DeployableItem) (apks []deployment.Deplo
 	return
 }
 
-func deploy(deployableItems []deployment.DeployableItem, config Config, logger loggerV2.Logger) (ArtifactURLCollection, []error) {
-	apks, aabs, others := findAPKsAndAABs(deployableItems)
-
-	var androidArtifacts []string
-	for _, artifacts := range append(apks, aabs...) {
-		androidArtifacts = append(androidArtifacts, artifacts.Path)
-	}
+func deploy(ctx context.Context, httpClient *http.Client, uploadScheduler *scheduler.Scheduler, deployableItems []deployment.DeployableItem, debugSymbolsMap map[string]string, deployRules []deployrules.Rule, config Config, summaryRecorder *summary.Recorder, logger loggerV2.Logger) (ArtifactURLCollection, []error) {
+	debugSymbols, deployableItems := findDebugSymbols(deployableItems, debugSymbolsMap, logger)
+	apks, aabs, androidArchives, others := findAPKsAndAABs(deployableItems)
 
 	artifactURLCollection := ArtifactURLCollection{
 		PublicInstallPageURLs: map[string]string{},
 		PermanentDownloadURLs: map[string]string{},
 		DetailsPageURLs:       map[string]string{},
+		SHA256Checksums:       map[string]string{},
+		UniversalAPKs:         map[string]string{},
 	}
 	var err error
 	var errorCollection []error
-	var wg sync.WaitGroup
+	var deployedItems, notDeployedItems []string
+
+	fileManager := fileutil.NewFileManager()
+	var aabParser uploaders.AABParser = aabparser.New(logger, fileManager)
+
+	var bTool bundletool.Path
+	if len(aabs) > 0 && config.DryRun {
+		logger.Warnf("Dry run, bundletool is not downloaded: aab metadata is parsed natively")
+		if config.GenerateUniversalAPK {
+			logger.Warnf("Dry run, universal APKs are not generated")
+		}
+	} else if len(aabs) > 0 && (config.AABMetadataParser == bundletoolAABParser || config.GenerateUniversalAPK) {
+		bTool, err = bundletoolprovider.New(bundletoolprovider.Config{
+			Version:   config.BundletoolVersion,
+			LocalPath: config.BundletoolPath,
+			CacheDir:  config.BundletoolCacheDir,
+			SHA256:    config.BundletoolSHA256,
+		}, logger).Provide()
+		if err != nil && config.GenerateUniversalAPK {
+			// The aab metadata is still parsed natively, but the universal APKs can't be generated.
+			errorCollection = handleDeploymentFailureError(fmt.Errorf("failed to provision bundletool, universal APKs are not generated: %w", err), errorCollection, logger)
+		} else if err != nil {
+			logger.Warnf("Failed to provision bundletool, falling back to the native aab metadata parser: %s", err)
+		} else if config.AABMetadataParser == bundletoolAABParser {
+			aabParser = androidparser.New(uploaders.NewLogger(), bTool, fileManager)
+		}
+
+		if err == nil && config.GenerateUniversalAPK {
+			universalAPKs, errs := generateUniversalAPKs(aabs, bTool, config, logger)
+			for _, err := range errs {
+				errorCollection = handleDeploymentFailureError(err, errorCollection, logger)
+			}
+			for _, apk := range universalAPKs {
+				artifactURLCollection.UniversalAPKs[apk.GeneratedFrom] = apk.Path
+			}
+			apks = append(apks, universalAPKs...)
+		}
+	}
+
+	var androidArtifacts []string
+	for _, artifacts := range append(apks, aabs...) {
+		androidArtifacts = append(androidArtifacts, artifacts.Path)
+	}
 
-	concurrency := determineConcurrency(config)
-	jobs := make(chan bool, concurrency)
-	combinedItems := append(append(apks, aabs...), others...)
+	combinedItems := append(append(append(apks, aabs...), androidArchives...), others...)
 	mapLock := &sync.RWMutex{}
 	errLock := &sync.RWMutex{}
 
-	var bTool bundletool.Path
-	if len(aabs) > 0 {
-		bTool, err = bundletool.New(config.BundletoolVersion)
+	androidParser := androidparser.New(uploaders.NewLogger(), bTool, fileManager)
+	iosParser := iosparser.New(logger, fileManager)
+	macosParser := macosparser.New(logger, fileManager)
+	var uploader *uploaders.Uploader
+	if config.DryRun {
+		uploader = uploaders.NewDryRun(logger, fileManager, androidParser, aabParser, iosParser, macosParser)
+	} else {
+		uploader = uploaders.New(logger, fileManager, androidParser, aabParser, iosParser, macosParser, uploaders.NewArtifactClient(httpClient, newRetryPolicy(config)))
+	}
+
+	itemSettings := func(item deployment.DeployableItem) deployrules.Settings {
+		settings, _ := deployrules.Resolve(deployRules, defaultDeploySettings(config), item.Path, deployType(item.Path))
+		return settings
+	}
+
+	// artifactIDs are the IDs of the deployed artifacts by their absolute path, to reference them from their debug symbols.
+	artifactIDs := map[string]string{}
+	deployItems := func(items []deployment.DeployableItem, itemType func(pth string) string, deployItem func(item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error)) {
+		var wg sync.WaitGroup
+		for _, item := range items {
+			wg.Add(1)
+
+			go func(item deployment.DeployableItem) {
+				defer wg.Done()
+
+				if err := uploadScheduler.Acquire(ctx); err != nil {
+					err := fmt.Errorf("%s was not deployed: %w", item.Path, err)
+					summaryRecorder.AddItem(summary.NewItem(item, itemType(item.Path), nil, err))
+					errLock.Lock()
+					errorCollection = handleDeploymentFailureError(withItemPhases(item, err), errorCollection, logger)
+					notDeployedItems = append(notDeployedItems, item.Path)
+					errLock.Unlock()
+					return
+				}
+
+				artifactURLs, err := deployItem(item)
+				var uploadReport *uploaders.UploadReport
+				var uploadedBytes int64
+				if report, ok := uploader.Report(item.Path); ok {
+					uploadReport = &report
+					for _, transfer := range report.Transfers {
+						uploadedBytes += transfer.Size
+					}
+				}
+				uploadScheduler.Release(uploadedBytes, err)
+				summaryRecorder.AddItem(summary.NewItem(item, itemType(item.Path), uploadReport, err))
+				if err != nil {
+					errLock.Lock()
+					errorCollection = handleDeploymentFailureError(withItemPhases(item, err), errorCollection, logger)
+					notDeployedItems = append(notDeployedItems, item.Path)
+					errLock.Unlock()
+				} else {
+					fillURLMaps(mapLock, artifactURLCollection, artifactURLs, item.Path, itemSettings(item).IsEnablePublicPage)
+					mapLock.Lock()
+					if len(artifactURLs) > 0 {
+						artifactIDs[absPath(item.Path)] = artifactURLs[0].ArtifactID
+					}
+					mapLock.Unlock()
+					errLock.Lock()
+					deployedItems = append(deployedItems, item.Path)
+					errLock.Unlock()
+				}
+			}(item)
+		}
+		wg.Wait()
+	}
+
+	deployItems(combinedItems, deployType, func(item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+		settings, rule := deployrules.Resolve(deployRules, defaultDeploySettings(config), item.Path, deployType(item.Path))
+		if rule >= 0 {
+			logger.Printf("Deploy rule #%d applies to %s", rule+1, item.Path)
+		}
+		return deploySingleItem(ctx, logger, uploader, item, config, settings, androidArtifacts)
+	})
+
+	// Debug symbols are deployed after the artifacts, as they reference the ID of their artifact.
+	symbolsByPath := map[string]debugSymbolsFile{}
+	var symbolItems []deployment.DeployableItem
+	for _, symbols := range debugSymbols {
+		if symbols.err != nil {
+			summaryRecorder.AddItem(summary.NewItem(symbols.item, string(symbols.symbolsType), nil, symbols.err))
+			errorCollection = handleDeploymentFailureError(withItemPhases(symbols.item, symbols.err), errorCollection, logger)
+			continue
+		}
+		symbolsByPath[symbols.item.Path] = symbols
+		symbolItems = append(symbolItems, symbols.item)
+	}
+	deployItems(symbolItems, func(pth string) string {
+		return string(symbolsByPath[pth].symbolsType)
+	}, func(item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+		return deployDebugSymbols(ctx, logger, uploader, symbolsByPath[item.Path], artifactIDs, config)
+	})
+
+	uploader.Wait()
+
+	if orphans := uploader.OrphanedArtifacts(); len(orphans) > 0 {
+		logger.Println()
+		logger.Warnf("Failed to clean up %d artifact record(s) of failed uploads, they are left on the build page without a file:", len(orphans))
+		for _, orphan := range orphans {
+			logger.Warnf("- artifact %s of %s: %s", orphan.ArtifactID, orphan.Path, orphan.Err)
+		}
+	}
+
+	if ctx.Err() != nil {
+		logDeadlineSummary(deployedItems, notDeployedItems, logger)
+	}
+
+	if config.DryRun {
+		logDeployPlan(uploader.Plan(), logger)
+	}
+
+	return artifactURLCollection, errorCollection
+}
+
+// generateUniversalAPKs generates the universal APK of each AAB, the APKs are deployed as the AABs' universal split.
+func generateUniversalAPKs(aabs []deployment.DeployableItem, bTool bundletool.Path, config Config, logger loggerV2.Logger) ([]deployment.DeployableItem, []error) {
+	logger.Println()
+	logger.Infof("Generating universal APKs...")
+
+	outputDir, err := pathutil.NormalizedOSTempDirPath("universal-apks")
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to create universal APK dir: %w", err)}
+	}
+
+	var keystore universalapk.Keystore
+	if config.UniversalAPKKeystorePath != "" {
+		keystore, err = universalapk.NewKeystore(config.UniversalAPKKeystorePath, string(config.UniversalAPKKeystorePassword), config.UniversalAPKKeystoreAlias, string(config.UniversalAPKKeyPassword))
+	} else {
+		logger.Printf("No keystore provided, signing universal APKs with the debug keystore")
+		keystore, err = universalapk.DebugKeystore(outputDir, logger)
+	}
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to set up keystore for universal APKs: %w", err)}
+	}
+
+	generator := universalapk.New(bTool, keystore, outputDir, logger)
+
+	var items []deployment.DeployableItem
+	var errs []error
+	for _, aab := range aabs {
+		apkPth, err := generator.Generate(aab.Path)
 		if err != nil {
-			errorCollection = handleDeploymentFailureError(err, errorCollection, logger)
+			errs = append(errs, fmt.Errorf("failed to generate universal APK for %s: %w", aab.Path, err))
+			continue
 		}
+
+		logger.Printf("- %s => %s", aab.Path, apkPth)
+		items = append(items, deployment.DeployableItem{
+			Path:              apkPth,
+			ArchiveAsArtifact: true,
+			GeneratedFrom:     aab.Path,
+		})
 	}
-	fileManager := fileutil.NewFileManager()
-	uploader := uploaders.New(
-		logger,
-		fileManager,
-		androidparser.New(uploaders.NewLogger(), bTool, fileManager),
-		iosparser.New(logger, fileManager),
-	)
 
-	for _, item := range combinedItems {
-		wg.Add(1)
+	return items, errs
+}
+
+// debugSymbolsFile is a debug symbols file to deploy, and the artifact it belongs to.
+type debugSymbolsFile struct {
+	item        deployment.DeployableItem
+	symbolsType debugsymbols.Type
+	metadata    debugsymbols.Metadata
+	parentPath  string
+	// err is set if an explicitly mapped debug symbols file is invalid.
+	err error
+}
 
-		go func(item deployment.DeployableItem) {
-			defer wg.Done()
+// parseDebugSymbolsMap parses the newline separated list of {debug symbols path}=>{artifact path} pairs,
+// the returned map is keyed by the absolute path of the debug symbols.
+func parseDebugSymbolsMap(value string) (map[string]string, error) {
+	debugSymbolsMap := map[string]string{}
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		symbolsPth, artifactPth, ok := strings.Cut(line, "=>")
+		symbolsPth, artifactPth = strings.TrimSpace(symbolsPth), strings.TrimSpace(artifactPth)
+		if !ok || symbolsPth == "" || artifactPth == "" {
+			return nil, fmt.Errorf("invalid debug symbols mapping: %s, expected format: {debug symbols path}=>{artifact path}", line)
+		}
 
-			jobs <- true
+		debugSymbolsMap[absPath(symbolsPth)] = absPath(artifactPth)
+	}
+
+	return debugSymbolsMap, nil
+}
+
+// addDebugSymbolsItems adds the explicitly mapped debug symbols to the deployable items, if they are not deployed yet.
+func addDebugSymbolsItems(items []deployment.DeployableItem, debugSymbolsMap map[string]string) []deployment.DeployableItem {
+	deployed := map[string]bool{}
+	for _, item := range items {
+		deployed[absPath(item.Path)] = true
+	}
+
+	var symbolsPths []string
+	for symbolsPth := range debugSymbolsMap {
+		if !deployed[symbolsPth] {
+			symbolsPths = append(symbolsPths, symbolsPth)
+		}
+	}
+	slices.Sort(symbolsPths)
+
+	for _, symbolsPth := range symbolsPths {
+		items = append(items, deployment.DeployableItem{Path: symbolsPth, ArchiveAsArtifact: true})
+	}
+
+	return items
+}
+
+// findDebugSymbols separates the debug symbols from the other deployable items, and finds the artifacts they belong to.
+// Debug symbols are detected by their name, invalid ones are deployed as regular files unless they are explicitly mapped.
+func findDebugSymbols(items []deployment.DeployableItem, debugSymbolsMap map[string]string, logger loggerV2.Logger) (symbols []debugSymbolsFile, others []deployment.DeployableItem) {
+	var artifactPths []string
+	for _, item := range items {
+		artifactPths = append(artifactPths, absPath(item.Path))
+	}
+
+	for _, item := range items {
+		parentPth, isMapped := debugSymbolsMap[absPath(item.Path)]
 
-			artifactURLs, err := deploySingleItem(logger, uploader, item, config, androidArtifacts)
+		var symbolsType debugsymbols.Type
+		if isMapped {
+			var err error
+			symbolsType, err = debugsymbols.DetectByContent(item.Path)
 			if err != nil {
-				errLock.Lock()
-				errorCollection = handleDeploymentFailureError(err, errorCollection, logger)
-				errLock.Unlock()
+				symbols = append(symbols, debugSymbolsFile{item: item, symbolsType: "file", err: err})
+				continue
+			}
+		} else {
+			var ok bool
+			if symbolsType, ok = debugsymbols.Detect(item.Path, artifactPths); !ok {
+				others = append(others, item)
+				continue
+			}
+		}
+
+		metadata, err := debugsymbols.Read(item.Path, symbolsType)
+		if err != nil {
+			if isMapped {
+				symbols = append(symbols, debugSymbolsFile{item: item, symbolsType: symbolsType, err: err})
 			} else {
-				fillURLMaps(mapLock, artifactURLCollection, artifactURLs, item.Path, config.IsPublicPageEnabled)
+				logger.Warnf("%s, deploying it as a regular file", err)
+				others = append(others, item)
 			}
+			continue
+		}
 
-			<-jobs
-		}(item)
+		if !isMapped {
+			parentPth, _ = debugsymbols.FindParent(item.Path, symbolsType, artifactPths)
+		}
+		if parentPth == "" {
+			logger.Warnf("No artifact found for the %s file: %s, use the debug_symbols_map input to specify it", symbolsType, item.Path)
+		}
+
+		symbols = append(symbols, debugSymbolsFile{item: item, symbolsType: symbolsType, metadata: metadata, parentPath: parentPth})
 	}
 
-	wg.Wait()
-	uploader.Wait()
+	return symbols, others
+}
 
-	return artifactURLCollection, errorCollection
+func deployDebugSymbols(ctx context.Context, logger loggerV2.Logger, uploader *uploaders.Uploader, symbols debugSymbolsFile, artifactIDs map[string]string, config Config) ([]uploaders.ArtifactURLs, error) {
+	defer logger.Println()
+
+	logger.Printf("Deploying %s file: %s", symbols.symbolsType, symbols.item.Path)
+
+	metadata := symbols.metadata
+	if symbols.parentPath != "" {
+		logger.Printf("Debug symbols of: %s", symbols.parentPath)
+		metadata.ParentFileName = filepath.Base(symbols.parentPath)
+		metadata.ParentArtifactID = artifactIDs[symbols.parentPath]
+		if metadata.ParentArtifactID == "" && !config.DryRun {
+			logger.Warnf("%s was not deployed, the debug symbols only reference its file name", symbols.parentPath)
+		}
+	}
+
+	return uploader.DeployDebugSymbols(ctx, symbols.item, metadata, config.BuildURL, config.APIToken)
+}
+
+// absPath returns the absolute path of pth, or pth itself if it can not be resolved.
+func absPath(pth string) string {
+	if abs, err := pathutil.AbsPath(pth); err == nil {
+		return abs
+	}
+
+	return pth
+}
+
+func logDeployPlan(plan []uploaders.PlannedUpload, logger loggerV2.Logger) {
+	logger.Println()
+	logger.Infof("Deploy plan (%d uploads):", len(plan))
+	for _, upload := range plan {
+		logger.Printf("- %s", upload)
+	}
 }
 
-func deploySingleItem(logger loggerV2.Logger, uploader *uploaders.Uploader, item deployment.DeployableItem, config Config, androidArtifacts []string) ([]uploaders.ArtifactURLs, error) {
+func logDeadlineSummary(deployedItems, notDeployedItems []string, logger loggerV2.Logger) {
+	logger.Println()
+	logger.Warnf("The deploy_timeout has been reached, in-flight uploads were cancelled.")
+
+	slices.Sort(deployedItems)
+	logger.Printf("Deployed files (%d):", len(deployedItems))
+	for _, pth := range deployedItems {
+		logger.Printf("- %s", pth)
+	}
+
+	slices.Sort(notDeployedItems)
+	logger.Printf("Not deployed files (%d):", len(notDeployedItems))
+	for _, pth := range notDeployedItems {
+		logger.Printf("- %s", pth)
+	}
+}
+
+func deploySingleItem(ctx context.Context, logger loggerV2.Logger, uploader *uploaders.Uploader, item deployment.DeployableItem, config Config, settings deployrules.Settings, androidArtifacts []string) ([]uploaders.ArtifactURLs, error) {
 	pth := item.Path
 	fileType := getFileType(pth)
 
@@ -578,28 +1153,44 @@ func deploySingleItem(logger loggerV2.Logger, uploader *uploaders.Uploader, item
 	case ".apk":
 		logger.Printf("Deploying apk file: %s", pth)
 
-		return uploader.DeployAPK(item, androidArtifacts, config.BuildURL, config.APIToken, config.NotifyUserGroups, config.AlwaysNotifyUserGroups, config.NotifyEmailList, config.IsPublicPageEnabled)
+		return uploader.DeployAPK(ctx, item, androidArtifacts, config.BuildURL, config.APIToken, settings.NotifyUserGroups, settings.AlwaysNotifyUserGroups, settings.NotifyEmails, settings.IsEnablePublicPage)
 	case ".aab":
 		logger.Printf("Deploying aab file: %s", pth)
 
-		return uploader.DeployAAB(item, androidArtifacts, config.BuildURL, config.APIToken)
+		return uploader.DeployAAB(ctx, item, androidArtifacts, config.BuildURL, config.APIToken)
+	case androidarchive.APKSExt, androidarchive.XAPKExt:
+		logger.Printf("Deploying %s file: %s", strings.TrimPrefix(fileType, "."), pth)
+
+		return uploader.DeployAndroidArchive(ctx, item, config.BuildURL, config.APIToken)
 	case ".ipa":
 		logger.Printf("Deploying ipa file: %s", pth)
 
-		return uploader.DeployIPA(item, config.BuildURL, config.APIToken, config.NotifyUserGroups, config.AlwaysNotifyUserGroups, config.NotifyEmailList, config.IsPublicPageEnabled)
+		return uploader.DeployIPA(ctx, item, config.BuildURL, config.APIToken, settings.NotifyUserGroups, settings.AlwaysNotifyUserGroups, settings.NotifyEmails, settings.IsEnablePublicPage)
 	case zippedXcarchiveExt:
 		logger.Printf("Deploying xcarchive file: %s", pth)
 
-		URLs, err := uploader.DeployXcarchive(item, config.BuildURL, config.APIToken)
+		URLs, err := uploader.DeployXcarchive(ctx, item, config.BuildURL, config.APIToken)
 		if errors.Is(err, iosparser.MacOSProjectIsNotSupported) {
-			logger.Printf("Deploying macOS xcarchive without metdata, as not yet supported.")
+			logger.Printf("Deploying macOS xcarchive")
 
-			return uploader.DeployFile(item, config.BuildURL, config.APIToken)
+			return uploader.DeployMacOSXcarchive(ctx, item, config.BuildURL, config.APIToken)
 		}
 
 		return URLs, err
+	case zippedAppExt:
+		logger.Printf("Deploying macOS app file: %s", pth)
+
+		return uploader.DeployMacOSApp(ctx, item, config.BuildURL, config.APIToken, settings.NotifyUserGroups, settings.AlwaysNotifyUserGroups, settings.NotifyEmails, settings.IsEnablePublicPage)
+	case ".pkg":
+		logger.Printf("Deploying pkg file: %s", pth)
+
+		return uploader.DeployPKG(ctx, item, config.BuildURL, config.APIToken, settings.NotifyUserGroups, settings.AlwaysNotifyUserGroups, settings.NotifyEmails, settings.IsEnablePublicPage)
+	case ".dmg":
+		logger.Printf("Deploying dmg file: %s", pth)
+
+		return uploader.DeployDMG(ctx, item, config.BuildURL, config.APIToken, settings.NotifyUserGroups, settings.AlwaysNotifyUserGroups, settings.NotifyEmails, settings.IsEnablePublicPage)
 	default:
-		return uploader.DeployFile(item, config.BuildURL, config.APIToken)
+		return uploader.DeployFile(ctx, item, config.BuildURL, config.APIToken)
 	}
 }
 
@@ -610,6 +1201,108 @@ func handleDeploymentFailureError(err error, errorCollection []error, logger log
 	return errorCollection
 }
 
+// phaseError is the deployment error of an item, with the deployment phases the item belongs to.
+type phaseError struct {
+	phases []failure.Phase
+	err    error
+}
+
+func (e *phaseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// withItemPhases attributes the item's error to the phases of the item: deploy_path items are artifacts,
+// pipeline_intermediate_files items are intermediate files, an item can be both.
+func withItemPhases(item deployment.DeployableItem, err error) error {
+	var phases []failure.Phase
+	if item.ArchiveAsArtifact {
+		phases = append(phases, failure.Artifacts)
+	}
+	if item.IsIntermediateFile() {
+		phases = append(phases, failure.IntermediateFiles)
+	}
+	if len(phases) == 0 {
+		return err
+	}
+
+	return &phaseError{phases: phases, err: err}
+}
+
+// phaseFailures are the deployment errors by phase.
+type phaseFailures map[failure.Phase][]error
+
+// add adds a deployment error to the phases of its item, errors without an item (like the universal APK generation)
+// belong to the artifacts phase.
+func (f phaseFailures) add(err error) {
+	phases := []failure.Phase{failure.Artifacts}
+	var phaseErr *phaseError
+	if errors.As(err, &phaseErr) {
+		phases = phaseErr.phases
+	}
+
+	for _, phase := range phases {
+		f.addToPhase(phase, err)
+	}
+}
+
+func (f phaseFailures) addToPhase(phase failure.Phase, errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			f[phase] = append(f[phase], err)
+		}
+	}
+}
+
+func failurePolicies(config Config) map[failure.Phase]failure.Policy {
+	return map[failure.Phase]failure.Policy{
+		failure.Artifacts:         config.ArtifactsFailurePolicy,
+		failure.IntermediateFiles: config.IntermediateFilesFailurePolicy,
+		failure.TestResults:       config.TestResultsFailurePolicy,
+		failure.HTMLReports:       config.HTMLReportsFailurePolicy,
+	}
+}
+
+// failureReason returns the reason of the first failure in the order of the deployment phases.
+func failureReason(failures phaseFailures) failure.Reason {
+	for _, phase := range failure.Phases {
+		if errs := failures[phase]; len(errs) > 0 {
+			return failure.Classify(errs[0])
+		}
+	}
+	return ""
+}
+
+// handleFailures warns about the failures of the phases with the warn policy
+// and returns an error with the failures of the phases with the fail policy.
+func handleFailures(failures phaseFailures, policies map[failure.Phase]failure.Policy, logger loggerV2.Logger) error {
+	var errMessage string
+	for _, phase := range failure.Phases {
+		errs := failures[phase]
+		if len(errs) == 0 {
+			continue
+		}
+
+		if policies[phase] == failure.Warn {
+			logger.Println()
+			logger.Warnf("%d %s failure(s) are ignored by the %s_failure_policy input", len(errs), phase, phase)
+			continue
+		}
+
+		for _, err := range errs {
+			errMessage += errorutil.FormattedError(err)
+		}
+	}
+	if errMessage == "" {
+		return nil
+	}
+
+	return errors.New(errMessage)
+}
+
 func fillURLMaps(lock *sync.RWMutex, artifactURLCollection ArtifactURLCollection, artifactURLs []uploaders.ArtifactURLs, path string, tryPublic bool) {
 	lock.Lock()
 	defer lock.Unlock()
@@ -624,6 +1317,66 @@ func fillURLMaps(lock *sync.RWMutex, artifactURLCollection ArtifactURLCollection
 		if urls.DetailsPageURL != "" {
 			artifactURLCollection.DetailsPageURLs[filepath.Base(path)] = urls.DetailsPageURL
 		}
+		if urls.Checksums.SHA256 != "" {
+			artifactURLCollection.SHA256Checksums[filepath.Base(path)] = urls.Checksums.SHA256
+		}
+	}
+}
+
+// notifiableArtifactTypes are the deploy types which support notifications and public install pages.
+var notifiableArtifactTypes = []string{"apk", "ipa", "app", "pkg", "dmg"}
+
+// defaultDeploySettings returns the notification and public install page settings of the Step's inputs.
+func defaultDeploySettings(config Config) deployrules.Settings {
+	return deployrules.Settings{
+		NotifyUserGroups:       config.NotifyUserGroups,
+		AlwaysNotifyUserGroups: config.AlwaysNotifyUserGroups,
+		NotifyEmails:           config.NotifyEmailList,
+		IsEnablePublicPage:     config.IsPublicPageEnabled,
+	}
+}
+
+// validateDeployRules validates the user groups of the deploy rules.
+func validateDeployRules(rules []deployrules.Rule, logger loggerV2.Logger) error {
+	for i, rule := range rules {
+		if rule.NotifyUserGroups != nil {
+			if err := validateUserGroups(*rule.NotifyUserGroups, logger); err != nil {
+				return fmt.Errorf("rule #%d: notify_user_groups - %w", i+1, err)
+			}
+		}
+		if rule.AlwaysNotifyUserGroups != nil {
+			if err := validateUserGroups(*rule.AlwaysNotifyUserGroups, logger); err != nil {
+				return fmt.Errorf("rule #%d: always_notify_user_groups - %w", i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deployType returns the kind of deployment used for the file.
+func deployType(pth string) string {
+	switch getFileType(pth) {
+	case ".apk":
+		return "apk"
+	case ".aab":
+		return "aab"
+	case androidarchive.APKSExt:
+		return "apks"
+	case androidarchive.XAPKExt:
+		return "xapk"
+	case ".ipa":
+		return "ipa"
+	case zippedXcarchiveExt:
+		return "xcarchive"
+	case zippedAppExt:
+		return "app"
+	case ".pkg":
+		return "pkg"
+	case ".dmg":
+		return "dmg"
+	default:
+		return "file"
 	}
 }
 
@@ -631,6 +1384,9 @@ func getFileType(pth string) string {
 	if strings.HasSuffix(pth, zippedXcarchiveExt) {
 		return zippedXcarchiveExt
 	}
+	if strings.HasSuffix(pth, zippedAppExt) {
+		return zippedAppExt
+	}
 	return filepath.Ext(pth)
 }
 
@@ -641,6 +1397,16 @@ func validateGoTemplate(publicInstallPageMapFormat string) error {
 	return err
 }
 
+// newUploadScheduler returns the scheduler shared by the artifact, test attachment and html report uploads:
+// an adaptive one if the upload_concurrency input is `adaptive`, otherwise one with the fixed concurrency of the input.
+func newUploadScheduler(config Config) *scheduler.Scheduler {
+	if strings.EqualFold(strings.TrimSpace(config.UploadConcurrency), adaptiveUploadConcurrency) {
+		return scheduler.NewAdaptive(scheduler.DefaultAdaptiveConcurrency, scheduler.MaxConcurrency)
+	}
+
+	return scheduler.NewFixed(determineConcurrency(config))
+}
+
 func determineConcurrency(config Config) int {
 	if config.UploadConcurrency == "" {
 		return 1
@@ -655,8 +1421,8 @@ func determineConcurrency(config Config) int {
 		return 1
 	}
 
-	if value > 20 {
-		return 20
+	if value > scheduler.MaxConcurrency {
+		return scheduler.MaxConcurrency
 	}
 
 	return value
@@ -1,13 +1,22 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
+	"os"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
 
 	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployrules"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/mocks"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -142,6 +151,13 @@ func TestUploadConcurrency(t *testing.T) {
 	}
 }
 
+func Test_newUploadScheduler(t *testing.T) {
+	assert.Equal(t, 1, newUploadScheduler(Config{}).Limit())
+	assert.Equal(t, 3, newUploadScheduler(Config{UploadConcurrency: "3"}).Limit())
+	assert.Equal(t, scheduler.DefaultAdaptiveConcurrency, newUploadScheduler(Config{UploadConcurrency: "adaptive"}).Limit())
+	assert.Equal(t, scheduler.DefaultAdaptiveConcurrency, newUploadScheduler(Config{UploadConcurrency: " Adaptive "}).Limit())
+}
+
 func Test_validateUserGroups(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -193,3 +209,120 @@ func Test_validateUserGroups(t *testing.T) {
 		})
 	}
 }
+
+func Test_fileMapValue(t *testing.T) {
+	checksums := map[string]string{
+		"ios_app.ipa":     "ipa-checksum",
+		"android_app.apk": "apk-checksum",
+	}
+
+	require.Equal(t, "android_app.apk=>apk-checksum|ios_app.ipa=>ipa-checksum", fileMapValue(checksums))
+}
+
+func Test_parseDebugSymbolsMap(t *testing.T) {
+	got, err := parseDebugSymbolsMap("/deploy/mapping.txt => /deploy/app-release.aab\n\n/deploy/MyApp.app.dSYM.zip=>/deploy/MyApp.ipa\n")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"/deploy/mapping.txt":        "/deploy/app-release.aab",
+		"/deploy/MyApp.app.dSYM.zip": "/deploy/MyApp.ipa",
+	}, got)
+
+	_, err = parseDebugSymbolsMap("/deploy/mapping.txt")
+	require.Error(t, err)
+}
+
+func Test_findDebugSymbols(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) string {
+		pth := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(pth, []byte(content), 0600))
+		return pth
+	}
+	aabPth := writeFile("app-release.aab", "aab")
+	apkPth := writeFile("app-debug.apk", "apk")
+	mappingPth := writeFile("app-release-mapping.txt", "io.bitrise.App -> a:\n")
+	emptyMappingPth := writeFile("app-debug-mapping.txt", "")
+	explicitPth := writeFile("r8.txt", "")
+	urlMappingPth := writeFile("url-mapping.txt", "/home => index.html\n")
+
+	items := deployment.ConvertPaths([]string{aabPth, apkPth, mappingPth, emptyMappingPth, explicitPth, urlMappingPth})
+	symbols, others := findDebugSymbols(items, map[string]string{explicitPth: aabPth}, log.NewLogger())
+
+	require.Equal(t, deployment.ConvertPaths([]string{aabPth, apkPth, emptyMappingPth, urlMappingPth}), others)
+	require.Len(t, symbols, 2)
+	require.Equal(t, debugSymbolsFile{
+		item:        items[2],
+		symbolsType: debugsymbols.ProguardMapping,
+		metadata:    debugsymbols.Metadata{SymbolsType: debugsymbols.ProguardMapping},
+		parentPath:  aabPth,
+	}, symbols[0])
+	require.Equal(t, items[4], symbols[1].item)
+	require.ErrorContains(t, symbols[1].err, "mapping file is empty")
+}
+
+func Test_validateDeployRules(t *testing.T) {
+	testers, invalid := "testers", "qa-team"
+	require.NoError(t, validateDeployRules([]deployrules.Rule{{Pattern: "*-qa.apk", NotifyUserGroups: &testers}}, log.NewLogger()))
+
+	err := validateDeployRules([]deployrules.Rule{
+		{Pattern: "*-qa.apk", NotifyUserGroups: &testers},
+		{ArtifactType: "ipa", AlwaysNotifyUserGroups: &invalid},
+	}, log.NewLogger())
+	require.EqualError(t, err, "rule #2: always_notify_user_groups - invalid user group: qa-team")
+}
+
+func Test_phaseFailures(t *testing.T) {
+	artifact := deployment.DeployableItem{Path: "app.ipa", ArchiveAsArtifact: true}
+	intermediate := deployment.DeployableItem{Path: "app.xcarchive.zip", IntermediateFileMeta: &deployment.IntermediateFileMetaData{EnvKey: "XCARCHIVE"}}
+
+	parseErr := failure.New(failure.Parse, errors.New("invalid ipa"))
+	authErr := failure.New(failure.Auth, errors.New("unauthorized"))
+	failures := phaseFailures{}
+	failures.add(fmt.Errorf("deploy failed, error: %w", withItemPhases(intermediate, authErr)))
+	failures.add(fmt.Errorf("deploy failed, error: %w", withItemPhases(artifact, parseErr)))
+	failures.add(errors.New("failed to generate universal APK"))
+	failures.addToPhase(failure.TestResults, nil)
+
+	require.Len(t, failures[failure.Artifacts], 2)
+	require.Len(t, failures[failure.IntermediateFiles], 1)
+	require.Empty(t, failures[failure.TestResults])
+	require.Equal(t, failure.Parse, failureReason(failures))
+	require.Equal(t, failure.Reason(""), failureReason(phaseFailures{}))
+
+	policies := map[failure.Phase]failure.Policy{
+		failure.Artifacts:         failure.Warn,
+		failure.IntermediateFiles: failure.Fail,
+		failure.TestResults:       failure.Fail,
+		failure.HTMLReports:       failure.Warn,
+	}
+	err := handleFailures(failures, policies, log.NewLogger())
+	require.ErrorContains(t, err, "unauthorized")
+	require.NotContains(t, err.Error(), "invalid ipa")
+
+	policies[failure.IntermediateFiles] = failure.Warn
+	require.NoError(t, handleFailures(failures, policies, log.NewLogger()))
+}
+
+func Test_parseBandwidthLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "0", want: 0},
+		{value: " 2.5 ", want: 2500000},
+		{value: "10", want: 10000000},
+		{value: "-1", wantErr: true},
+		{value: "fast", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseBandwidthLimit(tt.value)
+		if tt.wantErr {
+			require.Error(t, err, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		require.Equal(t, tt.want, got, tt.value)
+	}
+}
@@ -0,0 +1,144 @@
+// Package protowire decodes Protocol Buffers messages without their schema,
+// it supports the field types used by the bundletool and aapt2 protobuf files.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types, based on: https://protobuf.dev/programming-guides/encoding/
+const (
+	VarintType  = 0
+	Fixed64Type = 1
+	BytesType   = 2
+	Fixed32Type = 5
+)
+
+// ErrTruncated is returned when the message ends in the middle of a field.
+var ErrTruncated = errors.New("truncated protobuf message")
+
+type value struct {
+	varint uint64
+	bytes  []byte
+}
+
+// Message holds the fields of a decoded protobuf message by their field numbers.
+type Message map[uint64][]value
+
+// Decode decodes the fields of a protobuf message, embedded messages are decoded on demand.
+func Decode(content []byte) (Message, error) {
+	message := Message{}
+	for len(content) > 0 {
+		key, n := binary.Uvarint(content)
+		if n <= 0 {
+			return nil, ErrTruncated
+		}
+		content = content[n:]
+
+		fieldNumber, wireType := key>>3, key&7
+		var fieldValue value
+		switch wireType {
+		case VarintType:
+			fieldValue.varint, n = binary.Uvarint(content)
+			if n <= 0 {
+				return nil, ErrTruncated
+			}
+			content = content[n:]
+		case BytesType:
+			length, n := binary.Uvarint(content)
+			if n <= 0 || uint64(len(content)-n) < length {
+				return nil, ErrTruncated
+			}
+			fieldValue.bytes = content[n : n+int(length)]
+			content = content[n+int(length):]
+		case Fixed64Type:
+			if len(content) < 8 {
+				return nil, ErrTruncated
+			}
+			fieldValue.varint = binary.LittleEndian.Uint64(content)
+			content = content[8:]
+		case Fixed32Type:
+			if len(content) < 4 {
+				return nil, ErrTruncated
+			}
+			fieldValue.varint = uint64(binary.LittleEndian.Uint32(content))
+			content = content[4:]
+		default:
+			return nil, fmt.Errorf("unsupported protobuf wire type: %d", wireType)
+		}
+
+		message[fieldNumber] = append(message[fieldNumber], fieldValue)
+	}
+
+	return message, nil
+}
+
+// Has reports whether the field is present in the message.
+func (m Message) Has(field uint64) bool {
+	return len(m[field]) > 0
+}
+
+// Message returns the last occurrence of an embedded message field, as protobuf does for non-repeated fields.
+func (m Message) Message(field uint64) []byte {
+	values := m[field]
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values[len(values)-1].bytes
+}
+
+// Messages returns every occurrence of a repeated embedded message field.
+func (m Message) Messages(field uint64) [][]byte {
+	var messages [][]byte
+	for _, fieldValue := range m[field] {
+		messages = append(messages, fieldValue.bytes)
+	}
+
+	return messages
+}
+
+// String returns the value of a string field.
+func (m Message) String(field uint64) string {
+	return string(m.Message(field))
+}
+
+// Strings returns the values of a repeated string field.
+func (m Message) Strings(field uint64) []string {
+	var values []string
+	for _, fieldValue := range m[field] {
+		values = append(values, string(fieldValue.bytes))
+	}
+
+	return values
+}
+
+// Varint returns the value of a varint or fixed size integer field.
+func (m Message) Varint(field uint64) uint64 {
+	values := m[field]
+	if len(values) == 0 {
+		return 0
+	}
+
+	return values[len(values)-1].varint
+}
+
+// Bool returns the value of a bool field.
+func (m Message) Bool(field uint64) bool {
+	return m.Varint(field) != 0
+}
+
+// AppendBytes encodes a length-delimited field (a string, bytes or an embedded message).
+func AppendBytes(b []byte, field uint64, content []byte) []byte {
+	b = binary.AppendUvarint(b, field<<3|BytesType)
+	b = binary.AppendUvarint(b, uint64(len(content)))
+	return append(b, content...)
+}
+
+// AppendVarint encodes a varint field.
+func AppendVarint(b []byte, field, v uint64) []byte {
+	b = binary.AppendUvarint(b, field<<3|VarintType)
+	return binary.AppendUvarint(b, v)
+}
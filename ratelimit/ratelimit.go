@@ -0,0 +1,120 @@
+// Package ratelimit limits the bandwidth of the uploads with token buckets,
+// a bucket can be shared by any number of concurrent uploads.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxChunkSize is the largest amount of data read at once, so that concurrent readers of a limiter take turns.
+const maxChunkSize = 32 * 1024
+
+// Limiter is a token bucket of bytes, which is refilled at a constant rate.
+type Limiter struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewLimiter returns a limiter of the given bytes per second, or nil (no limit) if the rate is not positive.
+// The bucket holds at most one second worth of tokens, so an idle period doesn't allow a long burst.
+func NewLimiter(bytesPerSecond int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+
+	return newLimiter(float64(bytesPerSecond), time.Now, sleep)
+}
+
+func newLimiter(rate float64, now func() time.Time, sleep func(ctx context.Context, d time.Duration) error) *Limiter {
+	return &Limiter{
+		rate:   rate,
+		burst:  rate,
+		tokens: rate,
+		last:   now(),
+		now:    now,
+		sleep:  sleep,
+	}
+}
+
+// WaitN waits until n bytes can be sent. The bytes are reserved immediately, so the waiting callers are served in order
+// and the aggregate throughput of all callers stays at the rate of the limiter.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	if l == nil || n <= 0 {
+		return nil
+	}
+
+	l.lock.Lock()
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.lock.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	return l.sleep(ctx, wait)
+}
+
+// Reader limits the rate of the reads from the underlying reader by all of its limiters.
+type Reader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*Limiter
+}
+
+// NewReader returns a reader limited by the given limiters, nil limiters are ignored.
+func NewReader(ctx context.Context, reader io.Reader, limiters ...*Limiter) *Reader {
+	var active []*Limiter
+	for _, limiter := range limiters {
+		if limiter != nil {
+			active = append(active, limiter)
+		}
+	}
+
+	return &Reader{ctx: ctx, reader: reader, limiters: active}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	if len(r.limiters) == 0 {
+		return r.reader.Read(p)
+	}
+
+	if len(p) > maxChunkSize {
+		p = p[:maxChunkSize]
+	}
+
+	n, err := r.reader.Read(p)
+	for _, limiter := range r.limiters {
+		if waitErr := limiter.WaitN(r.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a virtual clock, which is advanced by the sleeps of the limiters.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) elapsed(start time.Time) time.Duration {
+	return c.Now().Sub(start)
+}
+
+func (c *fakeClock) limiter(bytesPerSecond float64) *Limiter {
+	return newLimiter(bytesPerSecond, c.Now, c.Sleep)
+}
+
+func TestReader_rate(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+
+	content := bytes.Repeat([]byte("a"), 5000)
+	read, err := io.ReadAll(NewReader(context.Background(), bytes.NewReader(content), clock.limiter(1000)))
+	require.NoError(t, err)
+	require.Equal(t, content, read)
+	// The first second worth of bytes is the burst of the bucket.
+	require.Equal(t, 4*time.Second, clock.elapsed(start))
+}
+
+func TestReader_sharedLimiter(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+	shared := clock.limiter(1000)
+
+	readers := []io.Reader{
+		NewReader(context.Background(), bytes.NewReader(make([]byte, 3000)), shared),
+		NewReader(context.Background(), bytes.NewReader(make([]byte, 3000)), shared),
+	}
+	// The readers take turns, like concurrent uploads do.
+	buffer := make([]byte, 500)
+	for done := 0; done < len(readers); {
+		done = 0
+		for _, reader := range readers {
+			if _, err := reader.Read(buffer); err == io.EOF {
+				done++
+			} else {
+				require.NoError(t, err)
+			}
+		}
+	}
+
+	require.Equal(t, 5*time.Second, clock.elapsed(start))
+}
+
+func TestReader_perConnectionLimit(t *testing.T) {
+	clock := newFakeClock()
+	start := clock.Now()
+
+	shared := clock.limiter(10000)
+	_, err := io.ReadAll(NewReader(context.Background(), bytes.NewReader(make([]byte, 3000)), shared, clock.limiter(1000)))
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, clock.elapsed(start))
+}
+
+func TestReader_noLimit(t *testing.T) {
+	require.Nil(t, NewLimiter(0))
+
+	content := bytes.Repeat([]byte("a"), 100*1024)
+	read, err := io.ReadAll(NewReader(context.Background(), bytes.NewReader(content), nil, NewLimiter(-1)))
+	require.NoError(t, err)
+	require.Equal(t, content, read)
+}
+
+func TestReader_cancelled(t *testing.T) {
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := io.ReadAll(NewReader(ctx, bytes.NewReader(make([]byte, 3000)), clock.limiter(1000)))
+	require.ErrorIs(t, err, context.Canceled)
+}
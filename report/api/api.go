@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,14 +12,15 @@ import (
 
 	"github.com/bitrise-io/go-utils/retry"
 	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders"
 )
 
 // ClientAPI ...
 type ClientAPI interface {
-	CreateReport(params CreateReportParameters) (CreateReportResponse, error)
-	UploadAsset(url, path, contentType string) error
-	FinishReport(identifier string, allAssetsUploaded bool) error
+	CreateReport(ctx context.Context, params CreateReportParameters) (CreateReportResponse, error)
+	UploadAsset(ctx context.Context, url, path, contentType string) error
+	FinishReport(ctx context.Context, identifier string, allAssetsUploaded bool) error
 }
 
 // HTTPClient ...
@@ -28,26 +30,29 @@ type HTTPClient interface {
 
 // TestReportClient ...
 type TestReportClient struct {
-	logger     log.Logger
-	httpClient HTTPClient
-	buildURL   string
-	authToken  string
+	logger         log.Logger
+	httpClient     HTTPClient
+	artifactClient uploaders.ArtifactClient
+	buildURL       string
+	authToken      string
 }
 
-// NewBitriseClient ...
-func NewBitriseClient(buildURL, authToken string, logger log.Logger) *TestReportClient {
-	httpClient := retry.NewHTTPClient().StandardClient()
+// NewBitriseClient returns a client sending the html report API and asset upload requests with the shared httpClient.
+func NewBitriseClient(buildURL, authToken string, httpClient *http.Client, retryPolicy retrypolicy.Policy, logger log.Logger) *TestReportClient {
+	retryClient := retryPolicy.ConfigureClient(retry.NewHTTPClient())
+	retryClient.HTTPClient = httpClient
 
 	return &TestReportClient{
-		logger:     logger,
-		httpClient: httpClient,
-		buildURL:   buildURL,
-		authToken:  authToken,
+		logger:         logger,
+		httpClient:     retryClient.StandardClient(),
+		artifactClient: uploaders.NewArtifactClient(httpClient, retryPolicy),
+		buildURL:       buildURL,
+		authToken:      authToken,
 	}
 }
 
 // CreateReport ...
-func (t *TestReportClient) CreateReport(params CreateReportParameters) (CreateReportResponse, error) {
+func (t *TestReportClient) CreateReport(ctx context.Context, params CreateReportParameters) (CreateReportResponse, error) {
 	url := fmt.Sprintf("%s/html_reports.json", t.buildURL)
 
 	body, err := json.Marshal(params)
@@ -55,7 +60,7 @@ func (t *TestReportClient) CreateReport(params CreateReportParameters) (CreateRe
 		return CreateReportResponse{}, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(body))
 	if err != nil {
 		return CreateReportResponse{}, err
 	}
@@ -79,7 +84,7 @@ func (t *TestReportClient) CreateReport(params CreateReportParameters) (CreateRe
 }
 
 // UploadAsset ...
-func (t *TestReportClient) UploadAsset(url, path, contentType string) error {
+func (t *TestReportClient) UploadAsset(ctx context.Context, url, path, contentType string) error {
 	fileInfo, err := os.Stat(path)
 	if err != nil {
 		return err
@@ -88,12 +93,12 @@ func (t *TestReportClient) UploadAsset(url, path, contentType string) error {
 		Path:     path,
 		FileSize: fileInfo.Size(),
 	}
-	_, err = uploaders.UploadArtifact(url, artifact, contentType)
+	_, err = t.artifactClient.UploadArtifact(ctx, url, artifact, contentType)
 	return err
 }
 
 // FinishReport ...
-func (t *TestReportClient) FinishReport(identifier string, allAssetsUploaded bool) error {
+func (t *TestReportClient) FinishReport(ctx context.Context, identifier string, allAssetsUploaded bool) error {
 	url := fmt.Sprintf("%s/html_reports/%s.json", t.buildURL, identifier)
 
 	type parameters struct {
@@ -106,7 +111,7 @@ func (t *TestReportClient) FinishReport(identifier string, allAssetsUploaded boo
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
@@ -154,7 +159,7 @@ func (t *TestReportClient) perform(request *http.Request) (*http.Response, error
 			t.logger.Warnf("Failed to parse error message from the response: %s", err)
 		}
 
-		return nil, fmt.Errorf("request to %s failed: status code should be 2xx (%d): %s", resp.Request.URL, resp.StatusCode, message)
+		return nil, retrypolicy.NewHTTPError(resp, fmt.Errorf("request to %s failed: status code should be 2xx (%d): %s", resp.Request.URL, resp.StatusCode, message))
 	}
 
 	return resp, nil
@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/require"
@@ -88,12 +89,12 @@ func TestCreateReport(t *testing.T) {
 			var request http.Request
 			setupMockNetworking(t, mockHTTPClient, &request, tt.responseBody, tt.responseStatusCode)
 
-			response, err := apiClient.CreateReport(tt.params)
+			response, err := apiClient.CreateReport(context.Background(), tt.params)
 			assert.Equal(t, fmt.Sprintf("%s/html_reports.json", buildURL), request.URL.String())
 			assert.Equal(t, []string{authToken}, request.Header["BUILD_API_TOKEN"]) //nolint:staticcheck // See TestReportClient.perform()
 
 			if tt.wantError {
-				assert.Equal(t, tt.expectedError, err)
+				assert.EqualError(t, err, tt.expectedError.Error())
 			} else {
 				var received CreateReportParameters
 				err = json.NewDecoder(request.Body).Decode(&received)
@@ -144,12 +145,12 @@ func TestFinishReport(t *testing.T) {
 			var request http.Request
 			setupMockNetworking(t, mockHTTPClient, &request, tt.responseBody, tt.responseStatusCode)
 
-			err := apiClient.FinishReport(tt.identifier, tt.allAssetsUploaded)
+			err := apiClient.FinishReport(context.Background(), tt.identifier, tt.allAssetsUploaded)
 			assert.Equal(t, fmt.Sprintf("%s/html_reports/%s.json", buildURL, tt.identifier), request.URL.String())
 			assert.Equal(t, []string{authToken}, request.Header["BUILD_API_TOKEN"]) //nolint:staticcheck // See TestReportClient.perform()
 
 			if tt.wantError {
-				assert.Equal(t, tt.expectedError, err)
+				assert.EqualError(t, err, tt.expectedError.Error())
 			} else {
 				assert.NoError(t, err)
 			}
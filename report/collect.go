@@ -10,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 )
 
 const (
@@ -73,7 +75,7 @@ func collectReports(dir string) ([]Report, error) {
 		var reportInfo Info
 		if infoFileData, err := os.ReadFile(filepath.Join(testDir, htmlReportInfoFile)); err == nil {
 			if err := json.Unmarshal(infoFileData, &reportInfo); err != nil {
-				return nil, fmt.Errorf("cannot parse report info file: %w", err)
+				return nil, failure.New(failure.Parse, fmt.Errorf("cannot parse report info file: %w", err))
 			}
 		}
 
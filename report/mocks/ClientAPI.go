@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	context "context"
+
 	api "github.com/bitrise-steplib/steps-deploy-to-bitrise-io/report/api"
 	mock "github.com/stretchr/testify/mock"
 )
@@ -12,23 +14,23 @@ type ClientAPI struct {
 	mock.Mock
 }
 
-// CreateReport provides a mock function with given fields: params
-func (_m *ClientAPI) CreateReport(params api.CreateReportParameters) (api.CreateReportResponse, error) {
-	ret := _m.Called(params)
+// CreateReport provides a mock function with given fields: ctx, params
+func (_m *ClientAPI) CreateReport(ctx context.Context, params api.CreateReportParameters) (api.CreateReportResponse, error) {
+	ret := _m.Called(ctx, params)
 
 	var r0 api.CreateReportResponse
 	var r1 error
-	if rf, ok := ret.Get(0).(func(api.CreateReportParameters) (api.CreateReportResponse, error)); ok {
-		return rf(params)
+	if rf, ok := ret.Get(0).(func(context.Context, api.CreateReportParameters) (api.CreateReportResponse, error)); ok {
+		return rf(ctx, params)
 	}
-	if rf, ok := ret.Get(0).(func(api.CreateReportParameters) api.CreateReportResponse); ok {
-		r0 = rf(params)
+	if rf, ok := ret.Get(0).(func(context.Context, api.CreateReportParameters) api.CreateReportResponse); ok {
+		r0 = rf(ctx, params)
 	} else {
 		r0 = ret.Get(0).(api.CreateReportResponse)
 	}
 
-	if rf, ok := ret.Get(1).(func(api.CreateReportParameters) error); ok {
-		r1 = rf(params)
+	if rf, ok := ret.Get(1).(func(context.Context, api.CreateReportParameters) error); ok {
+		r1 = rf(ctx, params)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -36,13 +38,13 @@ func (_m *ClientAPI) CreateReport(params api.CreateReportParameters) (api.Create
 	return r0, r1
 }
 
-// FinishReport provides a mock function with given fields: identifier, allAssetsUploaded
-func (_m *ClientAPI) FinishReport(identifier string, allAssetsUploaded bool) error {
-	ret := _m.Called(identifier, allAssetsUploaded)
+// FinishReport provides a mock function with given fields: ctx, identifier, allAssetsUploaded
+func (_m *ClientAPI) FinishReport(ctx context.Context, identifier string, allAssetsUploaded bool) error {
+	ret := _m.Called(ctx, identifier, allAssetsUploaded)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, bool) error); ok {
-		r0 = rf(identifier, allAssetsUploaded)
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool) error); ok {
+		r0 = rf(ctx, identifier, allAssetsUploaded)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -50,13 +52,13 @@ func (_m *ClientAPI) FinishReport(identifier string, allAssetsUploaded bool) err
 	return r0
 }
 
-// UploadAsset provides a mock function with given fields: url, path, contentType
-func (_m *ClientAPI) UploadAsset(url string, path string, contentType string) error {
-	ret := _m.Called(url, path, contentType)
+// UploadAsset provides a mock function with given fields: ctx, url, path, contentType
+func (_m *ClientAPI) UploadAsset(ctx context.Context, url string, path string, contentType string) error {
+	ret := _m.Called(ctx, url, path, contentType)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
-		r0 = rf(url, path, contentType)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) error); ok {
+		r0 = rf(ctx, url, path, contentType)
 	} else {
 		r0 = ret.Error(0)
 	}
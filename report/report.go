@@ -1,41 +1,80 @@
 package report
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"sync"
 
 	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/report/api"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
 )
 
 // HTMLReportUploader ...
 type HTMLReportUploader struct {
-	client      api.ClientAPI
-	logger      log.Logger
-	reportDir   string
-	concurrency int
+	client    api.ClientAPI
+	logger    log.Logger
+	reportDir string
+	scheduler *scheduler.Scheduler
 }
 
-// NewHTMLReportUploader ...
-func NewHTMLReportUploader(reportDir, buildURL, authToken string, concurrency int, logger log.Logger) HTMLReportUploader {
-	client := api.NewBitriseClient(buildURL, authToken, logger)
+// NewHTMLReportUploader returns an uploader whose asset uploads share the upload slots of the scheduler.
+func NewHTMLReportUploader(reportDir, buildURL, authToken string, uploadScheduler *scheduler.Scheduler, httpClient *http.Client, retryPolicy retrypolicy.Policy, logger log.Logger) HTMLReportUploader {
+	client := api.NewBitriseClient(buildURL, authToken, httpClient, retryPolicy, logger)
 
 	return HTMLReportUploader{
-		client:      client,
-		logger:      logger,
-		reportDir:   reportDir,
-		concurrency: concurrency,
+		client:    client,
+		logger:    logger,
+		reportDir: reportDir,
+		scheduler: uploadScheduler,
 	}
 }
 
 // DeployReports ...
-func (h *HTMLReportUploader) DeployReports() []error {
-	reports, err := collectReports(h.reportDir)
+func (h *HTMLReportUploader) DeployReports(ctx context.Context) []error {
+	validatedReports, err := h.collectValidReports()
 	if err != nil {
 		return []error{err}
 	}
 
+	var uploadErrors []error
+	for _, report := range validatedReports {
+		if err := h.uploadReport(ctx, report); err != nil {
+			uploadErrors = append(uploadErrors, err)
+		}
+	}
+
+	return uploadErrors
+}
+
+// PlanReports collects and validates the reports like DeployReports does, but only logs the reports which would be uploaded.
+func (h *HTMLReportUploader) PlanReports() []error {
+	validatedReports, err := h.collectValidReports()
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, report := range validatedReports {
+		var size int64
+		for _, asset := range report.Assets {
+			size += asset.FileSize
+		}
+		h.logger.Printf("Would upload %s (%d assets, %d bytes)", report.Name, len(report.Assets), size)
+	}
+
+	return nil
+}
+
+func (h *HTMLReportUploader) collectValidReports() ([]Report, error) {
+	reports, err := collectReports(h.reportDir)
+	if err != nil {
+		return nil, err
+	}
+
 	h.logger.Printf("Found reports (%d):", len(reports))
 	for _, report := range reports {
 		h.logger.Printf("- %s", report.Name)
@@ -50,14 +89,7 @@ func (h *HTMLReportUploader) DeployReports() []error {
 		}
 	}
 
-	var uploadErrors []error
-	for _, report := range validatedReports {
-		if err := h.uploadReport(report); err != nil {
-			uploadErrors = append(uploadErrors, err)
-		}
-	}
-
-	return uploadErrors
+	return validatedReports, nil
 }
 
 func (h *HTMLReportUploader) validate(reports []Report) ([]Report, []error) {
@@ -79,23 +111,23 @@ func (h *HTMLReportUploader) validate(reports []Report) ([]Report, []error) {
 			continue
 		}
 
-		validationErrors = append(validationErrors, fmt.Errorf("missing index.html file for %s", report.Name))
+		validationErrors = append(validationErrors, failure.New(failure.Parse, fmt.Errorf("missing index.html file for %s", report.Name)))
 	}
 
 	return validatedReports, validationErrors
 }
 
-func (h *HTMLReportUploader) uploadReport(report Report) error {
+func (h *HTMLReportUploader) uploadReport(ctx context.Context, report Report) error {
 	h.logger.Println()
 	h.logger.Printf("Uploading %s", report.Name)
 
-	serverReport, err := h.createReport(report)
+	serverReport, err := h.createReport(ctx, report)
 	if err != nil {
 		return err
 	}
 
 	allAssetsUploaded := true
-	errors := h.uploadAssets(report.Assets, serverReport.AssetURLs)
+	errors := h.uploadAssets(ctx, report.Assets, serverReport.AssetURLs)
 	if 0 < len(errors) {
 		for _, uploadError := range errors {
 			h.logger.Warnf("Asset upload failed:\n")
@@ -107,7 +139,7 @@ func (h *HTMLReportUploader) uploadReport(report Report) error {
 		h.logger.Warnf("Html report will be marked unsuccessful as some assets could not be saved")
 	}
 
-	err = h.finishReport(serverReport.Identifier, allAssetsUploaded)
+	err = h.finishReport(ctx, serverReport.Identifier, allAssetsUploaded)
 	if err != nil {
 		return err
 	}
@@ -115,7 +147,7 @@ func (h *HTMLReportUploader) uploadReport(report Report) error {
 	return nil
 }
 
-func (h *HTMLReportUploader) createReport(report Report) (ServerReport, error) {
+func (h *HTMLReportUploader) createReport(ctx context.Context, report Report) (ServerReport, error) {
 	var assets []api.CreateReportAsset
 	for _, asset := range report.Assets {
 		assets = append(assets, api.CreateReportAsset{
@@ -125,7 +157,7 @@ func (h *HTMLReportUploader) createReport(report Report) (ServerReport, error) {
 		})
 	}
 
-	resp, err := h.client.CreateReport(api.CreateReportParameters{
+	resp, err := h.client.CreateReport(ctx, api.CreateReportParameters{
 		Title:    report.Name,
 		Category: report.Info.Category,
 		Assets:   assets,
@@ -145,34 +177,40 @@ func (h *HTMLReportUploader) createReport(report Report) (ServerReport, error) {
 	}, nil
 }
 
-func (h *HTMLReportUploader) uploadAssets(assets []Asset, urls map[string]string) []error {
+func (h *HTMLReportUploader) uploadAssets(ctx context.Context, assets []Asset, urls map[string]string) []error {
 	var errors []error
+	var errorsLock sync.Mutex
 	var wg sync.WaitGroup
 
-	jobs := make(chan bool, h.concurrency)
+	addError := func(err error) {
+		errorsLock.Lock()
+		defer errorsLock.Unlock()
+		errors = append(errors, err)
+	}
 
 	for _, item := range assets {
 		wg.Add(1)
 
 		go func(asset Asset) {
 			defer wg.Done()
-			defer func() {
-				<-jobs
-			}()
-
-			jobs <- true
-
-			h.logger.Debugf("Uploading %s", asset.TestDirRelativePath)
 
 			url, ok := urls[asset.TestDirRelativePath]
 			if !ok {
-				errors = append(errors, fmt.Errorf("missing upload url for %s", asset.TestDirRelativePath))
+				addError(fmt.Errorf("missing upload url for %s", asset.TestDirRelativePath))
 				return
 			}
 
-			err := h.client.UploadAsset(url, asset.Path, asset.ContentType)
+			if err := h.scheduler.Acquire(ctx); err != nil {
+				addError(fmt.Errorf("%s was not uploaded: %w", asset.TestDirRelativePath, err))
+				return
+			}
+
+			h.logger.Debugf("Uploading %s", asset.TestDirRelativePath)
+
+			err := h.client.UploadAsset(ctx, url, asset.Path, asset.ContentType)
+			h.scheduler.Release(asset.FileSize, err)
 			if err != nil {
-				errors = append(errors, err)
+				addError(err)
 			}
 		}(item)
 	}
@@ -182,6 +220,6 @@ func (h *HTMLReportUploader) uploadAssets(assets []Asset, urls map[string]string
 	return errors
 }
 
-func (h *HTMLReportUploader) finishReport(identifier string, allAssetsUploaded bool) error {
-	return h.client.FinishReport(identifier, allAssetsUploaded)
+func (h *HTMLReportUploader) finishReport(ctx context.Context, identifier string, allAssetsUploaded bool) error {
+	return h.client.FinishReport(ctx, identifier, allAssetsUploaded)
 }
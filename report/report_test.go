@@ -1,6 +1,7 @@
 package report
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -11,9 +12,12 @@ import (
 	"testing"
 
 	loggerV2 "github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/report/api"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/report/mocks"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,25 +38,39 @@ func TestFindsAndUploadsReports(t *testing.T) {
 	setupMockingForReport(mockClient, reports[3])
 
 	uploader := HTMLReportUploader{
-		client:      mockClient,
-		logger:      loggerV2.NewLogger(),
-		reportDir:   reportDir,
-		concurrency: 1,
+		client:    mockClient,
+		logger:    loggerV2.NewLogger(),
+		reportDir: reportDir,
+		scheduler: scheduler.NewFixed(3),
 	}
 
-	uploadErrors := uploader.DeployReports()
+	uploadErrors := uploader.DeployReports(context.Background())
 	require.Equal(t, 0, len(uploadErrors))
 
 	mockClient.AssertExpectations(t)
 }
 
+func TestPlanReportsMakesNoRequests(t *testing.T) {
+	reportDir, _ := createReports(t)
+
+	mockClient := mocks.NewClientAPI(t)
+	uploader := HTMLReportUploader{
+		client:    mockClient,
+		logger:    loggerV2.NewLogger(),
+		reportDir: reportDir,
+		scheduler: scheduler.NewFixed(1),
+	}
+
+	require.Empty(t, uploader.PlanReports())
+}
+
 func TestInvalidReportFiltering(t *testing.T) {
 	reportDir, reports := createReports(t)
 	uploader := HTMLReportUploader{
-		client:      nil,
-		logger:      loggerV2.NewLogger(),
-		reportDir:   reportDir,
-		concurrency: 1,
+		client:    nil,
+		logger:    loggerV2.NewLogger(),
+		reportDir: reportDir,
+		scheduler: scheduler.NewFixed(1),
 	}
 
 	// Create an invalid report
@@ -62,8 +80,8 @@ func TestInvalidReportFiltering(t *testing.T) {
 	validatedReports, validatedErrors := uploader.validate(reports)
 
 	expectedErrors := []error{
-		fmt.Errorf("missing index.html file for Test A"),
-		fmt.Errorf("missing index.html file for Test B"),
+		failure.New(failure.Parse, fmt.Errorf("missing index.html file for Test A")),
+		failure.New(failure.Parse, fmt.Errorf("missing index.html file for Test B")),
 	}
 	assert.Equal(t, expectedErrors, validatedErrors)
 	assert.Equal(t, len(validatedReports), 2)
@@ -225,12 +243,12 @@ func setupMockingForReport(client *mocks.ClientAPI, report Report) {
 		AssetURLs:  responseURLs,
 	}
 
-	client.On("CreateReport", requestParams).Return(response, nil)
+	client.On("CreateReport", mock.Anything, requestParams).Return(response, nil)
 
 	for i, responseURL := range responseURLs {
 		asset := report.Assets[i]
-		client.On("UploadAsset", responseURL.URL, asset.Path, asset.ContentType).Return(nil)
+		client.On("UploadAsset", mock.Anything, responseURL.URL, asset.Path, asset.ContentType).Return(nil)
 	}
 
-	client.On("FinishReport", response.Identifier, true).Return(nil)
+	client.On("FinishReport", mock.Anything, response.Identifier, true).Return(nil)
 }
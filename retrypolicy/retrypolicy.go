@@ -0,0 +1,224 @@
+package retrypolicy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	// DefaultMaxRetries ...
+	DefaultMaxRetries = 3
+	// DefaultBaseDelay ...
+	DefaultBaseDelay = 5 * time.Second
+	// DefaultMaxDelay ...
+	DefaultMaxDelay = time.Minute
+
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Policy describes how failed requests are retried: network errors, 5xx (except 501) and 429 responses are retried
+// with an exponentially growing, jittered delay (or the delay requested by the Retry-After header),
+// other 4xx responses (like 401, 403 and 422) and errors which are not known to be transient fail immediately.
+type Policy struct {
+	MaxRetries uint
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// HTTPError is returned for unsuccessful HTTP responses, it carries the details needed to decide whether the request should be retried.
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+// DefaultPolicy ...
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+	}
+}
+
+// NewHTTPError ...
+func NewHTTPError(resp *http.Response, err error) *HTTPError {
+	retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter,
+		Err:        err,
+	}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable is implemented by errors which know whether the failed action can succeed if it is retried,
+// like a checksum mismatch caused by bytes corrupted on the network.
+type Retryable interface {
+	Retryable() bool
+}
+
+// IsRetryable reports whether the action which returned the given error can succeed if it is retried:
+// retryable HTTP responses and transient network errors. Other errors, like missing files, invalid responses
+// and cancelled contexts, fail the same way on every attempt.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return IsRetryableStatus(httpErr.StatusCode)
+	}
+
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+
+	return isTransient(err)
+}
+
+// isTransient reports whether the error is a network error, like a dropped or refused connection or a (TLS handshake) timeout.
+func isTransient(err error) bool {
+	// File system errors are checked first, as they are wrapped into the network errors of the request bodies.
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// IsRetryableStatus reports whether a request which received the given status code can succeed if it is retried.
+func IsRetryableStatus(statusCode int) bool {
+	switch {
+	case statusCode == http.StatusTooManyRequests, statusCode == http.StatusRequestTimeout:
+		return true
+	case statusCode == http.StatusNotImplemented:
+		return false
+	default:
+		return statusCode >= 500
+	}
+}
+
+// Do calls the action until it succeeds, returns a non-retryable error, runs out of retries or the context is done.
+func (p Policy) Do(ctx context.Context, action func(attempt uint) error) error {
+	for attempt := uint(0); ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := action(attempt)
+		if err == nil {
+			return nil
+		}
+		if attempt >= p.MaxRetries || !IsRetryable(err) || ctx.Err() != nil {
+			return err
+		}
+
+		var retryAfter time.Duration
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			retryAfter = httpErr.RetryAfter
+		}
+
+		timer := time.NewTimer(p.Delay(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Delay returns the wait time before the retry following the given (zero based) attempt.
+// The server requested retryAfter delay takes precedence over the exponential backoff.
+func (p Policy) Delay(attempt uint, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, maxRetryAfter)
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt))
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	// Equal jitter: keeps at least half of the backoff, so retries of concurrent uploads are spread out but never immediate.
+	half := delay / 2
+	return time.Duration(half + rand.Float64()*half)
+}
+
+// ConfigureClient applies the policy to a retryablehttp.Client.
+func (p Policy) ConfigureClient(client *retryablehttp.Client) *retryablehttp.Client {
+	client.RetryMax = int(p.MaxRetries)
+	client.RetryWaitMin = p.BaseDelay
+	client.RetryWaitMax = p.MaxDelay
+	client.CheckRetry = p.checkRetry
+	client.Backoff = p.backoff
+
+	return client
+}
+
+func (p Policy) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if err != nil || ctx.Err() != nil {
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+
+	return IsRetryableStatus(resp.StatusCode), nil
+}
+
+func (p Policy) backoff(_, _ time.Duration, attemptNum int, resp *http.Response) time.Duration {
+	var retryAfter time.Duration
+	if resp != nil {
+		retryAfter, _ = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return p.Delay(uint(attemptNum), retryAfter)
+}
+
+// parseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(time.Until(date), 0), true
+}
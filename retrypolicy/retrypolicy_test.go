@@ -0,0 +1,231 @@
+package retrypolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/require"
+)
+
+var testPolicy = Policy{
+	MaxRetries: 3,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   2 * time.Millisecond,
+}
+
+func TestPolicy_Do(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCodes  []int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "Succeeds on the first attempt", statusCodes: []int{http.StatusOK}, wantAttempts: 1},
+		{name: "Retries server errors", statusCodes: []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK}, wantAttempts: 3},
+		{name: "Retries rate limited requests", statusCodes: []int{http.StatusTooManyRequests, http.StatusOK}, wantAttempts: 2},
+		{name: "Gives up after the max retries", statusCodes: []int{http.StatusServiceUnavailable}, wantAttempts: 4, wantErr: true},
+		{name: "Fails immediately on 401", statusCodes: []int{http.StatusUnauthorized}, wantAttempts: 1, wantErr: true},
+		{name: "Fails immediately on 403", statusCodes: []int{http.StatusForbidden}, wantAttempts: 1, wantErr: true},
+		{name: "Fails immediately on 422", statusCodes: []int{http.StatusUnprocessableEntity}, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				statusCode := tt.statusCodes[min(attempts, len(tt.statusCodes)-1)]
+				attempts++
+				w.WriteHeader(statusCode)
+			}))
+			defer server.Close()
+
+			err := testPolicy.Do(context.Background(), func(attempt uint) error {
+				resp, err := http.Get(server.URL)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					require.NoError(t, resp.Body.Close())
+				}()
+
+				if resp.StatusCode != http.StatusOK {
+					return NewHTTPError(resp, fmt.Errorf("status code: %d", resp.StatusCode))
+				}
+				return nil
+			})
+
+			require.Equal(t, tt.wantAttempts, attempts)
+			require.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestPolicy_Do_networkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	attempts := 0
+	err := testPolicy.Do(context.Background(), func(attempt uint) error {
+		attempts++
+		_, err := http.Get(url)
+		return err
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 4, attempts)
+}
+
+func TestPolicy_Do_cancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := testPolicy.Do(ctx, func(attempt uint) error {
+		attempts++
+		cancel()
+		return io.ErrUnexpectedEOF
+	})
+
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Equal(t, 1, attempts)
+}
+
+func TestPolicy_Do_nonRetryableError(t *testing.T) {
+	attempts := 0
+	err := testPolicy.Do(context.Background(), func(attempt uint) error {
+		attempts++
+		return errors.New("failed to unmarshal response")
+	})
+
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+type retryableError bool
+
+func (e retryableError) Error() string   { return "retryable error" }
+func (e retryableError) Retryable() bool { return bool(e) }
+
+func TestIsRetryable(t *testing.T) {
+	_, missingFileErr := os.Open(filepath.Join(t.TempDir(), "missing.ipa"))
+	var response map[string]interface{}
+	jsonErr := json.Unmarshal([]byte("{"), &response)
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Server error", err: &HTTPError{StatusCode: http.StatusBadGateway, Err: errors.New("bad gateway")}, want: true},
+		{name: "Rate limited", err: &HTTPError{StatusCode: http.StatusTooManyRequests, Err: errors.New("too many requests")}, want: true},
+		{name: "Forbidden", err: &HTTPError{StatusCode: http.StatusForbidden, Err: errors.New("forbidden")}, want: false},
+		{name: "Network error", err: &url.Error{Op: "Put", URL: "https://storage", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, want: true},
+		{name: "Wrapped connection reset", err: fmt.Errorf("failed to upload: %w", syscall.ECONNRESET), want: true},
+		{name: "Unexpected EOF", err: fmt.Errorf("failed to read response: %w", io.ErrUnexpectedEOF), want: true},
+		{name: "TLS handshake timeout", err: &url.Error{Op: "Put", URL: "https://storage", Err: tlsHandshakeTimeoutError{}}, want: true},
+		{name: "Retryable error", err: fmt.Errorf("upload failed: %w", retryableError(true)), want: true},
+		{name: "Non-retryable error", err: retryableError(false), want: false},
+		{name: "Missing file", err: fmt.Errorf("failed to open artifact: %w", missingFileErr), want: false},
+		{name: "Missing file of a request body", err: &url.Error{Op: "Put", URL: "https://storage", Err: missingFileErr}, want: false},
+		{name: "Permission error", err: &os.PathError{Op: "open", Path: "app.ipa", Err: os.ErrPermission}, want: false},
+		{name: "JSON decode error", err: fmt.Errorf("failed to unmarshal response: %w", jsonErr), want: false},
+		{name: "API error message", err: errors.New("failed to create artifact, error message: invalid file"), want: false},
+		{name: "Cancelled context", err: fmt.Errorf("upload failed: %w", context.Canceled), want: false},
+		{name: "Cancelled request", err: &url.Error{Op: "Put", URL: "https://storage", Err: context.Canceled}, want: false},
+		{name: "No error", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+// tlsHandshakeTimeoutError mimics the unexported TLS handshake timeout error of net/http.
+type tlsHandshakeTimeoutError struct{}
+
+func (tlsHandshakeTimeoutError) Timeout() bool   { return true }
+func (tlsHandshakeTimeoutError) Temporary() bool { return true }
+func (tlsHandshakeTimeoutError) Error() string   { return "net/http: TLS handshake timeout" }
+
+func TestPolicy_Delay(t *testing.T) {
+	policy := Policy{
+		BaseDelay: time.Second,
+		MaxDelay:  10 * time.Second,
+	}
+
+	for attempt, wantMax := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		delay := policy.Delay(uint(attempt), 0)
+		require.GreaterOrEqual(t, delay, wantMax/2)
+		require.LessOrEqual(t, delay, wantMax)
+	}
+
+	require.Equal(t, 30*time.Second, policy.Delay(0, 30*time.Second))
+	require.Equal(t, maxRetryAfter, policy.Delay(0, time.Hour))
+}
+
+func TestPolicy_ConfigureClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusCodes  []int
+		retryAfter   string
+		wantAttempts int
+		wantStatus   int
+	}{
+		{name: "Retries server errors", statusCodes: []int{http.StatusInternalServerError, http.StatusOK}, wantAttempts: 2, wantStatus: http.StatusOK},
+		{name: "Respects Retry-After", statusCodes: []int{http.StatusTooManyRequests, http.StatusOK}, retryAfter: "0", wantAttempts: 2, wantStatus: http.StatusOK},
+		{name: "Does not retry 501", statusCodes: []int{http.StatusNotImplemented}, wantAttempts: 1, wantStatus: http.StatusNotImplemented},
+		{name: "Does not retry permanent client errors", statusCodes: []int{http.StatusForbidden}, wantAttempts: 1, wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				statusCode := tt.statusCodes[min(attempts, len(tt.statusCodes)-1)]
+				attempts++
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(statusCode)
+			}))
+			defer server.Close()
+
+			client := retryablehttp.NewClient()
+			client.ErrorHandler = retryablehttp.PassthroughErrorHandler
+			resp, err := testPolicy.ConfigureClient(client).StandardClient().Get(server.URL)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+
+			require.Equal(t, tt.wantAttempts, attempts)
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+		})
+	}
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	got, ok := parseRetryAfter("120")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, got)
+
+	got, ok = parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	require.True(t, ok)
+	require.InDelta(t, time.Hour, got, float64(2*time.Second))
+
+	_, ok = parseRetryAfter("")
+	require.False(t, ok)
+
+	_, ok = parseRetryAfter("-1")
+	require.False(t, ok)
+}
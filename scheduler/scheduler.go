@@ -0,0 +1,238 @@
+// Package scheduler limits the number of concurrent uploads of the Step. One scheduler is shared by the artifact,
+// test attachment and html report asset uploads, its limit is either fixed or adapted to the aggregate throughput of the uploads.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+)
+
+const (
+	// MaxConcurrency is the maximum number of concurrent uploads.
+	MaxConcurrency = 20
+	// DefaultAdaptiveConcurrency is the number of concurrent uploads the adaptive mode starts with.
+	DefaultAdaptiveConcurrency = 2
+
+	// minWindow is the minimum duration of the uploads whose throughput is compared with the previous limit,
+	// so that a burst of small files doesn't decide the limit.
+	minWindow = time.Second
+	// growthThreshold is the relative throughput improvement which is needed to keep increasing the limit.
+	growthThreshold = 0.1
+	// backOffCooldown is the time after a back-off while further errors don't decrease the limit again,
+	// as the uploads started before the back-off are still failing with the previous limit.
+	backOffCooldown = 2 * time.Second
+)
+
+// Scheduler hands out upload slots up to its limit.
+// The limit of an adaptive scheduler grows by one while the aggregate throughput keeps improving,
+// and it is halved on uploads failing with network, server or quota errors and on 429 (Too Many Requests) responses.
+type Scheduler struct {
+	lock     sync.Mutex
+	limit    int
+	active   int
+	released chan struct{}
+
+	adaptive       bool
+	minLimit       int
+	maxLimit       int
+	windowStart    time.Time
+	windowBytes    int64
+	windowUploads  int
+	lastThroughput float64
+	lastBackOff    time.Time
+
+	now func() time.Time
+}
+
+// NewFixed returns a scheduler of a constant limit, clamped between 1 and MaxConcurrency.
+func NewFixed(limit int) *Scheduler {
+	limit = clamp(limit, 1, MaxConcurrency)
+	return &Scheduler{
+		limit:    limit,
+		minLimit: limit,
+		maxLimit: limit,
+		released: make(chan struct{}),
+		now:      time.Now,
+	}
+}
+
+// NewAdaptive returns a scheduler which starts with the initial limit and adapts it between 1 and the max limit.
+func NewAdaptive(initial, maxLimit int) *Scheduler {
+	return newAdaptive(initial, maxLimit, time.Now)
+}
+
+func newAdaptive(initial, maxLimit int, now func() time.Time) *Scheduler {
+	maxLimit = clamp(maxLimit, 1, MaxConcurrency)
+	return &Scheduler{
+		limit:       clamp(initial, 1, maxLimit),
+		released:    make(chan struct{}),
+		adaptive:    true,
+		minLimit:    1,
+		maxLimit:    maxLimit,
+		windowStart: now(),
+		now:         now,
+	}
+}
+
+// Limit returns the current number of concurrent uploads, 0 (no limit) for a nil scheduler.
+func (s *Scheduler) Limit() int {
+	if s == nil {
+		return 0
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.limit
+}
+
+// Acquire waits for a free upload slot, which has to be given back with Release.
+// A nil scheduler doesn't limit the uploads.
+func (s *Scheduler) Acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	for {
+		s.lock.Lock()
+		if s.active < s.limit {
+			s.active++
+			s.lock.Unlock()
+			return nil
+		}
+		released := s.released
+		s.lock.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release gives back an upload slot, with the number of bytes the upload sent and its error.
+func (s *Scheduler) Release(bytes int64, err error) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.active--
+	if s.adaptive {
+		switch {
+		case err == nil:
+			s.record(bytes)
+		case isCongestion(err):
+			s.backOff()
+		}
+	}
+
+	s.notify()
+}
+
+// Transport returns a round tripper which backs off the adaptive scheduler on 429 (Too Many Requests) responses,
+// including the ones which are retried successfully by the retry policy.
+func (s *Scheduler) Transport(base http.RoundTripper) http.RoundTripper {
+	if s == nil || !s.adaptive {
+		return base
+	}
+
+	return &backOffTransport{base: base, scheduler: s}
+}
+
+type backOffTransport struct {
+	base      http.RoundTripper
+	scheduler *Scheduler
+}
+
+func (t *backOffTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := t.base.RoundTrip(request)
+	if err == nil && response.StatusCode == http.StatusTooManyRequests {
+		t.scheduler.lock.Lock()
+		t.scheduler.backOff()
+		t.scheduler.lock.Unlock()
+	}
+
+	return response, err
+}
+
+// record adds a successful upload to the current window, and once the window is complete
+// compares its throughput with the throughput of the previous window to grow or shrink the limit.
+func (s *Scheduler) record(bytes int64) {
+	s.windowBytes += bytes
+	s.windowUploads++
+
+	now := s.now()
+	elapsed := now.Sub(s.windowStart)
+	if s.windowUploads < s.limit || elapsed < minWindow {
+		return
+	}
+
+	throughput := float64(s.windowBytes) / elapsed.Seconds()
+	switch {
+	case s.lastThroughput == 0 || throughput > s.lastThroughput*(1+growthThreshold):
+		s.limit = clamp(s.limit+1, s.minLimit, s.maxLimit)
+	case throughput < s.lastThroughput*(1-growthThreshold):
+		// More concurrent uploads only compete for the same bandwidth.
+		s.limit = clamp(s.limit-1, s.minLimit, s.maxLimit)
+	}
+	s.lastThroughput = throughput
+	s.resetWindow(now)
+}
+
+func (s *Scheduler) backOff() {
+	now := s.now()
+	if !s.lastBackOff.IsZero() && now.Sub(s.lastBackOff) < backOffCooldown {
+		return
+	}
+
+	s.limit = clamp(s.limit/2, s.minLimit, s.maxLimit)
+	s.lastBackOff = now
+	s.lastThroughput = 0
+	s.resetWindow(now)
+}
+
+// isCongestion reports whether the upload failed because the network or the servers are overloaded,
+// unlike the failures of invalid files and requests, which don't depend on the number of concurrent uploads.
+func isCongestion(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch failure.Classify(err) {
+	case failure.Auth, failure.Request, failure.Parse, failure.File:
+		return false
+	default:
+		return true
+	}
+}
+
+func (s *Scheduler) resetWindow(now time.Time) {
+	s.windowStart = now
+	s.windowBytes = 0
+	s.windowUploads = 0
+}
+
+// notify wakes up the waiting Acquire calls, as a slot was released or the limit changed.
+func (s *Scheduler) notify() {
+	close(s.released)
+	s.released = make(chan struct{})
+}
+
+func clamp(value, lower, upper int) int {
+	if value < lower {
+		return lower
+	}
+	if value > upper {
+		return upper
+	}
+	return value
+}
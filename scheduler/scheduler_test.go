@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// uploadWindow runs as many concurrent uploads as the current limit of the scheduler,
+// which send the given bytes in total during the given duration.
+func uploadWindow(t *testing.T, s *Scheduler, clock *fakeClock, d time.Duration, bytes int64) {
+	limit := s.Limit()
+	for i := 0; i < limit; i++ {
+		require.NoError(t, s.Acquire(context.Background()))
+	}
+	clock.Advance(d)
+	for i := 0; i < limit; i++ {
+		s.Release(bytes/int64(limit), nil)
+	}
+}
+
+func TestNewFixed(t *testing.T) {
+	require.Equal(t, 1, NewFixed(0).Limit())
+	require.Equal(t, 3, NewFixed(3).Limit())
+	require.Equal(t, MaxConcurrency, NewFixed(100).Limit())
+
+	s := NewFixed(3)
+	s.Release(0, errors.New("upload failed"))
+	require.Equal(t, 3, s.Limit())
+}
+
+func TestScheduler_Acquire(t *testing.T) {
+	s := NewFixed(2)
+	require.NoError(t, s.Acquire(context.Background()))
+	require.NoError(t, s.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, s.Acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- s.Acquire(context.Background())
+	}()
+	s.Release(0, nil)
+	require.NoError(t, <-acquired)
+}
+
+func TestScheduler_Acquire_nil(t *testing.T) {
+	var s *Scheduler
+	require.NoError(t, s.Acquire(context.Background()))
+	s.Release(0, nil)
+	require.Equal(t, 0, s.Limit())
+}
+
+func TestAdaptive_growsWhileThroughputImproves(t *testing.T) {
+	clock := newFakeClock()
+	s := newAdaptive(DefaultAdaptiveConcurrency, MaxConcurrency, clock.Now)
+
+	// The throughput scales with the concurrency up to 4 uploads, then it is capped by the bandwidth.
+	for i := 0; i < 10; i++ {
+		uploadWindow(t, s, clock, time.Second, int64(min(s.Limit(), 4))*1000*1000)
+	}
+
+	// The 5th upload didn't improve the throughput, so the limit stays there.
+	require.Equal(t, 5, s.Limit())
+}
+
+func TestAdaptive_shrinksWhenThroughputDrops(t *testing.T) {
+	clock := newFakeClock()
+	s := newAdaptive(4, MaxConcurrency, clock.Now)
+
+	uploadWindow(t, s, clock, time.Second, 10*1000*1000)
+	require.Equal(t, 5, s.Limit())
+
+	uploadWindow(t, s, clock, time.Second, 5*1000*1000)
+	require.Equal(t, 4, s.Limit())
+}
+
+func TestAdaptive_waitsForCompleteWindow(t *testing.T) {
+	clock := newFakeClock()
+	s := newAdaptive(2, MaxConcurrency, clock.Now)
+
+	require.NoError(t, s.Acquire(context.Background()))
+	require.NoError(t, s.Acquire(context.Background()))
+	clock.Advance(time.Second)
+	s.Release(1000, nil)
+	require.Equal(t, 2, s.Limit())
+
+	s.Release(1000, nil)
+	require.Equal(t, 3, s.Limit())
+
+	// A window shorter than a second is not evaluated.
+	uploadWindow(t, s, clock, 100*time.Millisecond, 1000*1000)
+	require.Equal(t, 3, s.Limit())
+}
+
+func TestAdaptive_backsOffOnErrors(t *testing.T) {
+	clock := newFakeClock()
+	s := newAdaptive(8, MaxConcurrency, clock.Now)
+
+	require.NoError(t, s.Acquire(context.Background()))
+	s.Release(0, retrypolicy.NewHTTPError(&http.Response{StatusCode: http.StatusServiceUnavailable}, errors.New("503 Service Unavailable")))
+	require.Equal(t, 4, s.Limit())
+
+	// Errors of the uploads started with the previous limit don't back off again.
+	require.NoError(t, s.Acquire(context.Background()))
+	s.Release(0, errors.New("upload failed"))
+	require.Equal(t, 4, s.Limit())
+
+	clock.Advance(backOffCooldown)
+	require.NoError(t, s.Acquire(context.Background()))
+	s.Release(0, errors.New("upload failed"))
+	require.Equal(t, 2, s.Limit())
+
+	// Failures which don't depend on the concurrency don't back off.
+	for _, err := range []error{
+		context.Canceled,
+		failure.New(failure.Parse, errors.New("invalid ipa")),
+		&os.PathError{Op: "open", Path: "app.ipa", Err: os.ErrNotExist},
+		retrypolicy.NewHTTPError(&http.Response{StatusCode: http.StatusForbidden}, errors.New("403 Forbidden")),
+	} {
+		clock.Advance(backOffCooldown)
+		require.NoError(t, s.Acquire(context.Background()))
+		s.Release(0, err)
+		require.Equal(t, 2, s.Limit(), err.Error())
+	}
+
+	for i := 0; i < 2; i++ {
+		clock.Advance(backOffCooldown)
+		require.NoError(t, s.Acquire(context.Background()))
+		s.Release(0, errors.New("upload failed"))
+	}
+	require.Equal(t, 1, s.Limit())
+}
+
+func TestScheduler_Transport(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	clock := newFakeClock()
+	s := newAdaptive(8, MaxConcurrency, clock.Now)
+	client := &http.Client{Transport: s.Transport(http.DefaultTransport)}
+
+	response, err := client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, 8, s.Limit())
+
+	status = http.StatusTooManyRequests
+	response, err = client.Get(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	require.Equal(t, 4, s.Limit())
+
+	require.Equal(t, http.DefaultTransport, NewFixed(8).Transport(http.DefaultTransport))
+}
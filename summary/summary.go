@@ -0,0 +1,203 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders"
+)
+
+// Upload outcome statuses.
+const (
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Summary is the machine-readable result of a deployment.
+type Summary struct {
+	Items       []Item         `json:"items"`
+	TestResults *UploadOutcome `json:"test_results,omitempty"`
+	HTMLReports *UploadOutcome `json:"html_reports,omitempty"`
+}
+
+// Item describes the deployment of a single deployment.DeployableItem.
+type Item struct {
+	OriginalPath         string                               `json:"original_path"`
+	UploadedPath         string                               `json:"uploaded_path,omitempty"`
+	SnapshotMethod       string                               `json:"snapshot_method,omitempty"`
+	GeneratedFrom        string                               `json:"generated_from,omitempty"`
+	Type                 string                               `json:"type"`
+	ArchiveAsArtifact    bool                                 `json:"archive_as_artifact"`
+	IntermediateFileMeta *deployment.IntermediateFileMetaData `json:"intermediate_file_meta,omitempty"`
+	SizeBytes            int64                                `json:"size_bytes"`
+	Transfers            []Transfer                           `json:"transfers"`
+	URLs                 []URLs                               `json:"urls"`
+	AppMetadata          interface{}                          `json:"app_metadata,omitempty"`
+	CustomMetadata       map[string]interface{}               `json:"custom_metadata,omitempty"`
+	Error                string                               `json:"error,omitempty"`
+	FailureReason        failure.Reason                       `json:"failure_reason,omitempty"`
+}
+
+// Transfer describes the upload of an item to one of its storage locations.
+type Transfer struct {
+	Hostname                 string  `json:"hostname"`
+	DurationMs               int64   `json:"duration_ms"`
+	SizeBytes                int64   `json:"size_bytes"`
+	ThroughputBytesPerSecond float64 `json:"throughput_bytes_per_second"`
+	SHA256                   string  `json:"sha256,omitempty"`
+}
+
+// URLs are the pages and downloads of a deployed item.
+type URLs struct {
+	ArtifactID           string `json:"artifact_id,omitempty"`
+	PublicInstallPageURL string `json:"public_install_page_url,omitempty"`
+	PermanentDownloadURL string `json:"permanent_download_url,omitempty"`
+	DetailsPageURL       string `json:"details_page_url,omitempty"`
+	SHA256               string `json:"sha256,omitempty"`
+}
+
+// UploadOutcome describes the upload of the test results or the HTML reports.
+type UploadOutcome struct {
+	Status string   `json:"status"`
+	Errors []string `json:"errors,omitempty"`
+	// FailureReason is the reason of the first error.
+	FailureReason failure.Reason `json:"failure_reason,omitempty"`
+}
+
+// NewItem creates the summary of a deployable item based on its upload report and deployment error.
+func NewItem(item deployment.DeployableItem, itemType string, report *uploaders.UploadReport, err error) Item {
+	summaryItem := Item{
+		OriginalPath:         item.Path,
+		GeneratedFrom:        item.GeneratedFrom,
+		Type:                 itemType,
+		ArchiveAsArtifact:    item.ArchiveAsArtifact,
+		IntermediateFileMeta: item.IntermediateFileMeta,
+		CustomMetadata:       item.CustomMetadata,
+		Transfers:            []Transfer{},
+		URLs:                 []URLs{},
+	}
+	if item.SourcePath != "" {
+		summaryItem.OriginalPath = item.SourcePath
+	}
+	if err != nil {
+		summaryItem.Error = err.Error()
+		summaryItem.FailureReason = failure.Classify(err)
+	}
+	if report == nil {
+		return summaryItem
+	}
+
+	summaryItem.UploadedPath = report.UploadedPath
+	summaryItem.SnapshotMethod = report.SnapshotMethod
+	summaryItem.SizeBytes = report.FileSize
+	summaryItem.AppMetadata = report.AppMetadata
+	for _, details := range report.Transfers {
+		summaryItem.Transfers = append(summaryItem.Transfers, Transfer{
+			Hostname:                 details.Hostname,
+			DurationMs:               details.Duration.Milliseconds(),
+			SizeBytes:                details.Size,
+			ThroughputBytesPerSecond: details.Throughput,
+			SHA256:                   details.Checksums.SHA256,
+		})
+	}
+	for _, urls := range report.ArtifactURLs {
+		summaryItem.URLs = append(summaryItem.URLs, URLs{
+			ArtifactID:           urls.ArtifactID,
+			PublicInstallPageURL: urls.PublicInstallPageURL,
+			PermanentDownloadURL: urls.PermanentDownloadURL,
+			DetailsPageURL:       urls.DetailsPageURL,
+			SHA256:               urls.Checksums.SHA256,
+		})
+	}
+
+	return summaryItem
+}
+
+// NewUploadOutcome creates an UploadOutcome from the errors of an upload.
+func NewUploadOutcome(errs ...error) UploadOutcome {
+	outcome := UploadOutcome{Status: StatusSuccess}
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if outcome.Status != StatusFailed {
+			outcome.Status = StatusFailed
+			outcome.FailureReason = failure.Classify(err)
+		}
+		outcome.Errors = append(outcome.Errors, err.Error())
+	}
+
+	return outcome
+}
+
+// Recorder collects the summary of a deployment, it is safe for concurrent use.
+type Recorder struct {
+	lock    sync.Mutex
+	summary Summary
+}
+
+// NewRecorder ...
+func NewRecorder() *Recorder {
+	return &Recorder{summary: Summary{Items: []Item{}}}
+}
+
+// AddItem ...
+func (r *Recorder) AddItem(item Item) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary.Items = append(r.summary.Items, item)
+}
+
+// SetTestResults ...
+func (r *Recorder) SetTestResults(outcome UploadOutcome) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary.TestResults = &outcome
+}
+
+// SetHTMLReports ...
+func (r *Recorder) SetHTMLReports(outcome UploadOutcome) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.summary.HTMLReports = &outcome
+}
+
+// Summary returns the collected summary with the items ordered by their original path and type.
+func (r *Recorder) Summary() Summary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	summary := r.summary
+	summary.Items = append([]Item{}, r.summary.Items...)
+	sort.SliceStable(summary.Items, func(i, j int) bool {
+		if summary.Items[i].OriginalPath != summary.Items[j].OriginalPath {
+			return summary.Items[i].OriginalPath < summary.Items[j].OriginalPath
+		}
+		return summary.Items[i].Type < summary.Items[j].Type
+	})
+
+	return summary
+}
+
+// Write writes the collected summary as JSON to the given path.
+func (r *Recorder) Write(pth string) error {
+	content, err := json.MarshalIndent(r.Summary(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal deploy summary: %w", err)
+	}
+
+	if err := os.WriteFile(pth, content, 0600); err != nil {
+		return fmt.Errorf("failed to write deploy summary: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewItem(t *testing.T) {
+	item := deployment.DeployableItem{
+		Path:                 "/tmp/build.zip",
+		SourcePath:           "/bitrise/build",
+		IntermediateFileMeta: &deployment.IntermediateFileMetaData{EnvKey: "BUILD_DIR", IsDir: true},
+	}
+	report := uploaders.UploadReport{
+		FileSize:       1024,
+		UploadedPath:   "/tmp/build.zip",
+		SnapshotMethod: "clone",
+		Transfers: []uploaders.TransferDetails{
+			{
+				Size:       1024,
+				Duration:   1500 * time.Millisecond,
+				Hostname:   "storage.googleapis.com",
+				Checksums:  uploaders.Checksums{SHA256: "sha256"},
+				Throughput: 682.6,
+			},
+		},
+		ArtifactURLs: []uploaders.ArtifactURLs{
+			{ArtifactID: "artifact-id", PermanentDownloadURL: "https://app.bitrise.io/download", Checksums: uploaders.Checksums{SHA256: "sha256"}},
+		},
+	}
+
+	got := NewItem(item, "file", &report, nil)
+
+	require.Equal(t, Item{
+		OriginalPath:         "/bitrise/build",
+		UploadedPath:         "/tmp/build.zip",
+		SnapshotMethod:       "clone",
+		Type:                 "file",
+		IntermediateFileMeta: item.IntermediateFileMeta,
+		SizeBytes:            1024,
+		Transfers: []Transfer{
+			{Hostname: "storage.googleapis.com", DurationMs: 1500, SizeBytes: 1024, ThroughputBytesPerSecond: 682.6, SHA256: "sha256"},
+		},
+		URLs: []URLs{
+			{ArtifactID: "artifact-id", PermanentDownloadURL: "https://app.bitrise.io/download", SHA256: "sha256"},
+		},
+	}, got)
+}
+
+func TestNewItem_notUploaded(t *testing.T) {
+	item := deployment.DeployableItem{Path: "/bitrise/deploy/app.ipa", ArchiveAsArtifact: true}
+
+	got := NewItem(item, "ipa", nil, fmt.Errorf("app.ipa was not deployed: %w", context.DeadlineExceeded))
+
+	require.Equal(t, Item{
+		OriginalPath:      "/bitrise/deploy/app.ipa",
+		Type:              "ipa",
+		ArchiveAsArtifact: true,
+		Transfers:         []Transfer{},
+		URLs:              []URLs{},
+		Error:             "app.ipa was not deployed: context deadline exceeded",
+		FailureReason:     failure.Timeout,
+	}, got)
+}
+
+func TestNewUploadOutcome(t *testing.T) {
+	require.Equal(t, UploadOutcome{Status: StatusSuccess}, NewUploadOutcome())
+	require.Equal(t, UploadOutcome{Status: StatusSuccess}, NewUploadOutcome(nil))
+	require.Equal(t, UploadOutcome{Status: StatusFailed, Errors: []string{"a", "b"}, FailureReason: failure.Parse}, NewUploadOutcome(failure.New(failure.Parse, errors.New("a")), nil, errors.New("b")))
+}
+
+func TestRecorder_Write(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.AddItem(Item{OriginalPath: "/b.apk", UploadedPath: "/b.apk", SnapshotMethod: "copy", Type: "apk"})
+	recorder.AddItem(Item{OriginalPath: "/a.ipa", Type: "ipa"})
+	recorder.SetTestResults(UploadOutcome{Status: StatusSkipped})
+	recorder.SetHTMLReports(NewUploadOutcome(errors.New("upload failed")))
+
+	pth := filepath.Join(t.TempDir(), "summary.json")
+	require.NoError(t, recorder.Write(pth))
+
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(content, &got))
+
+	items := got["items"].([]interface{})
+	require.Len(t, items, 2)
+	require.Equal(t, "/a.ipa", items[0].(map[string]interface{})["original_path"])
+	require.NotContains(t, items[0], "uploaded_path")
+	require.Equal(t, "/b.apk", items[1].(map[string]interface{})["original_path"])
+	require.Equal(t, "/b.apk", items[1].(map[string]interface{})["uploaded_path"])
+	require.Equal(t, "copy", items[1].(map[string]interface{})["snapshot_method"])
+	require.Equal(t, map[string]interface{}{"status": "skipped"}, got["test_results"])
+	require.Equal(t, map[string]interface{}{"status": "failed", "errors": []interface{}{"upload failed"}, "failure_reason": "unknown"}, got["html_reports"])
+}
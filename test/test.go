@@ -2,20 +2,26 @@ package test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/bitrise-io/bitrise/models"
 	"github.com/bitrise-io/go-utils/fileutil"
 	"github.com/bitrise-io/go-utils/pathutil"
 	logV2 "github.com/bitrise-io/go-utils/v2/log"
 	"github.com/bitrise-io/go-utils/v2/retryhttp"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/test/converters"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/test/testasset"
 	"github.com/hashicorp/go-retryablehttp"
@@ -62,16 +68,15 @@ type Result struct {
 // Results ...
 type Results []Result
 
-func httpCall(apiToken, method, url string, input io.Reader, output interface{}, logger logV2.Logger) error {
+func httpCall(ctx context.Context, client *retryablehttp.Client, apiToken, method, url string, input io.Reader, output interface{}, logger logV2.Logger) error {
 	if apiToken != "" {
 		url = url + "/" + apiToken
 	}
-	req, err := retryablehttp.NewRequest(method, url, input)
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, url, input)
 	if err != nil {
 		return err
 	}
 
-	client := retryhttp.NewClient(logger)
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -87,9 +92,9 @@ func httpCall(apiToken, method, url string, input io.Reader, output interface{},
 		bodyData, err := io.ReadAll(resp.Body)
 		if err != nil {
 			logger.Warnf("Failed to read response: %s", err)
-			return fmt.Errorf("unsuccessful status code: %d", resp.StatusCode)
+			return retrypolicy.NewHTTPError(resp, fmt.Errorf("unsuccessful status code: %d", resp.StatusCode))
 		}
-		return fmt.Errorf("unsuccessful status code: %d, response: %s", resp.StatusCode, bodyData)
+		return retrypolicy.NewHTTPError(resp, fmt.Errorf("unsuccessful status code: %d, response: %s", resp.StatusCode, bodyData))
 	}
 
 	if output != nil {
@@ -229,12 +234,12 @@ func ParseTestResults(testsRootDir string, useLegacyXCResultExtractionMethod boo
 						Name string `json:"test-name"`
 					}
 					if err := json.Unmarshal(testInfoFileContent, &testInfo); err != nil {
-						return nil, err
+						return nil, failure.New(failure.Parse, fmt.Errorf("failed to parse test-info.json in %s: %w", testPhaseDirPath, err))
 					}
 
 					testReport, err := converter.Convert()
 					if err != nil {
-						return nil, err
+						return nil, failure.New(failure.Parse, fmt.Errorf("failed to convert test results in %s: %w", testPhaseDirPath, err))
 					}
 					xmlData, err := xml.MarshalIndent(testReport, "", " ")
 					if err != nil {
@@ -259,12 +264,16 @@ func ParseTestResults(testsRootDir string, useLegacyXCResultExtractionMethod boo
 	return results, nil
 }
 
-// Upload ...
-func (results Results) Upload(apiToken, endpointBaseURL, appSlug, buildSlug string, logger logV2.Logger) error {
+// Upload uploads the test results and their attachments, all requests are sent with the shared httpClient.
+// The attachments of a test result are uploaded concurrently, in the upload slots of the scheduler.
+func (results Results) Upload(ctx context.Context, apiToken, endpointBaseURL, appSlug, buildSlug string, httpClient *http.Client, retryPolicy retrypolicy.Policy, uploadScheduler *scheduler.Scheduler, logger logV2.Logger) error {
 	if results.calculateTotalSizeOfXMLContent() > maxTotalXMLSize {
-		return fmt.Errorf("the total size of the test result XML files (%d MiB) exceeds the maximum allowed size of 100 MiB", results.calculateTotalSizeOfXMLContent()/1024/1024)
+		return failure.New(failure.Quota, fmt.Errorf("the total size of the test result XML files (%d MiB) exceeds the maximum allowed size of 100 MiB", results.calculateTotalSizeOfXMLContent()/1024/1024))
 	}
 
+	client := retryPolicy.ConfigureClient(retryhttp.NewClient(logger))
+	client.HTTPClient = httpClient
+
 	for _, result := range results {
 		logger.Printf("Uploading: %s", result.Name)
 
@@ -296,31 +305,20 @@ func (results Results) Upload(apiToken, endpointBaseURL, appSlug, buildSlug stri
 			uploadResponse   UploadResponse
 			uploadRequestURL = fmt.Sprintf("%s/apps/%s/builds/%s/test_reports", endpointBaseURL, appSlug, buildSlug)
 		)
-		if err := httpCall(apiToken, http.MethodPost, uploadRequestURL, bytes.NewReader(uploadRequestBodyData), &uploadResponse, logger); err != nil {
+		if err := httpCall(ctx, client, apiToken, http.MethodPost, uploadRequestURL, bytes.NewReader(uploadRequestBodyData), &uploadResponse, logger); err != nil {
 			return fmt.Errorf("failed to initialise test result: %w", err)
 		}
 
-		if err := httpCall("", http.MethodPut, uploadResponse.URL, bytes.NewReader(result.XMLContent), nil, logger); err != nil {
+		if err := httpCall(ctx, client, "", http.MethodPut, uploadResponse.URL, bytes.NewReader(result.XMLContent), nil, logger); err != nil {
 			return fmt.Errorf("failed to upload test result xml: %w", err)
 		}
 
-		for _, upload := range uploadResponse.Assets {
-			for _, file := range result.AttachmentPaths {
-				if relativeFilePath(file, result.Name) == upload.FileName {
-					fi, err := os.Open(file)
-					if err != nil {
-						return fmt.Errorf("failed to open test result attachment (%s): %w", file, err)
-					}
-					if err := httpCall("", http.MethodPut, upload.URL, fi, nil, logger); err != nil {
-						return fmt.Errorf("failed to upload test result attachment (%s): %w", file, err)
-					}
-					break
-				}
-			}
+		if err := uploadAttachments(ctx, client, result, uploadResponse.Assets, uploadScheduler, logger); err != nil {
+			return err
 		}
 
 		var uploadPatchURL = fmt.Sprintf("%s/apps/%s/builds/%s/test_reports/%s", endpointBaseURL, appSlug, buildSlug, uploadResponse.ID)
-		if err := httpCall(apiToken, http.MethodPatch, uploadPatchURL, strings.NewReader(`{"uploaded":true}`), nil, logger); err != nil {
+		if err := httpCall(ctx, client, apiToken, http.MethodPatch, uploadPatchURL, strings.NewReader(`{"uploaded":true}`), nil, logger); err != nil {
 			return fmt.Errorf("failed to finalise test result: %w", err)
 		}
 	}
@@ -328,6 +326,73 @@ func (results Results) Upload(apiToken, endpointBaseURL, appSlug, buildSlug stri
 	return nil
 }
 
+// uploadAttachments uploads the attachments of the test result to their upload URLs concurrently.
+func uploadAttachments(ctx context.Context, client *retryablehttp.Client, result Result, uploads []UploadURL, uploadScheduler *scheduler.Scheduler, logger logV2.Logger) error {
+	var (
+		wg         sync.WaitGroup
+		errsLock   sync.Mutex
+		uploadErrs []error
+	)
+
+	for _, upload := range uploads {
+		for _, file := range result.AttachmentPaths {
+			if relativeFilePath(file, result.Name) != upload.FileName {
+				continue
+			}
+
+			wg.Add(1)
+			go func(file, url string) {
+				defer wg.Done()
+
+				if err := uploadAttachment(ctx, client, file, url, uploadScheduler, logger); err != nil {
+					errsLock.Lock()
+					uploadErrs = append(uploadErrs, err)
+					errsLock.Unlock()
+				}
+			}(file, upload.URL)
+			break
+		}
+	}
+	wg.Wait()
+
+	return errors.Join(uploadErrs...)
+}
+
+func uploadAttachment(ctx context.Context, client *retryablehttp.Client, file, url string, uploadScheduler *scheduler.Scheduler, logger logV2.Logger) error {
+	// The slot is acquired before the file is opened, so that at most as many attachments are open as the number of concurrent uploads.
+	if err := uploadScheduler.Acquire(ctx); err != nil {
+		return fmt.Errorf("test result attachment (%s) was not uploaded: %w", file, err)
+	}
+
+	size, err := putAttachment(ctx, client, file, url, logger)
+	uploadScheduler.Release(size, err)
+
+	return err
+}
+
+func putAttachment(ctx context.Context, client *retryablehttp.Client, file, url string, logger logV2.Logger) (int64, error) {
+	fi, err := os.Open(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open test result attachment (%s): %w", file, err)
+	}
+	defer func() {
+		if err := fi.Close(); err != nil {
+			logger.Warnf("Failed to close test result attachment (%s): %s", file, err)
+		}
+	}()
+
+	var size int64
+	if info, err := fi.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	if err := httpCall(ctx, client, "", http.MethodPut, url, fi, nil, logger); err != nil {
+		return size, fmt.Errorf("failed to upload test result attachment (%s): %w", file, err)
+	}
+
+	return size, nil
+}
+
 func (results Results) calculateTotalSizeOfXMLContent() int {
 	totalSize := 0
 	for _, result := range results {
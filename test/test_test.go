@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -17,6 +18,8 @@ import (
 	"github.com/bitrise-io/go-utils/v2/command"
 	"github.com/bitrise-io/go-utils/v2/env"
 	logV2 "github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/scheduler"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -158,7 +161,7 @@ func Test_Upload(t *testing.T) {
 
 	time.Sleep(time.Second)
 
-	if err := results.Upload("access-token", "http://localhost:8893/test", "test-app-slug", "test-build-slug", logV2.NewLogger()); err != nil {
+	if err := results.Upload(context.Background(), "access-token", "http://localhost:8893/test", "test-app-slug", "test-build-slug", http.DefaultClient, retrypolicy.DefaultPolicy(), scheduler.NewFixed(2), logV2.NewLogger()); err != nil {
 		t.Fatalf("%v", errors.WithStack(err))
 		return
 	}
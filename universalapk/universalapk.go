@@ -0,0 +1,213 @@
+// Package universalapk generates universal APKs from Android App Bundles with bundletool.
+package universalapk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-android/v2/metaparser/bundletool"
+	"github.com/bitrise-io/go-utils/v2/command"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/androidarchive"
+)
+
+const (
+	universalSplitParam = "universal"
+	universalAPKPath    = "universal.apk"
+
+	debugKeystorePassword = "android"
+	debugKeystoreAlias    = "androiddebugkey"
+)
+
+// Keystore is the keystore used to sign the generated APKs.
+type Keystore struct {
+	Path        string
+	Password    string
+	Alias       string
+	KeyPassword string
+}
+
+// NewKeystore returns the given keystore, the key password defaults to the keystore password.
+func NewKeystore(pth, password, alias, keyPassword string) (Keystore, error) {
+	pth = strings.TrimPrefix(pth, "file://")
+	if alias == "" {
+		return Keystore{}, errors.New("keystore alias is required when a keystore is provided")
+	}
+	if keyPassword == "" {
+		keyPassword = password
+	}
+
+	return Keystore{
+		Path:        pth,
+		Password:    password,
+		Alias:       alias,
+		KeyPassword: keyPassword,
+	}, nil
+}
+
+// DebugKeystore returns the Android debug keystore of the user,
+// if it does not exist a new debug keystore is generated in the tmpDir.
+func DebugKeystore(tmpDir string, logger log.Logger) (Keystore, error) {
+	keystore := Keystore{
+		Password:    debugKeystorePassword,
+		Alias:       debugKeystoreAlias,
+		KeyPassword: debugKeystorePassword,
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		pth := filepath.Join(homeDir, ".android", "debug.keystore")
+		if _, err := os.Stat(pth); err == nil {
+			keystore.Path = pth
+			return keystore, nil
+		}
+	}
+
+	keystore.Path = filepath.Join(tmpDir, "debug.keystore")
+	logger.Printf("No debug keystore found, generating one: %s", keystore.Path)
+
+	factory := command.NewFactory(env.NewRepository())
+	cmd := factory.Create("keytool", []string{
+		"-genkeypair", "-noprompt",
+		"-keystore", keystore.Path,
+		"-storepass", keystore.Password,
+		"-alias", keystore.Alias,
+		"-keypass", keystore.KeyPassword,
+		"-keyalg", "RSA",
+		"-keysize", "2048",
+		"-validity", "10000",
+		"-dname", "CN=Android Debug,O=Android,C=US",
+	}, nil)
+	if out, err := cmd.RunAndReturnTrimmedCombinedOutput(); err != nil {
+		return Keystore{}, fmt.Errorf("failed to generate debug keystore: %s: %w", out, err)
+	}
+
+	return keystore, nil
+}
+
+// Generator generates universal APKs from Android App Bundles.
+type Generator struct {
+	bundletool bundletool.Path
+	keystore   Keystore
+	outputDir  string
+	logger     log.Logger
+}
+
+// New ...
+func New(bundletoolPath bundletool.Path, keystore Keystore, outputDir string, logger log.Logger) *Generator {
+	return &Generator{
+		bundletool: bundletoolPath,
+		keystore:   keystore,
+		outputDir:  outputDir,
+		logger:     logger,
+	}
+}
+
+// Generate builds the universal APK of the AAB and returns its path.
+func (g *Generator) Generate(aabPth string) (string, error) {
+	workDir, err := os.MkdirTemp(g.outputDir, "universal-apk")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			g.logger.Warnf("Failed to remove temporary directory: %s", err)
+		}
+	}()
+
+	// Passwords are passed in files, as bundletool's command line is logged on failure.
+	keystorePasswordPth, err := writePasswordFile(workDir, g.keystore.Password)
+	if err != nil {
+		return "", err
+	}
+	keyPasswordPth, err := writePasswordFile(workDir, g.keystore.KeyPassword)
+	if err != nil {
+		return "", err
+	}
+
+	apksPth := filepath.Join(workDir, "universal.apks")
+	if _, err := g.bundletool.Exec("build-apks",
+		"--bundle="+aabPth,
+		"--output="+apksPth,
+		"--mode=universal",
+		"--overwrite",
+		"--ks="+g.keystore.Path,
+		"--ks-pass=file:"+keystorePasswordPth,
+		"--ks-key-alias="+g.keystore.Alias,
+		"--key-pass=file:"+keyPasswordPth,
+	); err != nil {
+		return "", fmt.Errorf("failed to build universal APK set: %w", err)
+	}
+
+	apkPth, err := g.apkPath(aabPth)
+	if err != nil {
+		return "", err
+	}
+	if err := androidarchive.ExtractAPK(apksPth, universalAPKPath, apkPth); err != nil {
+		return "", fmt.Errorf("failed to extract universal APK: %w", err)
+	}
+
+	return apkPth, nil
+}
+
+// apkPath returns the path of the AAB's universal APK in a new directory of the output dir,
+// as the AABs of different modules or source sets might have the same file name.
+func (g *Generator) apkPath(aabPth string) (string, error) {
+	apkDir, err := os.MkdirTemp(g.outputDir, "apk")
+	if err != nil {
+		return "", fmt.Errorf("failed to create universal APK dir: %w", err)
+	}
+
+	return filepath.Join(apkDir, APKName(aabPth)), nil
+}
+
+// writePasswordFile writes the password to a new file of the dir, which is removed if the write fails.
+func writePasswordFile(dir, password string) (pth string, err error) {
+	file, err := os.CreateTemp(dir, "password")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if removeErr := os.Remove(file.Name()); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+				err = fmt.Errorf("%w, failed to remove password file: %s", err, removeErr)
+			}
+		}
+	}()
+
+	if _, err := file.WriteString(password); err != nil {
+		_ = file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// APKName returns the file name of the AAB's universal APK, the universal split param is inserted
+// before the build type (<module>-<product flavor?>-universal-<build type>), so the APK is mapped to the same variant as the AAB.
+func APKName(aabPth string) string {
+	base := strings.TrimSuffix(filepath.Base(aabPth), filepath.Ext(aabPth))
+
+	var signingSuffix string
+	for _, suffix := range []string{"-bitrise-signed", "-unsigned"} {
+		if strings.HasSuffix(base, suffix) {
+			base = strings.TrimSuffix(base, suffix)
+			signingSuffix = suffix
+			break
+		}
+	}
+
+	segments := strings.Split(base, "-")
+	if len(segments) < 2 {
+		return base + "-" + universalSplitParam + signingSuffix + ".apk"
+	}
+
+	segments = append(segments[:len(segments)-1], universalSplitParam, segments[len(segments)-1])
+	return strings.Join(segments, "-") + signingSuffix + ".apk"
+}
@@ -0,0 +1,76 @@
+package universalapk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-android/v2/metaparser/androidartifact"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPKName(t *testing.T) {
+	tests := []struct {
+		aabPth string
+		want   string
+	}{
+		{aabPth: "/bitrise/deploy/app-release.aab", want: "app-universal-release.apk"},
+		{aabPth: "/bitrise/deploy/app-demo-debug.aab", want: "app-demo-universal-debug.apk"},
+		{aabPth: "/bitrise/deploy/app-release-unsigned.aab", want: "app-universal-release-unsigned.apk"},
+		{aabPth: "/bitrise/deploy/app-release-bitrise-signed.aab", want: "app-universal-release-bitrise-signed.apk"},
+		{aabPth: "/bitrise/deploy/app.aab", want: "app-universal.apk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aabPth, func(t *testing.T) {
+			require.Equal(t, tt.want, APKName(tt.aabPth))
+		})
+	}
+}
+
+func TestAPKName_mapsToAABVariant(t *testing.T) {
+	aabPth := "/bitrise/deploy/app-demo-release.aab"
+	aabInfo := androidartifact.ParseArtifactPath(aabPth)
+	apkInfo := androidartifact.ParseArtifactPath(APKName(aabPth))
+
+	require.True(t, apkInfo.SplitInfo.Universal)
+	require.Equal(t, aabInfo.Module, apkInfo.Module)
+	require.Equal(t, aabInfo.ProductFlavour, apkInfo.ProductFlavour)
+	require.Equal(t, aabInfo.BuildType, apkInfo.BuildType)
+}
+
+func TestGenerator_apkPath_isUniquePerAAB(t *testing.T) {
+	outputDir := t.TempDir()
+	generator := New("", Keystore{}, outputDir, nil)
+
+	appPth, err := generator.apkPath("/bitrise/src/app/build/outputs/bundle/release/app-release.aab")
+	require.NoError(t, err)
+	wearPth, err := generator.apkPath("/bitrise/src/wear/build/outputs/bundle/release/app-release.aab")
+	require.NoError(t, err)
+
+	require.NotEqual(t, appPth, wearPth)
+	for _, pth := range []string{appPth, wearPth} {
+		require.Equal(t, "app-universal-release.apk", filepath.Base(pth))
+		require.Equal(t, outputDir, filepath.Dir(filepath.Dir(pth)))
+	}
+}
+
+func Test_writePasswordFile(t *testing.T) {
+	dir := t.TempDir()
+	pth, err := writePasswordFile(dir, "store-pass")
+	require.NoError(t, err)
+	content, err := os.ReadFile(pth)
+	require.NoError(t, err)
+	require.Equal(t, "store-pass", string(content))
+
+	_, err = writePasswordFile(filepath.Join(dir, "missing"), "store-pass")
+	require.Error(t, err)
+}
+
+func TestNewKeystore(t *testing.T) {
+	keystore, err := NewKeystore("file:///bitrise/keystore.jks", "store-pass", "upload", "")
+	require.NoError(t, err)
+	require.Equal(t, Keystore{Path: "/bitrise/keystore.jks", Password: "store-pass", Alias: "upload", KeyPassword: "store-pass"}, keystore)
+
+	_, err = NewKeystore("/bitrise/keystore.jks", "store-pass", "", "")
+	require.Error(t, err)
+}
@@ -1,19 +1,33 @@
 package uploaders
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bitrise-io/go-android/v2/metaparser/androidartifact"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 )
 
 // DeployAAB ...
-func (u *Uploader) DeployAAB(item deployment.DeployableItem, artifacts []string, buildURL, token string) ([]ArtifactURLs, error) {
+func (u *Uploader) DeployAAB(ctx context.Context, item deployment.DeployableItem, artifacts []string, buildURL, token string) ([]ArtifactURLs, error) {
 	pth := item.Path
 
-	aabInfo, err := u.androidParser.ParseAABData(pth)
+	const AABContentType = "application/octet-stream aab"
+
+	aabInfo, err := u.aabParser.ParseAABData(pth)
 	if err != nil {
-		return nil, err
+		if !u.dryRun {
+			return nil, failure.New(failure.Parse, err)
+		}
+
+		// The upload is planned without the aab metadata, so that the plan lists every deployable item.
+		u.logger.Warnf("Failed to parse aab metadata: %s", err)
+		fileSize, err := u.fileManager.FileSizeInBytes(pth)
+		if err != nil {
+			return nil, fmt.Errorf("get file size: %w", err)
+		}
+		return u.upload(ctx, buildURL, token, ArtifactArgs{Path: pth, FileSize: fileSize}, "android-apk", AABContentType, &item, &AppDeploymentMetaData{})
 	}
 
 	u.logger.Printf("aab infos: %+v", printableAppInfo(aabInfo.AppInfo))
@@ -47,7 +61,6 @@ func (u *Uploader) DeployAAB(item deployment.DeployableItem, artifacts []string,
 
 	// ---
 
-	const AABContentType = "application/octet-stream aab"
 	artifact := ArtifactArgs{
 		Path:     pth,
 		FileSize: aabInfo.FileSizeBytes,
@@ -60,7 +73,7 @@ func (u *Uploader) DeployAAB(item deployment.DeployableItem, artifacts []string,
 		IsEnablePublicPage:     false,
 	}
 
-	urLs, err := u.upload(buildURL, token, artifact, "android-apk", AABContentType, &item, &buildArtifactMeta)
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "android-apk", AABContentType, &item, &buildArtifactMeta)
 	if err != nil {
 		return nil, fmt.Errorf("failed aab deploy: %w", err)
 	}
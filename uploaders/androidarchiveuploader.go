@@ -0,0 +1,103 @@
+package uploaders
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
+	"github.com/bitrise-io/go-android/v2/metaparser/androidartifact"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/androidarchive"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+)
+
+// DeployAndroidArchive deploys an .apks APK set or an .xapk archive with the metadata of its base APK.
+func (u *Uploader) DeployAndroidArchive(ctx context.Context, item deployment.DeployableItem, buildURL, token string) ([]ArtifactURLs, error) {
+	pth := item.Path
+
+	archiveInfo, err := u.parseAndroidArchive(pth)
+	if err != nil {
+		return nil, failure.New(failure.Parse, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err))
+	}
+
+	archiveType := strings.TrimPrefix(strings.ToLower(filepath.Ext(pth)), ".")
+	u.logger.Printf("%s infos: %+v", archiveType, printableAppInfo(archiveInfo.AppInfo))
+
+	contentType := "application/octet-stream " + archiveType
+	artifact := ArtifactArgs{
+		Path:     pth,
+		FileSize: archiveInfo.FileSizeBytes,
+	}
+	buildArtifactMeta := AppDeploymentMetaData{
+		AndroidArtifactInfo:    archiveInfo,
+		NotifyUserGroups:       "",
+		AlwaysNotifyUserGroups: "",
+		NotifyEmails:           "",
+		IsEnablePublicPage:     false,
+	}
+
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "android-apk", contentType, &item, &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed %s deploy: %w", archiveType, err)
+	}
+
+	return urLs, nil
+}
+
+// parseAndroidArchive parses the base APK of the archive, and describes the archive's APKs in the split metadata.
+func (u *Uploader) parseAndroidArchive(pth string) (*androidparser.ArtifactMetadata, error) {
+	archive, err := androidarchive.Read(pth)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "android-archive")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			u.logger.Warnf("Failed to remove temporary directory: %s", err)
+		}
+	}()
+
+	baseAPKPth := filepath.Join(tmpDir, filepath.Base(archive.BaseAPK))
+	if err := androidarchive.ExtractAPK(pth, archive.BaseAPK, baseAPKPth); err != nil {
+		return nil, err
+	}
+
+	archiveInfo, err := u.androidParser.ParseAPKData(baseAPKPth)
+	if err != nil {
+		return nil, failure.New(failure.Parse, fmt.Errorf("failed to parse base APK (%s): %w", archive.BaseAPK, err))
+	}
+
+	if archive.PackageName != "" && archiveInfo.AppInfo.PackageName != archive.PackageName {
+		u.logger.Warnf("Package name of the base APK (%s) differs from the archive's package name (%s)", archiveInfo.AppInfo.PackageName, archive.PackageName)
+	}
+
+	fileSize, err := u.fileManager.FileSizeInBytes(pth)
+	if err != nil {
+		u.logger.Warnf("Failed to get file size, error: %s", err)
+	}
+	archiveInfo.FileSizeBytes = fileSize
+
+	info := androidartifact.ParseArtifactPath(pth)
+	archiveInfo.Module = info.Module
+	archiveInfo.ProductFlavour = info.ProductFlavour
+	archiveInfo.BuildType = info.BuildType
+
+	archiveInfo.Artifact = androidartifact.Artifact{
+		Split:        archive.SplitPaths(),
+		UniversalApk: archive.UniversalAPK,
+	}
+
+	for _, split := range archive.Splits {
+		u.logger.Printf("- split: %s, module: %s, ABIs: %s, screen densities: %s, languages: %s",
+			split.Path, split.Module, strings.Join(split.ABIs, ", "), strings.Join(split.ScreenDensities, ", "), strings.Join(split.Languages, ", "))
+	}
+
+	return archiveInfo, nil
+}
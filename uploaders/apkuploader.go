@@ -1,6 +1,7 @@
 package uploaders
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bitrise-io/go-android/v2/metaparser/androidartifact"
@@ -8,7 +9,7 @@ import (
 )
 
 // DeployAPK ...
-func (u *Uploader) DeployAPK(item deployment.DeployableItem, artifacts []string, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
+func (u *Uploader) DeployAPK(ctx context.Context, item deployment.DeployableItem, artifacts []string, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
 	pth := item.Path
 
 	apkInfo, err := u.androidParser.ParseAPKData(pth)
@@ -40,7 +41,7 @@ func (u *Uploader) DeployAPK(item deployment.DeployableItem, artifacts []string,
 		IsEnablePublicPage:     isEnablePublicPage,
 	}
 
-	urLs, err := u.upload(buildURL, token, artifact, "android-apk", APKContentType, &item, &buildArtifactMeta)
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "android-apk", APKContentType, &item, &buildArtifactMeta)
 	if err != nil {
 		return nil, fmt.Errorf("failed apk deploy: %w", err)
 	}
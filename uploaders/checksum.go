@@ -0,0 +1,104 @@
+package uploaders
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+)
+
+// Checksums holds the hex encoded digests of an uploaded file.
+type Checksums struct {
+	SHA256 string
+	MD5    string
+}
+
+// ChecksumMismatchError is returned when the uploaded bytes do not match the file's checksums,
+// either because the storage rejected the upload's digest or because the file changed while it was read.
+type ChecksumMismatchError struct {
+	Reason string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch: %s", e.Reason)
+}
+
+// FailureReason categorizes the mismatch as a network failure, as the bytes were corrupted on their way to the storage.
+func (e *ChecksumMismatchError) FailureReason() failure.Reason {
+	return failure.Network
+}
+
+// Retryable reports that the upload can succeed if it is sent again.
+func (e *ChecksumMismatchError) Retryable() bool {
+	return true
+}
+
+// contentMD5 returns the MD5 digest in the base64 encoded format expected by the Content-MD5 header.
+func (c Checksums) contentMD5() string {
+	digest, err := hex.DecodeString(c.MD5)
+	if err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(digest)
+}
+
+func calculateChecksums(pth string) (Checksums, error) {
+	file, err := os.Open(pth)
+	if err != nil {
+		return Checksums{}, fmt.Errorf("failed to open artifact, error: %s", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warnf("failed to close file, error: %s", err)
+		}
+	}()
+
+	reader := newChecksumReader(file)
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return Checksums{}, fmt.Errorf("failed to read artifact, error: %s", err)
+	}
+
+	return reader.checksums(), nil
+}
+
+// checksumReader calculates the SHA-256 and MD5 digests of the content streamed through it.
+type checksumReader struct {
+	reader io.Reader
+	sha256 hash.Hash
+	md5    hash.Hash
+	read   int64
+}
+
+func newChecksumReader(reader io.Reader) *checksumReader {
+	r := &checksumReader{
+		sha256: sha256.New(),
+		md5:    md5.New(),
+	}
+	r.reader = io.TeeReader(reader, io.MultiWriter(r.sha256, r.md5))
+	return r
+}
+
+func (r *checksumReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	return n, err
+}
+
+// size returns the number of bytes read so far.
+func (r *checksumReader) size() int64 {
+	return r.read
+}
+
+func (r *checksumReader) checksums() Checksums {
+	return Checksums{
+		SHA256: hex.EncodeToString(r.sha256.Sum(nil)),
+		MD5:    hex.EncodeToString(r.md5.Sum(nil)),
+	}
+}
@@ -0,0 +1,81 @@
+package uploaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// abortTimeout limits the cleanup of the artifact records of a failed upload.
+const abortTimeout = time.Minute
+
+// ErrAbortUnsupported is returned by AbortArtifact if the backend can't remove artifact records.
+var ErrAbortUnsupported = errors.New("cleanup of artifact records is not supported")
+
+// OrphanedArtifact is an artifact record of a failed upload which could not be removed,
+// it is left on the build page without a file.
+type OrphanedArtifact struct {
+	ArtifactID string
+	Path       string
+	Err        error
+}
+
+// FinishOutcomeUnknownError is returned when a finish request failed, but one of its attempts might have finished the upload
+// (for example the request timed out after it was sent). The artifact record of such an upload is not aborted.
+type FinishOutcomeUnknownError struct {
+	ArtifactID string
+	Err        error
+}
+
+func (e FinishOutcomeUnknownError) Error() string {
+	return fmt.Sprintf("artifact %s might have been finished: %s", e.ArtifactID, e.Err)
+}
+
+func (e FinishOutcomeUnknownError) Unwrap() error {
+	return e.Err
+}
+
+// isRequestNotSent reports whether the request failed before it reached the server: the server couldn't be resolved or connected to.
+func isRequestNotSent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// OrphanedArtifacts returns the artifact records which could not be cleaned up after a failed upload.
+func (u *Uploader) OrphanedArtifacts() []OrphanedArtifact {
+	u.orphansLock.Lock()
+	defer u.orphansLock.Unlock()
+
+	return append([]OrphanedArtifact(nil), u.orphans...)
+}
+
+// abortArtifacts removes the artifact records of the failed upload tasks, so that no record without a file is left on the build page.
+// The cleanup runs even if the deployment was cancelled (for example by the deploy timeout).
+func (u *Uploader) abortArtifacts(ctx context.Context, buildURL, token, pth string, tasks []UploadTask) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), abortTimeout)
+	defer cancel()
+
+	for _, task := range tasks {
+		if err := u.client.AbortArtifact(ctx, buildURL, token, task.Identifier()); errors.Is(err, ErrAbortUnsupported) {
+			u.logger.Printf("Artifact record %s of %s is left on the build page: %s", task.Identifier(), filepath.Base(pth), err)
+			continue
+		} else if err != nil {
+			u.logger.Errorf("Failed to clean up artifact record %s of %s: %s", task.Identifier(), filepath.Base(pth), err)
+
+			u.orphansLock.Lock()
+			u.orphans = append(u.orphans, OrphanedArtifact{ArtifactID: task.Identifier(), Path: pth, Err: err})
+			u.orphansLock.Unlock()
+			continue
+		}
+
+		u.logger.Printf("Cleaned up artifact record %s of %s", task.Identifier(), filepath.Base(pth))
+	}
+}
@@ -0,0 +1,41 @@
+package uploaders
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+)
+
+// ArtifactClient ...
+type ArtifactClient interface {
+	CreateArtifact(ctx context.Context, buildURL, token string, artifact ArtifactArgs, artifactType, contentType string, archiveAsArtifact bool, pipelineMeta *deployment.IntermediateFileMetaData) ([]UploadTask, error)
+	// RenewUploadTask returns the upload task with a new upload URL, to replace its expired URL (artifacts/<id>/upload_url.json).
+	// ErrRenewalUnsupported is returned if the backend doesn't have the endpoint, then the upload fails with the expiry error
+	// and no more renewals are attempted. If the renewal fails otherwise, the upload fails with both errors.
+	RenewUploadTask(ctx context.Context, buildURL, token string, task UploadTask) (UploadTask, error)
+	UploadArtifact(ctx context.Context, uploadURL string, artifact ArtifactArgs, contentType string) (TransferDetails, error)
+	UploadArtifacts(ctx context.Context, uploadURLs []string, artifact ArtifactArgs, contentType string) ([]TransferDetails, []error)
+	// FinishArtifact finishes the upload of the artifact record. The retries of the request carry the same Idempotency-Key header,
+	// but the backend isn't known to honour it: a 409 (Conflict) is accepted once an attempt could have finished the upload.
+	// If the outcome of the request is unknown, a FinishOutcomeUnknownError is returned.
+	FinishArtifact(ctx context.Context, buildURL, token, artifactID string, appDeploymentMeta *AppDeploymentMetaData, checksums Checksums) (ArtifactURLs, error)
+	// AbortArtifact removes the artifact record of an upload which can't be finished (artifacts/<id>/abort_upload.json).
+	// ErrAbortUnsupported is returned if the backend doesn't have the endpoint, any other error leaves an orphaned artifact record.
+	AbortArtifact(ctx context.Context, buildURL, token, artifactID string) error
+}
+
+// BitriseArtifactClient is the ArtifactClient implementation talking to the Bitrise artifact API and the storage behind the upload URLs.
+type BitriseArtifactClient struct {
+	httpClient  *http.Client
+	retryPolicy retrypolicy.Policy
+}
+
+// NewArtifactClient ...
+func NewArtifactClient(httpClient *http.Client, retryPolicy retrypolicy.Policy) *BitriseArtifactClient {
+	return &BitriseArtifactClient{
+		httpClient:  httpClient,
+		retryPolicy: retryPolicy,
+	}
+}
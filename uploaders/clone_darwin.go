@@ -0,0 +1,8 @@
+package uploaders
+
+import "golang.org/x/sys/unix"
+
+// cloneFile creates a copy-on-write clone of the source file, supported by APFS.
+func cloneFile(sourcePath, destinationPath string) error {
+	return unix.Clonefile(sourcePath, destinationPath, unix.CLONE_NOFOLLOW)
+}
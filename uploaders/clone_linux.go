@@ -0,0 +1,35 @@
+package uploaders
+
+import (
+	"os"
+
+	"github.com/bitrise-io/go-utils/log"
+	"golang.org/x/sys/unix"
+)
+
+// cloneFile creates a copy-on-write clone (reflink) of the source file, supported by file systems like Btrfs and XFS.
+func cloneFile(sourcePath, destinationPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			log.Warnf("Failed to close original file: %s", err)
+		}
+	}()
+
+	destination, err := os.Create(destinationPath)
+	if err != nil {
+		return err
+	}
+
+	cloneErr := unix.IoctlFileClone(int(destination.Fd()), int(source.Fd()))
+	closeErr := destination.Close()
+	if cloneErr != nil {
+		_ = os.Remove(destinationPath)
+		return cloneErr
+	}
+
+	return closeErr
+}
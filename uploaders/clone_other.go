@@ -0,0 +1,7 @@
+//go:build !linux && !darwin
+
+package uploaders
+
+func cloneFile(_, _ string) error {
+	return errCloneNotSupported
+}
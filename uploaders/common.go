@@ -2,6 +2,8 @@ package uploaders
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,25 +20,47 @@ import (
 
 	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
 	"github.com/bitrise-io/go-utils/log"
-	"github.com/bitrise-io/go-utils/retry"
 	"github.com/bitrise-io/go-utils/urlutil"
 	iosparser "github.com/bitrise-io/go-xcode/v2/metaparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+)
+
+const (
+	// customMetadataKey is the artifact info key of the metadata read from the artifact's sidecar file.
+	customMetadataKey = "custom_metadata"
+	// generatedFromKey is the artifact info key of the file name of the artifact the file was generated from,
+	// like the AAB of a universal APK.
+	generatedFromKey = "generated_from_file_name"
+	// idempotencyKeyHeader identifies the retries of a finish request, it is sent on a best-effort basis.
+	idempotencyKeyHeader = "Idempotency-Key"
 )
 
 type ArtifactURLs struct {
+	// ArtifactID is the ID of the artifact record the URLs belong to.
+	ArtifactID           string
 	PublicInstallPageURL string
 	PermanentDownloadURL string
 	DetailsPageURL       string
+	Checksums            Checksums
 }
 
 type AppDeploymentMetaData struct {
 	AndroidArtifactInfo    *androidparser.ArtifactMetadata
 	IOSArtifactInfo        *iosparser.ArtifactMetadata
+	MacOSArtifactInfo      *macosparser.ArtifactMetadata
+	DebugSymbolsInfo       *debugsymbols.Metadata
 	NotifyUserGroups       string
 	AlwaysNotifyUserGroups string
 	NotifyEmails           string
 	IsEnablePublicPage     bool
+	// CustomMetadata is sent in the artifact info under the customMetadataKey.
+	CustomMetadata map[string]interface{}
+	// GeneratedFrom is the file name of the artifact the file was generated from, sent in the artifact info under the generatedFromKey.
+	GeneratedFrom string
 }
 
 type ArtifactArgs struct {
@@ -45,9 +69,12 @@ type ArtifactArgs struct {
 }
 
 type TransferDetails struct {
-	Size     int64
-	Duration time.Duration
-	Hostname string
+	Size      int64
+	Duration  time.Duration
+	Hostname  string
+	Checksums Checksums
+	// Throughput is the average transfer speed in bytes per second.
+	Throughput float64
 }
 
 type UploadTask struct {
@@ -61,14 +88,15 @@ func (u UploadTask) Identifier() string {
 	return fmt.Sprintf("%d", u.ID)
 }
 
-func createArtifact(buildURL, token string, artifact ArtifactArgs, artifactType, contentType string, archiveAsArtifact bool, pipelineMeta *deployment.IntermediateFileMetaData) ([]UploadTask, error) {
+// CreateArtifact ...
+func (c *BitriseArtifactClient) CreateArtifact(ctx context.Context, buildURL, token string, artifact ArtifactArgs, artifactType, contentType string, archiveAsArtifact bool, pipelineMeta *deployment.IntermediateFileMetaData) ([]UploadTask, error) {
 	// create form data
 	artifactName := filepath.Base(artifact.Path)
 
 	log.Printf("file size: %s", units.BytesSize(float64(artifact.FileSize)))
 
 	if strings.TrimSpace(token) == "" {
-		return nil, fmt.Errorf("provided API token is empty")
+		return nil, failure.New(failure.Auth, errors.New("provided API token is empty"))
 	}
 
 	data := url.Values{
@@ -100,13 +128,13 @@ func createArtifact(buildURL, token string, artifact ArtifactArgs, artifactType,
 	var response *http.Response
 	var uploadTasks []UploadTask
 
-	if err := retry.Times(3).Wait(5 * time.Second).Try(func(attempt uint) error {
+	if err := c.retryPolicy.Do(ctx, func(attempt uint) error {
 		if attempt > 0 {
 			log.Warnf("%d attempt failed", attempt)
 		}
-		response, err = http.PostForm(uri, data)
+		response, err = c.postForm(ctx, uri, data, nil)
 		if err != nil {
-			return fmt.Errorf("failed to perform create artifact request, error: %s", err)
+			return fmt.Errorf("failed to perform create artifact request, error: %w", err)
 		}
 
 		defer func() {
@@ -117,7 +145,7 @@ func createArtifact(buildURL, token string, artifact ArtifactArgs, artifactType,
 
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			return fmt.Errorf("failed to read create artifact response, error: %s", err)
+			return fmt.Errorf("failed to read create artifact response, error: %w", err)
 		}
 		if response.StatusCode != http.StatusOK {
 			type errorResponse struct {
@@ -125,10 +153,10 @@ func createArtifact(buildURL, token string, artifact ArtifactArgs, artifactType,
 			}
 			var createResponse errorResponse
 			if unmarshalErr := json.Unmarshal(body, &createResponse); unmarshalErr != nil {
-				return errors.New(string(body))
+				return retrypolicy.NewHTTPError(response, errors.New(string(body)))
 			}
 
-			return errors.New(createResponse.ErrorMessage)
+			return retrypolicy.NewHTTPError(response, errors.New(createResponse.ErrorMessage))
 		}
 
 		if err := json.Unmarshal(body, &uploadTasks); err != nil {
@@ -160,95 +188,239 @@ func createArtifact(buildURL, token string, artifact ArtifactArgs, artifactType,
 	return uploadTasks, nil
 }
 
-func UploadArtifact(uploadURL string, artifact ArtifactArgs, contentType string) (TransferDetails, error) {
-	netClient := &http.Client{
-		Timeout: 10 * time.Minute,
+// RenewUploadTask requests a new upload URL for the artifact record of the upload task,
+// the record is kept, so the upload can continue without creating a duplicate artifact.
+func (c *BitriseArtifactClient) RenewUploadTask(ctx context.Context, buildURL, token string, task UploadTask) (UploadTask, error) {
+	uri, err := urlutil.Join(buildURL, "artifacts", task.Identifier(), "upload_url.json")
+	if err != nil {
+		return UploadTask{}, fmt.Errorf("failed to generate renew upload url, error: %s", err)
 	}
 
-	start := time.Now()
-
-	err := retry.Times(3).Wait(5).Try(func(attempt uint) error {
-		file, err := os.Open(artifact.Path)
+	var renewed UploadTask
+	if err := c.retryPolicy.Do(ctx, func(attempt uint) error {
+		if attempt > 0 {
+			log.Warnf("%d attempt failed", attempt)
+		}
+		response, err := c.postForm(ctx, uri, url.Values{"api_token": {token}}, nil)
 		if err != nil {
-			return fmt.Errorf("failed to open artifact, error: %s", err)
+			return fmt.Errorf("failed to perform renew upload url request, error: %w", err)
 		}
 		defer func() {
-			if err := file.Close(); err != nil {
-				log.Warnf("failed to close file, error: %s", err)
+			if err := response.Body.Close(); err != nil {
+				log.Errorf("Failed to close reponse body, error: %s", err)
 			}
 		}()
 
-		// Initializes request body to nil to send a Content-Length of 0: https://github.com/golang/go/issues/20257#issuecomment-299509391
-		var reqBody io.Reader
-		if artifact.FileSize > 0 {
-			reqBody = io.NopCloser(file)
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read renew upload url response, error: %w", err)
+		}
+		// A backend without the renewal endpoint answers with 404 (Not Found), 405 (Method Not Allowed) or 501 (Not Implemented).
+		switch response.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return fmt.Errorf("%w, status code: %d", ErrRenewalUnsupported, response.StatusCode)
+		}
+		if response.StatusCode != http.StatusOK {
+			return retrypolicy.NewHTTPError(response, fmt.Errorf("failed to renew upload url, status code: %d, response: %s", response.StatusCode, string(body)))
 		}
 
-		request, err := http.NewRequest(http.MethodPut, uploadURL, reqBody)
-		if err != nil {
-			return fmt.Errorf("failed to create request, error: %s", err)
+		if err := json.Unmarshal(body, &renewed); err != nil {
+			return fmt.Errorf("failed to unmarshal response (%s), error: %s", string(body), err)
+		}
+		if renewed.ErrorMessage != "" {
+			return fmt.Errorf("failed to renew upload url, error message: %s", renewed.ErrorMessage)
+		}
+		if renewed.URL == "" {
+			return fmt.Errorf("failed to renew upload url, error: missing upload url")
 		}
 
-		if contentType != "" {
-			request.Header.Add("Content-Type", contentType)
+		return nil
+	}); err != nil {
+		return UploadTask{}, err
+	}
+
+	renewed.ID = task.ID
+	renewed.IsIntermediate = task.IsIntermediate
+
+	return renewed, nil
+}
+
+// UploadArtifact ...
+// The checksums are calculated while the file is streamed to the storage. Once an attempt read the whole file,
+// the following attempts send them in the Content-MD5 header. The first attempt is sent without Content-MD5,
+// so the storage can't reject its corrupted content: only the stored MD5 check after the upload protects it.
+func (c *BitriseArtifactClient) UploadArtifact(ctx context.Context, uploadURL string, artifact ArtifactArgs, contentType string) (TransferDetails, error) {
+	start := time.Now()
+
+	var checksums Checksums
+	var lastErr error
+	err := c.retryPolicy.Do(ctx, func(attempt uint) error {
+		var offset int64
+		var mismatchErr *ChecksumMismatchError
+		if errors.As(lastErr, &mismatchErr) {
+			// The stored content can't be trusted, restart the upload and calculate the file's current checksums.
+			checksums = Checksums{}
+		} else if attempt > 0 && artifact.FileSize > 0 && isResumableSession(uploadURL) {
+			committed, err := queryCommittedBytes(ctx, c.httpClient, uploadURL, artifact.FileSize)
+			if err != nil {
+				log.Warnf("Restarting upload from the beginning: %s", err)
+			} else if committed < artifact.FileSize {
+				log.Printf("Resuming upload from %s", units.BytesSize(float64(committed)))
+				offset = committed
+			}
 		}
 
-		request.Header.Add("X-Upload-Content-Length", strconv.FormatInt(artifact.FileSize, 10)) // header used by Google Cloud Storage signed URLs
-		request.ContentLength = artifact.FileSize
+		checksums, lastErr = c.uploadArtifactFrom(ctx, uploadURL, artifact, contentType, checksums, offset)
+		return lastErr
+	})
 
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-		defer cancel()
-		request = request.WithContext(ctx)
+	duration := time.Since(start)
+	details := TransferDetails{
+		Size:       artifact.FileSize,
+		Duration:   duration,
+		Hostname:   extractHost(uploadURL),
+		Checksums:  checksums,
+		Throughput: throughput(artifact.FileSize, duration),
+	}
 
-		resp, err := netClient.Do(request)
-		if err != nil {
-			return fmt.Errorf("failed to upload artifact, error: %s", err)
+	return details, err
+}
+
+// uploadArtifactFrom uploads the artifact's content starting at the given byte offset, and returns the checksums of the file
+// if it was read completely, otherwise the given checksums of a previous attempt.
+// The whole file is hashed: the committed part of a resumed upload is read first, the rest is hashed while it is sent.
+// Full uploads are sent with the Content-MD5 header of the previous attempt's checksums, if there are any,
+// resumed uploads are verified against the checksum of the whole object reported by the storage.
+func (c *BitriseArtifactClient) uploadArtifactFrom(ctx context.Context, uploadURL string, artifact ArtifactArgs, contentType string, checksums Checksums, offset int64) (Checksums, error) {
+	file, err := os.Open(artifact.Path)
+	if err != nil {
+		return checksums, fmt.Errorf("failed to open artifact, error: %s", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warnf("failed to close file, error: %s", err)
 		}
+	}()
 
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				log.Errorf("Failed to close response body, error: %s", err)
-			}
-		}()
+	contentReader := newChecksumReader(io.NewSectionReader(file, 0, artifact.FileSize))
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, contentReader, offset); err != nil {
+			return checksums, fmt.Errorf("failed to read artifact, error: %s", err)
+		}
+	}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read response body, error: %s", err)
+	contentLength := artifact.FileSize - offset
+
+	// Initializes request body to nil to send a Content-Length of 0: https://github.com/golang/go/issues/20257#issuecomment-299509391
+	var reqBody io.Reader
+	if contentLength > 0 {
+		reqBody = io.NopCloser(newProgressReader(contentReader, filepath.Base(artifact.Path), offset, artifact.FileSize))
+	}
+
+	header := http.Header{}
+	if offset > 0 {
+		header.Add("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, artifact.FileSize-1, artifact.FileSize))
+	} else if contentMD5 := checksums.contentMD5(); contentMD5 != "" {
+		header.Add("Content-MD5", contentMD5)
+	}
+
+	respHeader, err := c.putContent(ctx, uploadURL, reqBody, contentLength, artifact.FileSize, contentType, header)
+	if contentReader.size() != artifact.FileSize {
+		if err == nil {
+			err = fmt.Errorf("failed to upload artifact, %d of %d bytes were sent: %w", contentReader.size()-offset, contentLength, io.ErrUnexpectedEOF)
 		}
+		return checksums, err
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("non success status code: %d, headers: %s, body: %s", resp.StatusCode, resp.Header, body)
+	uploaded := contentReader.checksums()
+	if err != nil {
+		if checksums == (Checksums{}) {
+			return uploaded, err
 		}
+		return checksums, err
+	}
 
-		return nil
-	})
+	if checksums != (Checksums{}) && uploaded != checksums {
+		return Checksums{}, &ChecksumMismatchError{Reason: fmt.Sprintf("file changed during upload, sha256 before: %s, uploaded: %s", checksums.SHA256, uploaded.SHA256)}
+	}
 
-	details := TransferDetails{
-		Size:     artifact.FileSize,
-		Duration: time.Since(start),
-		Hostname: extractHost(uploadURL),
+	// GCS reports the MD5 of the stored object, it is required to trust a resumed upload.
+	if stored := storedMD5(respHeader); (stored != "" || offset > 0) && stored != uploaded.contentMD5() {
+		return Checksums{}, &ChecksumMismatchError{Reason: fmt.Sprintf("stored object's md5 is %q, uploaded: %q", stored, uploaded.contentMD5())}
 	}
 
-	return details, err
+	return uploaded, nil
 }
 
-func finishArtifact(buildURL, token, artifactID string, appDeploymentMeta *AppDeploymentMetaData) (ArtifactURLs, error) {
+// putContent sends contentLength bytes of an artifact of fileSize bytes to the upload URL, and returns the headers of the response.
+func (c *BitriseArtifactClient) putContent(ctx context.Context, uploadURL string, body io.Reader, contentLength, fileSize int64, contentType string, header http.Header) (http.Header, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request, error: %s", err)
+	}
+
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	if contentType != "" {
+		request.Header.Add("Content-Type", contentType)
+	}
+
+	request.Header.Add("X-Upload-Content-Length", strconv.FormatInt(fileSize, 10)) // header used by Google Cloud Storage signed URLs
+	request.ContentLength = contentLength
+
+	resp, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload artifact, error: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Failed to close response body, error: %s", err)
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body, error: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusBadRequest && isDigestError(string(respBody)) {
+		return nil, &ChecksumMismatchError{Reason: fmt.Sprintf("storage rejected the uploaded content: %s", respBody)}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err := retrypolicy.NewHTTPError(resp, fmt.Errorf("non success status code: %d, headers: %s, body: %s", resp.StatusCode, resp.Header, respBody))
+		if isExpiryResponse(resp.StatusCode, string(respBody), uploadURL, time.Now()) {
+			return nil, &UploadURLExpiredError{Err: err}
+		}
+		return nil, err
+	}
+
+	return resp.Header, nil
+}
+
+// isDigestError reports whether the storage's error response is about a Content-MD5 mismatch (GCS and S3 error codes).
+func isDigestError(body string) bool {
+	return strings.Contains(body, "BadDigest") || strings.Contains(body, "InvalidDigest")
+}
+
+// FinishArtifact ...
+func (c *BitriseArtifactClient) FinishArtifact(ctx context.Context, buildURL, token, artifactID string, appDeploymentMeta *AppDeploymentMetaData, checksums Checksums) (ArtifactURLs, error) {
 	// create form data
 	data := url.Values{"api_token": {token}}
+	if checksums.SHA256 != "" {
+		data["sha256_checksum"] = []string{checksums.SHA256}
+	}
+	if checksums.MD5 != "" {
+		data["md5_checksum"] = []string{checksums.MD5}
+	}
 	if appDeploymentMeta != nil {
-		var artifactInfoBytes []byte
-		var err error
-		if appDeploymentMeta.IOSArtifactInfo != nil {
-			artifactInfoBytes, err = json.Marshal(appDeploymentMeta.IOSArtifactInfo)
-		} else if appDeploymentMeta.AndroidArtifactInfo != nil {
-			artifactInfoBytes, err = json.Marshal(appDeploymentMeta.AndroidArtifactInfo)
-		} else {
-			err = fmt.Errorf("artifact metadata is missing")
-		}
+		artifactInfo, err := artifactInfoPayload(appDeploymentMeta)
 		if err != nil {
 			return ArtifactURLs{}, fmt.Errorf("failed to marshal app deployment meta: %s", err)
 		}
-		artifactInfo := string(artifactInfoBytes)
 
 		if artifactInfo != "" {
 			data["artifact_info"] = []string{artifactInfo}
@@ -284,14 +456,26 @@ func finishArtifact(buildURL, token, artifactID string, appDeploymentMeta *AppDe
 		InvalidEmails        []string `json:"invalid_emails"`
 	}
 
+	// The retries of a finish request are sent with the same idempotency key. The backend doesn't document handling it,
+	// so the key is only a hint: a retry after a lost response might be rejected as a conflict or might notify the users again.
+	idempotencyKey, err := newIdempotencyKey(artifactID)
+	if err != nil {
+		return ArtifactURLs{}, err
+	}
+
 	var artifactResponse finishArtifactResponse
-	if err := retry.Times(3).Wait(5 * time.Second).Try(func(attempt uint) error {
+	// mayBeFinished is set once an attempt could have finished the upload without the Step knowing about it,
+	// after that the artifact record must not be aborted.
+	mayBeFinished := false
+	alreadyFinished := false
+	if err := c.retryPolicy.Do(ctx, func(attempt uint) error {
 		if attempt > 0 {
 			log.Warnf("%d attempt failed", attempt)
 		}
-		response, err = http.PostForm(uri, data)
+		response, err = c.postForm(ctx, uri, data, http.Header{idempotencyKeyHeader: {idempotencyKey}})
 		if err != nil {
-			return fmt.Errorf("failed to perform finish artifact request, error: %s", err)
+			mayBeFinished = mayBeFinished || !isRequestNotSent(err)
+			return fmt.Errorf("failed to perform finish artifact request, error: %w", err)
 		}
 		defer func() {
 			if err := response.Body.Close(); err != nil {
@@ -302,32 +486,165 @@ func finishArtifact(buildURL, token, artifactID string, appDeploymentMeta *AppDe
 		// process response
 		body, err := io.ReadAll(response.Body)
 		if err != nil {
-			return fmt.Errorf("failed to read finish artifact response, error: %s", err)
+			mayBeFinished = mayBeFinished || response.StatusCode == http.StatusOK
+			return fmt.Errorf("failed to read finish artifact response, error: %w", err)
+		}
+		if response.StatusCode == http.StatusConflict && mayBeFinished {
+			// A previous attempt finished the upload, but its response was lost, so the retry is rejected as a conflict.
+			// The artifact's URLs are read from the conflict response if it has them.
+			alreadyFinished = true
+			_ = json.Unmarshal(body, &artifactResponse)
+			return nil
 		}
 		if response.StatusCode != http.StatusOK {
-			return fmt.Errorf("failed to create artifact on bitrise, status code: %d, response: %s", response.StatusCode, string(body))
+			// A gateway error doesn't tell whether the backend processed the request.
+			mayBeFinished = mayBeFinished || response.StatusCode == http.StatusBadGateway || response.StatusCode == http.StatusGatewayTimeout
+			return retrypolicy.NewHTTPError(response, fmt.Errorf("failed to finish artifact upload on bitrise, status code: %d, response: %s", response.StatusCode, string(body)))
 		}
 
 		if err := json.Unmarshal(body, &artifactResponse); err != nil {
+			mayBeFinished = true
 			return fmt.Errorf("failed to unmarshal response (%s), error: %s", string(body), err)
 		}
 
 		return nil
 	}); err != nil {
+		if mayBeFinished {
+			return ArtifactURLs{}, FinishOutcomeUnknownError{ArtifactID: artifactID, Err: err}
+		}
 		return ArtifactURLs{}, err
 	}
 
+	if alreadyFinished {
+		if artifactResponse.PermanentDownloadURL == "" && artifactResponse.DetailsPageURL == "" {
+			log.Warnf("Artifact %s was already finished by a previous attempt, its URLs are not available", artifactID)
+		} else {
+			log.Warnf("Artifact %s was already finished by a previous attempt", artifactID)
+		}
+	}
+
 	if len(artifactResponse.InvalidEmails) > 0 {
 		log.Warnf("Invalid e-mail addresses: %s", strings.Join(artifactResponse.InvalidEmails, ", "))
 	}
 
 	return ArtifactURLs{
+		ArtifactID:           artifactID,
 		PermanentDownloadURL: artifactResponse.PermanentDownloadURL,
 		DetailsPageURL:       artifactResponse.DetailsPageURL,
 		PublicInstallPageURL: artifactResponse.PublicInstallPageURL,
+		Checksums:            checksums,
 	}, nil
 }
 
+// artifactInfoPayload returns the JSON encoded artifact info: the parsed app metadata extended with the custom metadata
+// and the artifact the file was generated from.
+func artifactInfoPayload(appDeploymentMeta *AppDeploymentMetaData) (string, error) {
+	extensions := map[string]interface{}{}
+	if len(appDeploymentMeta.CustomMetadata) > 0 {
+		extensions[customMetadataKey] = appDeploymentMeta.CustomMetadata
+	}
+	if appDeploymentMeta.GeneratedFrom != "" {
+		extensions[generatedFromKey] = appDeploymentMeta.GeneratedFrom
+	}
+
+	var appInfo interface{}
+	if appDeploymentMeta.IOSArtifactInfo != nil {
+		appInfo = appDeploymentMeta.IOSArtifactInfo
+	} else if appDeploymentMeta.AndroidArtifactInfo != nil {
+		appInfo = appDeploymentMeta.AndroidArtifactInfo
+	} else if appDeploymentMeta.MacOSArtifactInfo != nil {
+		appInfo = appDeploymentMeta.MacOSArtifactInfo
+	} else if appDeploymentMeta.DebugSymbolsInfo != nil {
+		appInfo = appDeploymentMeta.DebugSymbolsInfo
+	} else if len(extensions) == 0 {
+		return "", fmt.Errorf("artifact metadata is missing")
+	}
+
+	if len(extensions) == 0 {
+		artifactInfoBytes, err := json.Marshal(appInfo)
+		return string(artifactInfoBytes), err
+	}
+
+	artifactInfo := map[string]interface{}{}
+	if appInfo != nil {
+		appInfoBytes, err := json.Marshal(appInfo)
+		if err != nil {
+			return "", err
+		}
+		if err := json.Unmarshal(appInfoBytes, &artifactInfo); err != nil {
+			return "", err
+		}
+	}
+	for key, value := range extensions {
+		artifactInfo[key] = value
+	}
+
+	artifactInfoBytes, err := json.Marshal(artifactInfo)
+	return string(artifactInfoBytes), err
+}
+
+// AbortArtifact ...
+func (c *BitriseArtifactClient) AbortArtifact(ctx context.Context, buildURL, token, artifactID string) error {
+	uri, err := urlutil.Join(buildURL, "artifacts", artifactID, "abort_upload.json")
+	if err != nil {
+		return fmt.Errorf("failed to generate abort artifact url, error: %s", err)
+	}
+
+	return c.retryPolicy.Do(ctx, func(attempt uint) error {
+		if attempt > 0 {
+			log.Warnf("%d attempt failed", attempt)
+		}
+		response, err := c.postForm(ctx, uri, url.Values{"api_token": {token}}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to perform abort artifact request, error: %w", err)
+		}
+		defer func() {
+			if err := response.Body.Close(); err != nil {
+				log.Errorf("Failed to close reponse body, error: %s", err)
+			}
+		}()
+
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read abort artifact response, error: %w", err)
+		}
+		// A backend without the abort endpoint answers with 404 (Not Found), 405 (Method Not Allowed) or 501 (Not Implemented),
+		// the record can't be cleaned up there, but it isn't a failed cleanup either.
+		switch response.StatusCode {
+		case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return fmt.Errorf("%w, status code: %d", ErrAbortUnsupported, response.StatusCode)
+		}
+		if response.StatusCode != http.StatusOK {
+			return retrypolicy.NewHTTPError(response, fmt.Errorf("failed to abort artifact, status code: %d, response: %s", response.StatusCode, string(body)))
+		}
+
+		return nil
+	})
+}
+
+// newIdempotencyKey returns a random key, which identifies the retries of the same request.
+func newIdempotencyKey(artifactID string) (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return artifactID + "-" + hex.EncodeToString(key), nil
+}
+
+// postForm is the context aware version of http.PostForm, the optional header is added to the request.
+func (c *BitriseArtifactClient) postForm(ctx context.Context, uri string, data url.Values, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return c.httpClient.Do(request)
+}
+
 func printableAppInfo(appInfo interface{}) string {
 	bytes, err := json.Marshal(appInfo)
 	if err != nil {
@@ -1,6 +1,14 @@
 package uploaders
 
 import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"image"
 	"image/png"
 	"io"
@@ -10,8 +18,15 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
+	iosparser "github.com/bitrise-io/go-xcode/v2/metaparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
 	"github.com/stretchr/testify/require"
 )
 
@@ -98,9 +113,521 @@ func Test_uploadArtifact(t *testing.T) {
 				Path:     tt.artifactPth,
 				FileSize: fileInfo.Size(),
 			}
-			if _, err := UploadArtifact(tt.uploadURL, artifact, tt.contentType); (err != nil) != tt.wantErr {
+			if _, err := newTestArtifactClient().UploadArtifact(context.Background(), tt.uploadURL, artifact, tt.contentType); (err != nil) != tt.wantErr {
 				t.Errorf("UploadArtifact() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
+
+func Test_uploadArtifact_resumesAfterDroppedConnection(t *testing.T) {
+	content := make([]byte, 1024*1024)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	contentMD5 := md5.Sum(content)
+
+	testFilePath := filepath.Join(t.TempDir(), "artifact.ipa")
+	require.NoError(t, os.WriteFile(testFilePath, content, 0600))
+
+	tests := []struct {
+		name             string
+		uploadPath       string
+		supportsResume   bool
+		corruptResume    bool
+		wantProbes       int
+		wantUploadedFrom []int64
+	}{
+		{
+			name:             "Resumes a resumable session from the committed offset",
+			uploadPath:       "/upload?upload_id=session-id",
+			supportsResume:   true,
+			wantProbes:       1,
+			wantUploadedFrom: []int64{0, int64(len(content)) / 2},
+		},
+		{
+			name:             "Restarts from the beginning if resuming is not supported",
+			uploadPath:       "/upload?upload_id=session-id",
+			supportsResume:   false,
+			wantProbes:       1,
+			wantUploadedFrom: []int64{0, 0},
+		},
+		{
+			name:             "Doesn't probe plain signed URLs",
+			uploadPath:       "/artifact.ipa?X-Goog-Signature=abc",
+			supportsResume:   true,
+			wantProbes:       0,
+			wantUploadedFrom: []int64{0, 0},
+		},
+		{
+			name:             "Restarts from the beginning if the resumed object's checksum doesn't match",
+			uploadPath:       "/upload?upload_id=session-id",
+			supportsResume:   true,
+			corruptResume:    true,
+			wantProbes:       1,
+			wantUploadedFrom: []int64{0, int64(len(content)) / 2, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored []byte
+			var uploadedFrom []int64
+			probes := 0
+			storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contentRange := r.Header.Get("Content-Range")
+
+				if contentRange == fmt.Sprintf("bytes */%d", len(content)) {
+					probes++
+					if !tt.supportsResume {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					if len(stored) > 0 {
+						w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(stored)-1))
+					}
+					w.WriteHeader(statusResumeIncomplete)
+					return
+				}
+
+				var offset int64
+				if contentRange != "" {
+					_, err := fmt.Sscanf(contentRange, "bytes %d-", &offset)
+					require.NoError(t, err)
+				}
+				uploadedFrom = append(uploadedFrom, offset)
+
+				if len(uploadedFrom) == 1 {
+					// Simulate a dropped connection after the storage persisted the first half of the file.
+					half := make([]byte, len(content)/2)
+					_, err := io.ReadFull(r.Body, half)
+					require.NoError(t, err)
+					stored = half
+
+					conn, _, err := w.(http.Hijacker).Hijack()
+					require.NoError(t, err)
+					require.NoError(t, conn.Close())
+					return
+				}
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				stored = append(stored[:offset], body...)
+				if tt.corruptResume && offset > 0 {
+					stored[0] ^= 0xff
+				}
+
+				storedMD5 := md5.Sum(stored)
+				w.Header().Set("X-Goog-Hash", "crc32c=AAAAAA==,md5="+base64.StdEncoding.EncodeToString(storedMD5[:]))
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer storage.Close()
+
+			artifact := ArtifactArgs{
+				Path:     testFilePath,
+				FileSize: int64(len(content)),
+			}
+			_, err := newTestArtifactClient().UploadArtifact(context.Background(), storage.URL+tt.uploadPath, artifact, "")
+			require.NoError(t, err)
+			require.Equal(t, tt.wantProbes, probes)
+			require.Equal(t, tt.wantUploadedFrom, uploadedFrom)
+			require.Equal(t, md5.Sum(stored), contentMD5)
+		})
+	}
+}
+
+func Test_storedMD5(t *testing.T) {
+	header := http.Header{}
+	require.Equal(t, "", storedMD5(header))
+
+	header.Add("X-Goog-Hash", "crc32c=n03x6A==")
+	header.Add("X-Goog-Hash", "md5=Ojk9c3dhfxgoKVVHYwFbHQ==")
+	require.Equal(t, "Ojk9c3dhfxgoKVVHYwFbHQ==", storedMD5(header))
+
+	header = http.Header{}
+	header.Set("X-Goog-Hash", "crc32c=n03x6A==, md5=Ojk9c3dhfxgoKVVHYwFbHQ==")
+	require.Equal(t, "Ojk9c3dhfxgoKVVHYwFbHQ==", storedMD5(header))
+}
+
+func Test_parseCommittedBytes(t *testing.T) {
+	tests := []struct {
+		name        string
+		rangeHeader string
+		want        int64
+		wantErr     bool
+	}{
+		{name: "No bytes persisted", rangeHeader: "", want: 0},
+		{name: "Some bytes persisted", rangeHeader: "bytes=0-1023", want: 1024},
+		{name: "Invalid range", rangeHeader: "bytes=10-1023", wantErr: true},
+		{name: "Invalid value", rangeHeader: "bytes=0-abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCommittedBytes(tt.rangeHeader)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_isExpiryResponse(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	s3URL := "https://bucket.s3.amazonaws.com/file?X-Amz-Date=20240501T100000Z&X-Amz-Expires=3600&X-Amz-Signature=abc"
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		uploadURL  string
+		want       bool
+	}{
+		{name: "S3 expiry message", statusCode: http.StatusForbidden, body: "<Message>Request has expired</Message>", uploadURL: "https://storage/file", want: true},
+		{name: "GCS expired token", statusCode: http.StatusBadRequest, body: "<Code>ExpiredToken</Code>", uploadURL: "https://storage/file", want: true},
+		{name: "Azure SAS time frame", statusCode: http.StatusForbidden, body: "Signature not valid in the specified time frame", uploadURL: "https://storage/file", want: true},
+		{name: "Expired S3 V4 URL", statusCode: http.StatusForbidden, body: "<Code>AccessDenied</Code>", uploadURL: s3URL, want: true},
+		{name: "Valid S3 V4 URL", statusCode: http.StatusForbidden, body: "<Code>AccessDenied</Code>", uploadURL: strings.Replace(s3URL, "X-Amz-Expires=3600", "X-Amz-Expires=86400", 1), want: false},
+		{name: "Expired GCS V2 URL", statusCode: http.StatusForbidden, uploadURL: "https://storage.googleapis.com/file?Expires=1714550400", want: true},
+		{name: "Expired Azure SAS URL", statusCode: http.StatusForbidden, uploadURL: "https://account.blob.core.windows.net/file?se=2024-05-01T11%3A00%3A00Z&sig=abc", want: true},
+		{name: "Server error", statusCode: http.StatusInternalServerError, body: "token expired", uploadURL: s3URL, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, isExpiryResponse(tt.statusCode, tt.body, tt.uploadURL, now))
+		})
+	}
+}
+
+func Test_uploadArtifact_checksums(t *testing.T) {
+	content := []byte("test artifact content")
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, content, 0600))
+
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	wantChecksums := Checksums{
+		SHA256: hex.EncodeToString(sha256Sum[:]),
+		MD5:    hex.EncodeToString(md5Sum[:]),
+	}
+
+	contentMD5 := base64.StdEncoding.EncodeToString(md5Sum[:])
+
+	type storageResponse struct {
+		statusCode int
+		body       string
+		storedMD5  string
+	}
+	tests := []struct {
+		name           string
+		responses      []storageResponse
+		wantContentMD5 []string
+		wantErr        bool
+	}{
+		{
+			name:           "Calculates the checksums while the file is streamed",
+			responses:      []storageResponse{{statusCode: http.StatusOK}},
+			wantContentMD5: []string{""},
+		},
+		{
+			name:           "Sends Content-MD5 on the retries of a completely read file",
+			responses:      []storageResponse{{statusCode: http.StatusInternalServerError}, {statusCode: http.StatusOK}},
+			wantContentMD5: []string{"", contentMD5},
+		},
+		{
+			name: "Retries a digest mismatch reported by the storage",
+			responses: []storageResponse{
+				{statusCode: http.StatusInternalServerError},
+				{statusCode: http.StatusBadRequest, body: "<Error><Code>BadDigest</Code></Error>"},
+				{statusCode: http.StatusOK},
+			},
+			wantContentMD5: []string{"", contentMD5, ""},
+		},
+		{
+			name:           "Retries if the stored object's checksum doesn't match",
+			responses:      []storageResponse{{statusCode: http.StatusOK, storedMD5: "invalid"}, {statusCode: http.StatusOK, storedMD5: contentMD5}},
+			wantContentMD5: []string{"", ""},
+		},
+		{
+			name:           "Returns a typed error if the stored object's checksum keeps mismatching",
+			responses:      []storageResponse{{statusCode: http.StatusOK, storedMD5: "invalid"}},
+			wantContentMD5: []string{"", "", "", ""},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotContentMD5 []string
+			storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				require.Equal(t, content, body)
+
+				gotContentMD5 = append(gotContentMD5, r.Header.Get("Content-MD5"))
+				response := tt.responses[min(len(gotContentMD5), len(tt.responses))-1]
+				if response.storedMD5 != "" {
+					w.Header().Set("X-Goog-Hash", "md5="+response.storedMD5)
+				}
+				w.WriteHeader(response.statusCode)
+				_, err = w.Write([]byte(response.body))
+				require.NoError(t, err)
+			}))
+			defer storage.Close()
+
+			artifact := ArtifactArgs{
+				Path:     testFilePath,
+				FileSize: int64(len(content)),
+			}
+			details, err := newTestArtifactClient().UploadArtifact(context.Background(), storage.URL, artifact, "")
+			require.Equal(t, tt.wantContentMD5, gotContentMD5)
+			if tt.wantErr {
+				var mismatchErr *ChecksumMismatchError
+				require.True(t, errors.As(err, &mismatchErr))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, wantChecksums, details.Checksums)
+		})
+	}
+}
+
+func Test_uploadArtifact_cancelledContext(t *testing.T) {
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, []byte("content"), 0600))
+
+	attempts := 0
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer storage.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	artifact := ArtifactArgs{
+		Path:     testFilePath,
+		FileSize: 7,
+	}
+	_, err := newTestArtifactClient().UploadArtifact(ctx, storage.URL, artifact, "")
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 0, attempts)
+}
+
+func newTestArtifactClient() *BitriseArtifactClient {
+	return NewArtifactClient(&http.Client{}, retrypolicy.Policy{
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   time.Millisecond,
+	})
+}
+
+func Test_uploadArtifact_permanentError(t *testing.T) {
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, []byte("content"), 0600))
+
+	attempts := 0
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer storage.Close()
+
+	artifact := ArtifactArgs{
+		Path:     testFilePath,
+		FileSize: 7,
+	}
+	_, err := newTestArtifactClient().UploadArtifact(context.Background(), storage.URL, artifact, "")
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func Test_artifactInfoPayload(t *testing.T) {
+	customMetadata := map[string]interface{}{"release_channel": "beta"}
+
+	got, err := artifactInfoPayload(&AppDeploymentMetaData{CustomMetadata: customMetadata})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"custom_metadata": {"release_channel": "beta"}}`, got)
+
+	got, err = artifactInfoPayload(&AppDeploymentMetaData{
+		IOSArtifactInfo: &iosparser.ArtifactMetadata{FileSizeBytes: 10},
+		CustomMetadata:  customMetadata,
+	})
+	require.NoError(t, err)
+	var artifactInfo map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got), &artifactInfo))
+	require.Equal(t, float64(10), artifactInfo["file_size_bytes"])
+	require.Equal(t, customMetadata, artifactInfo["custom_metadata"])
+
+	got, err = artifactInfoPayload(&AppDeploymentMetaData{
+		MacOSArtifactInfo: &macosparser.ArtifactMetadata{
+			AppInfo:     macosparser.Info{BundleID: "io.bitrise.example", MinOSVersion: "12.0"},
+			SigningInfo: macosparser.SigningInfo{SigningIdentity: "Developer ID Application: Example Inc. (TEAM123456)", TeamID: "TEAM123456"},
+		},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"app_info": {"app_title": "", "bundle_id": "io.bitrise.example", "version": "", "build_number": "", "min_OS_version": "12.0"},
+		"file_size_bytes": 0,
+		"signing_info": {"signing_identity": "Developer ID Application: Example Inc. (TEAM123456)", "team_id": "TEAM123456"}
+	}`, got)
+
+	got, err = artifactInfoPayload(&AppDeploymentMetaData{
+		DebugSymbolsInfo: &debugsymbols.Metadata{
+			SymbolsType:      debugsymbols.DSYM,
+			ParentArtifactID: "artifact-id",
+			ParentFileName:   "MyApp.ipa",
+			FileSizeBytes:    10,
+			UUIDs:            []debugsymbols.UUID{{UUID: "00112233-4455-6677-8899-AABBCCDDEEFF", Arch: "arm64", Binary: "MyApp"}},
+		},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"symbols_type": "dsym",
+		"parent_artifact_id": "artifact-id",
+		"parent_file_name": "MyApp.ipa",
+		"file_size_bytes": 10,
+		"uuids": [{"uuid": "00112233-4455-6677-8899-AABBCCDDEEFF", "arch": "arm64", "binary": "MyApp"}]
+	}`, got)
+
+	got, err = artifactInfoPayload(&AppDeploymentMetaData{
+		AndroidArtifactInfo: &androidparser.ArtifactMetadata{FileSizeBytes: 10},
+		GeneratedFrom:       "app-release.aab",
+	})
+	require.NoError(t, err)
+	artifactInfo = map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(got), &artifactInfo))
+	require.Equal(t, float64(10), artifactInfo["file_size_bytes"])
+	require.Equal(t, "app-release.aab", artifactInfo["generated_from_file_name"])
+
+	_, err = artifactInfoPayload(&AppDeploymentMetaData{})
+	require.EqualError(t, err, "artifact metadata is missing")
+}
+
+func Test_finishArtifact_retryAfterLostResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		conflictBody string
+		wantURLs     ArtifactURLs
+	}{
+		{
+			name:         "Conflict for the finished artifact with its URLs",
+			conflictBody: `{"permanent_download_url":"https://app.bitrise.io/artifacts/1/download"}`,
+			wantURLs:     ArtifactURLs{ArtifactID: "1", PermanentDownloadURL: "https://app.bitrise.io/artifacts/1/download"},
+		},
+		{
+			name:         "Conflict for the finished artifact without its URLs",
+			conflictBody: `{"error_msg":"artifact is already finished"}`,
+			wantURLs:     ArtifactURLs{ArtifactID: "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			notifications := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+				if len(keys) > 1 {
+					w.WriteHeader(http.StatusConflict)
+					_, _ = w.Write([]byte(tt.conflictBody))
+					return
+				}
+
+				// The artifact is finished and the users are notified, but the response is lost.
+				notifications++
+				conn, _, err := w.(http.Hijacker).Hijack()
+				require.NoError(t, err)
+				require.NoError(t, conn.Close())
+			}))
+			defer server.Close()
+
+			urls, err := newTestArtifactClient().FinishArtifact(context.Background(), server.URL, "token", "1", nil, Checksums{})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantURLs, urls)
+			require.Equal(t, 1, notifications)
+			require.Len(t, keys, 2)
+			require.NotEmpty(t, keys[0])
+			require.Equal(t, keys[0], keys[1])
+		})
+	}
+}
+
+func Test_finishArtifact_outcome(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantUnknown bool
+	}{
+		{
+			name: "Rejected request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+		},
+		{
+			name: "Conflict without a lost response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+			},
+		},
+		{
+			name: "Invalid response of a finished upload",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("<html>"))
+			},
+			wantUnknown: true,
+		},
+		{
+			name: "Gateway timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusGatewayTimeout)
+			},
+			wantUnknown: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			_, err := newTestArtifactClient().FinishArtifact(context.Background(), server.URL, "token", "1", nil, Checksums{})
+			require.Error(t, err)
+			require.Equal(t, tt.wantUnknown, errors.As(err, &FinishOutcomeUnknownError{}))
+		})
+	}
+
+	// The request didn't reach the server.
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	_, err := newTestArtifactClient().FinishArtifact(context.Background(), server.URL, "token", "1", nil, Checksums{})
+	require.Error(t, err)
+	require.False(t, errors.As(err, &FinishOutcomeUnknownError{}))
+}
+
+func Test_abortArtifact(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/artifacts/1/abort_upload.json":
+			w.WriteHeader(http.StatusOK)
+		case "/artifacts/2/abort_upload.json":
+			w.WriteHeader(http.StatusNotFound)
+		case "/artifacts/3/abort_upload.json":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestArtifactClient()
+	require.NoError(t, client.AbortArtifact(context.Background(), server.URL, "token", "1"))
+	// A backend without the abort endpoint can't clean up the record.
+	require.ErrorIs(t, client.AbortArtifact(context.Background(), server.URL, "token", "2"), ErrAbortUnsupported)
+	require.ErrorIs(t, client.AbortArtifact(context.Background(), server.URL, "token", "3"), ErrAbortUnsupported)
+	err := client.AbortArtifact(context.Background(), server.URL, "token", "4")
+	require.ErrorContains(t, err, "status code: 403")
+	require.False(t, errors.Is(err, ErrAbortUnsupported))
+	require.Equal(t, []string{"/artifacts/1/abort_upload.json", "/artifacts/2/abort_upload.json", "/artifacts/3/abort_upload.json", "/artifacts/4/abort_upload.json"}, paths)
+}
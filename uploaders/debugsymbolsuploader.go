@@ -0,0 +1,41 @@
+package uploaders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+)
+
+// DeployDebugSymbols deploys a validated debug symbols file, its metadata references the artifact the symbols belong to.
+func (u *Uploader) DeployDebugSymbols(ctx context.Context, item deployment.DeployableItem, symbolsInfo debugsymbols.Metadata, buildURL, token string) ([]ArtifactURLs, error) {
+	pth := item.Path
+
+	fileSize, err := u.fileManager.FileSizeInBytes(pth)
+	if err != nil {
+		return nil, fmt.Errorf("get file size: %w", err)
+	}
+	symbolsInfo.FileSizeBytes = fileSize
+
+	u.logger.Printf("%s infos: %+v", symbolsInfo.SymbolsType, printableAppInfo(symbolsInfo))
+
+	contentType := "application/zip"
+	if symbolsInfo.SymbolsType == debugsymbols.ProguardMapping {
+		contentType = "text/plain"
+	}
+	artifact := ArtifactArgs{
+		Path:     pth,
+		FileSize: fileSize,
+	}
+	buildArtifactMeta := AppDeploymentMetaData{
+		DebugSymbolsInfo: &symbolsInfo,
+	}
+
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "debug-symbols", contentType, &item, &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed %s deploy: %w", symbolsInfo.SymbolsType, err)
+	}
+
+	return urLs, nil
+}
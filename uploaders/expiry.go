@@ -0,0 +1,104 @@
+package uploaders
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+)
+
+// maxUploadURLRenewals is the number of times an expired upload URL of an upload task is replaced with a new one.
+const maxUploadURLRenewals = 2
+
+// ErrRenewalUnsupported is returned by RenewUploadTask if the backend can't renew upload URLs.
+var ErrRenewalUnsupported = errors.New("renewal of upload URLs is not supported")
+
+// UploadURLExpiredError is returned when the storage rejects an upload because its signed upload URL expired,
+// the upload can continue with a renewed URL of the same upload task.
+type UploadURLExpiredError struct {
+	Err error
+}
+
+func (e *UploadURLExpiredError) Error() string {
+	return fmt.Sprintf("upload URL expired: %s", e.Err)
+}
+
+func (e *UploadURLExpiredError) Unwrap() error {
+	return e.Err
+}
+
+// FailureReason categorizes the expiry as a timeout, as the upload took longer than the validity of its URL.
+func (e *UploadURLExpiredError) FailureReason() failure.Reason {
+	return failure.Timeout
+}
+
+// isUploadURLExpired reports whether the error is caused by an expired upload URL.
+func isUploadURLExpired(err error) bool {
+	var expiredErr *UploadURLExpiredError
+	return errors.As(err, &expiredErr)
+}
+
+// isExpiryResponse reports whether a rejected storage response is caused by the expiry of the signed upload URL:
+// either the storage says so (S3, GCS and Azure error messages), or the URL's own expiry time has passed.
+func isExpiryResponse(statusCode int, body, uploadURL string, now time.Time) bool {
+	if statusCode != http.StatusBadRequest && statusCode != http.StatusUnauthorized && statusCode != http.StatusForbidden {
+		return false
+	}
+
+	lowerBody := strings.ToLower(body)
+	if strings.Contains(lowerBody, "expired") || strings.Contains(lowerBody, "not valid in the specified time frame") {
+		return true
+	}
+
+	expiry, ok := signedURLExpiry(uploadURL)
+	return ok && !now.Before(expiry)
+}
+
+// signedURLExpiry returns the expiry time of an S3, GCS (V2 and V4 signatures) or Azure SAS signed URL.
+func signedURLExpiry(uploadURL string) (time.Time, bool) {
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	query := u.Query()
+
+	for _, prefix := range []string{"X-Amz-", "X-Goog-"} {
+		date, expires := query.Get(prefix+"Date"), query.Get(prefix+"Expires")
+		if date == "" || expires == "" {
+			continue
+		}
+
+		signedAt, err := time.Parse("20060102T150405Z", date)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return signedAt.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	if expires := query.Get("Expires"); expires != "" {
+		seconds, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(seconds, 0), true
+	}
+
+	if expires := query.Get("se"); expires != "" {
+		expiry, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return expiry, true
+	}
+
+	return time.Time{}, false
+}
@@ -1,19 +1,21 @@
 package uploaders
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/bitrise-io/go-xcode/exportoptions"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 )
 
 // DeployIPA ...
-func (u *Uploader) DeployIPA(item deployment.DeployableItem, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
+func (u *Uploader) DeployIPA(ctx context.Context, item deployment.DeployableItem, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
 	pth := item.Path
 
 	ipaInfo, err := u.iosParser.ParseIPAData(pth)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+		return nil, failure.New(failure.Parse, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err))
 	}
 
 	if ipaInfo.ProvisioningInfo.IPAExportMethod == exportoptions.MethodAppStore {
@@ -37,7 +39,7 @@ func (u *Uploader) DeployIPA(item deployment.DeployableItem, buildURL, token, no
 		IsEnablePublicPage:     isEnablePublicPage,
 	}
 
-	urLs, err := u.upload(buildURL, token, artifact, "ios-ipa", IPAContentType, &item, &buildArtifactMeta)
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "ios-ipa", IPAContentType, &item, &buildArtifactMeta)
 	if err != nil {
 		return nil, fmt.Errorf("failed ipa deploy: %w", err)
 	}
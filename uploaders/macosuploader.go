@@ -0,0 +1,125 @@
+package uploaders
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
+)
+
+// DeployMacOSXcarchive deploys a zipped macOS xcarchive.
+func (u *Uploader) DeployMacOSXcarchive(ctx context.Context, item deployment.DeployableItem, buildURL, token string) ([]ArtifactURLs, error) {
+	xcarchiveInfo, err := u.macosParser.ParseXCArchiveData(item.Path)
+	if err != nil {
+		return u.deployMacOSFile(ctx, item, buildURL, token, err)
+	}
+
+	u.logger.Printf("macOS xcarchive infos: %+v", printableAppInfo(xcarchiveInfo))
+
+	buildArtifactMeta := AppDeploymentMetaData{
+		MacOSArtifactInfo: xcarchiveInfo,
+	}
+
+	urLs, err := u.deployMacOS(ctx, buildURL, token, item, xcarchiveInfo, "macos-xcarchive", "", &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed macOS xcarchive deploy: %w", err)
+	}
+
+	return urLs, nil
+}
+
+// DeployMacOSApp deploys a zipped macOS .app bundle.
+func (u *Uploader) DeployMacOSApp(ctx context.Context, item deployment.DeployableItem, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
+	appInfo, err := u.macosParser.ParseAppZipData(item.Path)
+	if err != nil {
+		return u.deployMacOSFile(ctx, item, buildURL, token, err)
+	}
+
+	u.logger.Printf("macOS app infos: %+v", printableAppInfo(appInfo))
+
+	const AppZipContentType = "application/zip"
+	buildArtifactMeta := AppDeploymentMetaData{
+		MacOSArtifactInfo:      appInfo,
+		NotifyUserGroups:       notifyUserGroups,
+		AlwaysNotifyUserGroups: alwaysNotifyUserGroups,
+		NotifyEmails:           notifyEmails,
+		IsEnablePublicPage:     isEnablePublicPage,
+	}
+
+	urLs, err := u.deployMacOS(ctx, buildURL, token, item, appInfo, "macos-app", AppZipContentType, &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed macOS app deploy: %w", err)
+	}
+
+	return urLs, nil
+}
+
+// DeployPKG deploys a macOS .pkg installer.
+func (u *Uploader) DeployPKG(ctx context.Context, item deployment.DeployableItem, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
+	pkgInfo, err := u.macosParser.ParsePKGData(item.Path)
+	if err != nil {
+		return u.deployMacOSFile(ctx, item, buildURL, token, err)
+	}
+
+	u.logger.Printf("pkg infos: %+v", printableAppInfo(pkgInfo))
+
+	const PKGContentType = "application/octet-stream pkg"
+	buildArtifactMeta := AppDeploymentMetaData{
+		MacOSArtifactInfo:      pkgInfo,
+		NotifyUserGroups:       notifyUserGroups,
+		AlwaysNotifyUserGroups: alwaysNotifyUserGroups,
+		NotifyEmails:           notifyEmails,
+		IsEnablePublicPage:     isEnablePublicPage,
+	}
+
+	urLs, err := u.deployMacOS(ctx, buildURL, token, item, pkgInfo, "macos-pkg", PKGContentType, &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed pkg deploy: %w", err)
+	}
+
+	return urLs, nil
+}
+
+// DeployDMG deploys a macOS .dmg disk image.
+func (u *Uploader) DeployDMG(ctx context.Context, item deployment.DeployableItem, buildURL, token, notifyUserGroups, alwaysNotifyUserGroups, notifyEmails string, isEnablePublicPage bool) ([]ArtifactURLs, error) {
+	dmgInfo, err := u.macosParser.ParseDMGData(item.Path)
+	if err != nil {
+		return u.deployMacOSFile(ctx, item, buildURL, token, err)
+	}
+
+	u.logger.Printf("dmg infos: %+v", printableAppInfo(dmgInfo))
+
+	const DMGContentType = "application/x-apple-diskimage"
+	buildArtifactMeta := AppDeploymentMetaData{
+		MacOSArtifactInfo:      dmgInfo,
+		NotifyUserGroups:       notifyUserGroups,
+		AlwaysNotifyUserGroups: alwaysNotifyUserGroups,
+		NotifyEmails:           notifyEmails,
+		IsEnablePublicPage:     isEnablePublicPage,
+	}
+
+	urLs, err := u.deployMacOS(ctx, buildURL, token, item, dmgInfo, "macos-dmg", DMGContentType, &buildArtifactMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed dmg deploy: %w", err)
+	}
+
+	return urLs, nil
+}
+
+func (u *Uploader) deployMacOS(ctx context.Context, buildURL, token string, item deployment.DeployableItem, info *macosparser.ArtifactMetadata, artifactType, contentType string, buildArtifactMeta *AppDeploymentMetaData) ([]ArtifactURLs, error) {
+	artifact := ArtifactArgs{
+		Path:     item.Path,
+		FileSize: info.FileSizeBytes,
+	}
+
+	return u.upload(ctx, buildURL, token, artifact, artifactType, contentType, &item, buildArtifactMeta)
+}
+
+// deployMacOSFile deploys an artifact whose metadata couldn't be parsed as a generic file,
+// like the Step did before the macOS artifacts were deployed with their metadata.
+func (u *Uploader) deployMacOSFile(ctx context.Context, item deployment.DeployableItem, buildURL, token string, parseErr error) ([]ArtifactURLs, error) {
+	u.logger.Warnf("Failed to parse deployment info for %s, deploying it without metadata: %s", item.Path, parseErr)
+
+	return u.DeployFile(ctx, item, buildURL, token)
+}
@@ -0,0 +1,157 @@
+package uploaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+var errAllStreamsFailed = errors.New("all streamed uploads failed")
+
+// UploadArtifacts uploads the artifact to every upload URL while reading the file only once:
+// its content is streamed to all the URLs in parallel.
+// The checksums are calculated from the shared stream, so these uploads are not sent with a Content-MD5 header,
+// instead the MD5 stored by each destination (X-Goog-Hash response header) is compared with the streamed checksum.
+// As every destination receives the same chunks, the slowest destination paces the others. A destination which fails
+// mid-stream is dropped from the stream without failing the others, and the URLs whose stream failed (or whose stored MD5
+// differs) are retried one by one with UploadArtifact.
+// The returned details and errors are in the order of the upload URLs.
+func (c *BitriseArtifactClient) UploadArtifacts(ctx context.Context, uploadURLs []string, artifact ArtifactArgs, contentType string) ([]TransferDetails, []error) {
+	details := make([]TransferDetails, len(uploadURLs))
+	errs := make([]error, len(uploadURLs))
+
+	if len(uploadURLs) < 2 || artifact.FileSize == 0 {
+		for i, uploadURL := range uploadURLs {
+			details[i], errs[i] = c.UploadArtifact(ctx, uploadURL, artifact, contentType)
+		}
+		return details, errs
+	}
+
+	start := time.Now()
+	streamErrs, checksums := c.streamToAll(ctx, uploadURLs, artifact, contentType)
+	duration := time.Since(start)
+
+	for i, uploadURL := range uploadURLs {
+		if streamErrs[i] != nil {
+			if ctx.Err() != nil {
+				errs[i] = streamErrs[i]
+				continue
+			}
+
+			log.Warnf("Streamed upload to %s failed, uploading separately: %s", extractHost(uploadURL), streamErrs[i])
+			details[i], errs[i] = c.UploadArtifact(ctx, uploadURL, artifact, contentType)
+			continue
+		}
+
+		details[i] = TransferDetails{
+			Size:       artifact.FileSize,
+			Duration:   duration,
+			Hostname:   extractHost(uploadURL),
+			Checksums:  checksums,
+			Throughput: throughput(artifact.FileSize, duration),
+		}
+	}
+
+	return details, errs
+}
+
+// streamToAll reads the artifact once and sends its content to every upload URL in parallel.
+func (c *BitriseArtifactClient) streamToAll(ctx context.Context, uploadURLs []string, artifact ArtifactArgs, contentType string) ([]error, Checksums) {
+	errs := make([]error, len(uploadURLs))
+
+	file, err := os.Open(artifact.Path)
+	if err != nil {
+		for i := range errs {
+			errs[i] = fmt.Errorf("failed to open artifact, error: %s", err)
+		}
+		return errs, Checksums{}
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Warnf("failed to close file, error: %s", err)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	headers := make([]http.Header, len(uploadURLs))
+	writers := make([]*io.PipeWriter, len(uploadURLs))
+	for i, uploadURL := range uploadURLs {
+		reader, writer := io.Pipe()
+		writers[i] = writer
+
+		wg.Add(1)
+		go func(i int, uploadURL string, body *io.PipeReader) {
+			defer wg.Done()
+
+			headers[i], errs[i] = c.putContent(ctx, uploadURL, body, artifact.FileSize, artifact.FileSize, contentType, http.Header{})
+
+			// Unblocks the writer if the request finished without consuming the whole stream.
+			if err := body.CloseWithError(errs[i]); err != nil {
+				log.Warnf("failed to close stream, error: %s", err)
+			}
+		}(i, uploadURL, reader)
+	}
+
+	// The read is limited to the announced size, so a file growing during the upload doesn't break the requests.
+	contentReader := newChecksumReader(newProgressReader(io.LimitReader(file, artifact.FileSize), filepath.Base(artifact.Path), 0, artifact.FileSize))
+	_, copyErr := io.Copy(newFanOutWriter(writers), contentReader)
+	for _, writer := range writers {
+		if err := writer.CloseWithError(copyErr); err != nil {
+			log.Warnf("failed to close stream, error: %s", err)
+		}
+	}
+
+	wg.Wait()
+
+	checksums := contentReader.checksums()
+	for i := range uploadURLs {
+		if errs[i] != nil {
+			continue
+		}
+		if stored := storedMD5(headers[i]); stored != "" && stored != checksums.contentMD5() {
+			errs[i] = &ChecksumMismatchError{Reason: fmt.Sprintf("stored object's md5 is %q, uploaded: %q", stored, checksums.contentMD5())}
+		}
+	}
+
+	return errs, checksums
+}
+
+// fanOutWriter writes to every writer, the writers which fail are dropped so that the remaining streams can complete.
+type fanOutWriter struct {
+	writers []*io.PipeWriter
+	failed  []bool
+}
+
+func newFanOutWriter(writers []*io.PipeWriter) *fanOutWriter {
+	return &fanOutWriter{
+		writers: writers,
+		failed:  make([]bool, len(writers)),
+	}
+}
+
+func (w *fanOutWriter) Write(p []byte) (int, error) {
+	active := 0
+	for i, writer := range w.writers {
+		if w.failed[i] {
+			continue
+		}
+		if _, err := writer.Write(p); err != nil {
+			w.failed[i] = true
+			continue
+		}
+		active++
+	}
+
+	if active == 0 {
+		return 0, errAllStreamsFailed
+	}
+	return len(p), nil
+}
@@ -0,0 +1,125 @@
+package uploaders
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_UploadArtifacts_streamsToAllURLs(t *testing.T) {
+	content := bytes.Repeat([]byte("artifact content "), 64*1024)
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, content, 0600))
+
+	var mu sync.Mutex
+	received := map[string][]byte{}
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = body
+	}))
+	defer storage.Close()
+
+	artifact := ArtifactArgs{Path: testFilePath, FileSize: int64(len(content))}
+	uploadURLs := []string{storage.URL + "/artifact", storage.URL + "/intermediate"}
+
+	details, errs := newTestArtifactClient().UploadArtifacts(context.Background(), uploadURLs, artifact, "")
+	require.Equal(t, []error{nil, nil}, errs)
+
+	wantChecksums, err := calculateChecksums(testFilePath)
+	require.NoError(t, err)
+	for i := range uploadURLs {
+		require.Equal(t, artifact.FileSize, details[i].Size)
+		require.Equal(t, wantChecksums, details[i].Checksums)
+	}
+
+	require.Equal(t, content, received["/artifact"])
+	require.Equal(t, content, received["/intermediate"])
+}
+
+func Test_UploadArtifacts_retriesFailedStreamSeparately(t *testing.T) {
+	content := bytes.Repeat([]byte("artifact content "), 64*1024)
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, content, 0600))
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	received := map[string][]byte{}
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts[r.URL.Path]++
+		attempt := attempts[r.URL.Path]
+		mu.Unlock()
+
+		if r.URL.Path == "/flaky" && attempt == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.URL.Path] = body
+	}))
+	defer storage.Close()
+
+	artifact := ArtifactArgs{Path: testFilePath, FileSize: int64(len(content))}
+	uploadURLs := []string{storage.URL + "/stable", storage.URL + "/flaky"}
+
+	_, errs := newTestArtifactClient().UploadArtifacts(context.Background(), uploadURLs, artifact, "")
+	require.Equal(t, []error{nil, nil}, errs)
+
+	require.Equal(t, 1, attempts["/stable"])
+	require.Equal(t, 2, attempts["/flaky"])
+	require.Equal(t, content, received["/stable"])
+	require.Equal(t, content, received["/flaky"])
+}
+
+func Test_UploadArtifacts_verifiesStoredMD5OfEachURL(t *testing.T) {
+	content := bytes.Repeat([]byte("artifact content "), 64*1024)
+	testFilePath := filepath.Join(t.TempDir(), "artifact.txt")
+	require.NoError(t, os.WriteFile(testFilePath, content, 0600))
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		attempts[r.URL.Path]++
+		attempt := attempts[r.URL.Path]
+		mu.Unlock()
+
+		if r.URL.Path == "/corrupted" && attempt == 1 {
+			// The destination stored a corrupted copy of the streamed content.
+			body = append(body[:len(body)-1], 'x')
+		}
+		digest := md5.Sum(body)
+		w.Header().Set("X-Goog-Hash", "crc32c=AAAAAA==,md5="+base64.StdEncoding.EncodeToString(digest[:]))
+	}))
+	defer storage.Close()
+
+	artifact := ArtifactArgs{Path: testFilePath, FileSize: int64(len(content))}
+	uploadURLs := []string{storage.URL + "/stable", storage.URL + "/corrupted"}
+
+	_, errs := newTestArtifactClient().UploadArtifacts(context.Background(), uploadURLs, artifact, "")
+	require.Equal(t, []error{nil, nil}, errs)
+	// The corrupted destination is uploaded again separately.
+	require.Equal(t, map[string]int{"/stable": 1, "/corrupted": 2}, attempts)
+}
@@ -0,0 +1,121 @@
+package uploaders
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/docker/go-units"
+
+	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
+	"github.com/bitrise-io/go-utils/v2/env"
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	iosparser "github.com/bitrise-io/go-xcode/v2/metaparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
+)
+
+// PlannedUpload describes an upload which would be made, it is collected in dry run mode.
+type PlannedUpload struct {
+	Path                   string
+	FileSize               int64
+	ArtifactType           string
+	ContentType            string
+	ArchiveAsArtifact      bool
+	IntermediateFileMeta   *deployment.IntermediateFileMetaData
+	NotifyUserGroups       string
+	AlwaysNotifyUserGroups string
+	NotifyEmails           string
+	IsEnablePublicPage     bool
+	CustomMetadata         map[string]interface{}
+}
+
+// NewDryRun creates an Uploader which parses the deployable items, but instead of uploading them only collects the upload plan.
+func NewDryRun(
+	logger log.Logger,
+	fileManager fileutil.FileManager,
+	androidParser *androidparser.Parser,
+	aabParser AABParser,
+	iosParser *iosparser.Parser,
+	macosParser *macosparser.Parser,
+) *Uploader {
+	return &Uploader{
+		logger:        logger,
+		fileManager:   fileManager,
+		androidParser: androidParser,
+		aabParser:     aabParser,
+		iosParser:     iosParser,
+		macosParser:   macosParser,
+		tracker:       newTracker(env.NewRepository(), logger),
+		reports:       map[string]UploadReport{},
+		dryRun:        true,
+	}
+}
+
+// Plan returns the uploads collected in dry run mode, ordered by their path.
+func (u *Uploader) Plan() []PlannedUpload {
+	u.reportsLock.Lock()
+	defer u.reportsLock.Unlock()
+
+	plan := append([]PlannedUpload{}, u.plan...)
+	sort.SliceStable(plan, func(i, j int) bool {
+		return plan[i].Path < plan[j].Path
+	})
+
+	return plan
+}
+
+func (u *Uploader) addPlannedUpload(upload PlannedUpload) {
+	u.reportsLock.Lock()
+	defer u.reportsLock.Unlock()
+
+	u.plan = append(u.plan, upload)
+}
+
+func (p PlannedUpload) String() string {
+	var targets []string
+	if p.ArchiveAsArtifact {
+		targets = append(targets, "Build Artifact")
+	}
+	if p.IntermediateFileMeta != nil {
+		targets = append(targets, fmt.Sprintf("Pipeline intermediate file (%s)", p.IntermediateFileMeta.EnvKey))
+	}
+
+	description := fmt.Sprintf("%s (%s) as %s", p.Path, units.BytesSize(float64(p.FileSize)), p.ArtifactType)
+	if p.ContentType != "" {
+		description += fmt.Sprintf(" (%s)", p.ContentType)
+	}
+	description += ", " + strings.Join(targets, " and ")
+
+	if p.NotifyUserGroups != "" || p.AlwaysNotifyUserGroups != "" || p.NotifyEmails != "" || p.IsEnablePublicPage {
+		description += fmt.Sprintf(", notify user groups: %q, always notify user groups: %q, notify emails: %q, public install page: %t",
+			p.NotifyUserGroups, p.AlwaysNotifyUserGroups, p.NotifyEmails, p.IsEnablePublicPage)
+	}
+
+	if len(p.CustomMetadata) > 0 {
+		description += fmt.Sprintf(", custom metadata: %s", printableAppInfo(p.CustomMetadata))
+	}
+
+	return description
+}
+
+func newPlannedUpload(artifact ArtifactArgs, artifactType, contentType string, item *deployment.DeployableItem, buildArtifactMeta *AppDeploymentMetaData) PlannedUpload {
+	upload := PlannedUpload{
+		Path:                 item.Path,
+		FileSize:             artifact.FileSize,
+		ArtifactType:         artifactType,
+		ContentType:          contentType,
+		ArchiveAsArtifact:    item.ArchiveAsArtifact,
+		IntermediateFileMeta: item.IntermediateFileMeta,
+	}
+	if buildArtifactMeta != nil {
+		upload.NotifyUserGroups = buildArtifactMeta.NotifyUserGroups
+		upload.AlwaysNotifyUserGroups = buildArtifactMeta.AlwaysNotifyUserGroups
+		upload.NotifyEmails = buildArtifactMeta.NotifyEmails
+		upload.IsEnablePublicPage = buildArtifactMeta.IsEnablePublicPage
+		upload.CustomMetadata = buildArtifactMeta.CustomMetadata
+	}
+
+	return upload
+}
@@ -0,0 +1,78 @@
+package uploaders
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/go-units"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const progressLogInterval = 10 * time.Second
+
+// progressReader periodically logs the progress of the upload streamed through it.
+type progressReader struct {
+	reader io.Reader
+	label  string
+	total  int64
+	offset int64
+	sent   int64
+
+	start   time.Time
+	lastLog time.Time
+	now     func() time.Time
+}
+
+// newProgressReader creates a progressReader for an upload of total bytes, starting at the given offset (for resumed uploads).
+func newProgressReader(reader io.Reader, label string, offset, total int64) *progressReader {
+	start := time.Now()
+	return &progressReader{
+		reader:  reader,
+		label:   label,
+		total:   total,
+		offset:  offset,
+		start:   start,
+		lastLog: start,
+		now:     time.Now,
+	}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.sent += int64(n)
+
+	if now := r.now(); now.Sub(r.lastLog) >= progressLogInterval && r.offset+r.sent < r.total {
+		r.lastLog = now
+		log.Printf("%s", progressMessage(r.label, r.offset, r.sent, r.total, now.Sub(r.start)))
+	}
+
+	return n, err
+}
+
+// progressMessage describes the state of an upload, the throughput and the ETA are based on the bytes sent since the upload (re)started.
+func progressMessage(label string, offset, sent, total int64, elapsed time.Duration) string {
+	uploaded := offset + sent
+	percentage := 100.0
+	if total > 0 {
+		percentage = float64(uploaded) / float64(total) * 100
+	}
+
+	bytesPerSecond := throughput(sent, elapsed)
+	eta := "unknown"
+	if bytesPerSecond > 0 {
+		eta = time.Duration(float64(total-uploaded) / bytesPerSecond * float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s: uploaded %s of %s (%.1f%%), %.2f MiB/s, ETA %s",
+		label, units.BytesSize(float64(uploaded)), units.BytesSize(float64(total)), percentage, bytesPerSecond/units.MiB, eta)
+}
+
+// throughput returns the transfer speed in bytes per second.
+func throughput(size int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(size) / duration.Seconds()
+}
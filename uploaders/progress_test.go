@@ -0,0 +1,68 @@
+package uploaders
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_progressMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		offset  int64
+		sent    int64
+		total   int64
+		elapsed time.Duration
+		want    string
+	}{
+		{
+			name:    "In progress",
+			sent:    10 * 1024 * 1024,
+			total:   40 * 1024 * 1024,
+			elapsed: 5 * time.Second,
+			want:    "app.ipa: uploaded 10MiB of 40MiB (25.0%), 2.00 MiB/s, ETA 15s",
+		},
+		{
+			name:    "Resumed upload",
+			offset:  20 * 1024 * 1024,
+			sent:    10 * 1024 * 1024,
+			total:   40 * 1024 * 1024,
+			elapsed: 10 * time.Second,
+			want:    "app.ipa: uploaded 30MiB of 40MiB (75.0%), 1.00 MiB/s, ETA 10s",
+		},
+		{
+			name:  "Nothing sent yet",
+			total: 40 * 1024 * 1024,
+			want:  "app.ipa: uploaded 0B of 40MiB (0.0%), 0.00 MiB/s, ETA unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, progressMessage("app.ipa", tt.offset, tt.sent, tt.total, tt.elapsed))
+		})
+	}
+}
+
+func Test_progressReader(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 1024)
+	reader := newProgressReader(bytes.NewReader(content), "file.txt", 0, int64(len(content)))
+
+	now := reader.start
+	reader.now = func() time.Time {
+		now = now.Add(progressLogInterval)
+		return now
+	}
+
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, content, got)
+	require.Equal(t, int64(len(content)), reader.sent)
+}
+
+func Test_throughput(t *testing.T) {
+	require.Equal(t, float64(1024), throughput(2048, 2*time.Second))
+	require.Equal(t, float64(0), throughput(2048, 0))
+}
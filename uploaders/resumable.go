@@ -0,0 +1,96 @@
+package uploaders
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+// statusResumeIncomplete is the status code used by resumable upload capable storages (like Google Cloud Storage)
+// to signal that the upload is not yet complete.
+const statusResumeIncomplete = 308
+
+// isResumableSession reports whether the upload URL is a resumable upload session (a GCS session URI with an upload_id).
+// Plain signed URLs are simple uploads: a status probe would overwrite their object with an empty one, so they are not resumed.
+// The Bitrise backend currently returns plain signed URLs, so its uploads are restarted from the beginning on retries,
+// resuming only applies to storages which return session URIs.
+func isResumableSession(uploadURL string) bool {
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return false
+	}
+
+	return u.Query().Get("upload_id") != ""
+}
+
+// storedMD5 returns the base64 encoded MD5 digest of the stored object, which GCS reports in the X-Goog-Hash header.
+func storedMD5(header http.Header) string {
+	for _, value := range header.Values("X-Goog-Hash") {
+		for _, digest := range strings.Split(value, ",") {
+			if md5, ok := strings.CutPrefix(strings.TrimSpace(digest), "md5="); ok {
+				return md5
+			}
+		}
+	}
+
+	return ""
+}
+
+// queryCommittedBytes asks the storage how many bytes of the resumable upload session it has already persisted,
+// using a GCS `Content-Range: bytes */<size>` status probe.
+// An error is returned if the storage doesn't support resuming the upload, in which case it should be restarted from the beginning.
+func queryCommittedBytes(ctx context.Context, client *http.Client, uploadURL string, fileSize int64) (int64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request, error: %s", err)
+	}
+
+	request.Header.Add("Content-Range", fmt.Sprintf("bytes */%d", fileSize))
+	request.ContentLength = 0
+
+	resp, err := client.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query upload status, error: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Errorf("Failed to close response body, error: %s", err)
+		}
+	}()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, fmt.Errorf("failed to read response body, error: %w", err)
+	}
+
+	if resp.StatusCode != statusResumeIncomplete {
+		return 0, fmt.Errorf("resumable upload is not supported, status code: %d", resp.StatusCode)
+	}
+
+	return parseCommittedBytes(resp.Header.Get("Range"))
+}
+
+// parseCommittedBytes returns the number of persisted bytes based on a `Range: bytes=0-<last byte>` header value.
+// A missing header means that no bytes have been persisted yet.
+func parseCommittedBytes(rangeHeader string) (int64, error) {
+	if rangeHeader == "" {
+		return 0, nil
+	}
+
+	value, ok := strings.CutPrefix(rangeHeader, "bytes=0-")
+	if !ok {
+		return 0, fmt.Errorf("unexpected Range header: %s", rangeHeader)
+	}
+
+	lastByte, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || lastByte < 0 {
+		return 0, fmt.Errorf("unexpected Range header: %s", rangeHeader)
+	}
+
+	return lastByte + 1, nil
+}
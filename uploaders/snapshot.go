@@ -0,0 +1,187 @@
+package uploaders
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
+)
+
+// snapshotFileSizeLimitInBytes is the size limit of files which are fully copied when cheaper snapshots are not available.
+const snapshotFileSizeLimitInBytes = 1024 * 1024 * 1024
+
+var errCloneNotSupported = errors.New("file cloning is not supported on this platform")
+
+type snapshotMethod string
+
+const (
+	// snapshotClone is a copy-on-write copy (reflink), it is instant and independent of the original file.
+	snapshotClone snapshotMethod = "clone"
+	// snapshotHardLink shares the content with the original file: it survives the original being replaced or removed,
+	// but in-place writes are visible through it, so the upload needs to be verified.
+	snapshotHardLink snapshotMethod = "hard link"
+	// snapshotCopy is a full copy of the original file.
+	snapshotCopy snapshotMethod = "copy"
+	// snapshotNone means that the original file is uploaded, so the upload needs to be verified.
+	snapshotNone snapshotMethod = "none"
+)
+
+// FileChangedError is returned when the file was modified while it was being uploaded.
+type FileChangedError struct {
+	Path   string
+	Reason string
+}
+
+func (e FileChangedError) Error() string {
+	return fmt.Sprintf("%s was modified during the upload (%s), make sure the file is not written by other processes while it is being deployed", e.Path, e.Reason)
+}
+
+// FailureReason categorizes the change as a file failure, as the file was modified by another process.
+func (e FileChangedError) FailureReason() failure.Reason {
+	return failure.File
+}
+
+// fileState is the size and modification time of a file, used for cheap change detection.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func statFile(pth string) (fileState, error) {
+	info, err := os.Stat(pth)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+// fileSnapshot is the file which is uploaded in place of the original one.
+type fileSnapshot struct {
+	path         string
+	originalPath string
+	method       snapshotMethod
+	state        fileState
+}
+
+// needsVerification reports whether changes of the original file can affect the uploaded content.
+func (s fileSnapshot) needsVerification() bool {
+	return s.method == snapshotHardLink || s.method == snapshotNone
+}
+
+// verify checks that the snapshot has the given state and content, it is used to detect modifications made during the upload.
+func (s fileSnapshot) verify(uploaded Checksums) error {
+	state, err := statFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to get file info, error: %s", err)
+	}
+
+	if state.size != s.state.size {
+		return FileChangedError{Path: s.originalPath, Reason: fmt.Sprintf("size changed from %d to %d bytes", s.state.size, state.size)}
+	}
+	if !state.modTime.Equal(s.state.modTime) {
+		return FileChangedError{Path: s.originalPath, Reason: "modification time changed"}
+	}
+
+	// The modification time has a whole second resolution on some file systems, so a modification during the upload
+	// can keep it: on these the content is compared too. Otherwise the checksums calculated during the upload are trusted,
+	// and the file isn't read again.
+	if uploaded.SHA256 == "" || state.modTime.Nanosecond() != 0 {
+		return nil
+	}
+	current, err := calculateChecksums(s.path)
+	if err != nil {
+		return err
+	}
+	if current.SHA256 != uploaded.SHA256 {
+		return FileChangedError{Path: s.originalPath, Reason: "content changed"}
+	}
+
+	return nil
+}
+
+// createSnapshot creates a snapshot of the file in a temporary directory with the same file name.
+// A copy-on-write clone is preferred, then a hard link, then a full copy for files up to snapshotFileSizeLimitInBytes.
+// If none of these succeed the original file is used, and the caller is expected to verify the upload.
+// The returned cleanup function removes the snapshot.
+func createSnapshot(originalPath string) (fileSnapshot, func(), error) {
+	noop := func() {}
+
+	state, err := statFile(originalPath)
+	if err != nil {
+		return fileSnapshot{}, noop, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	original := fileSnapshot{path: originalPath, originalPath: originalPath, method: snapshotNone, state: state}
+
+	tmpDir, err := pathutil.NormalizedOSTempDirPath("snapshot")
+	if err != nil {
+		return original, noop, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			log.Warnf("Failed to remove snapshot: %s", err)
+		}
+	}
+
+	snapshotPath := filepath.Join(tmpDir, filepath.Base(originalPath))
+
+	method := snapshotClone
+	err = cloneFile(originalPath, snapshotPath)
+	if err != nil {
+		method = snapshotHardLink
+		err = os.Link(originalPath, snapshotPath)
+	}
+	if err != nil && state.size <= snapshotFileSizeLimitInBytes {
+		method = snapshotCopy
+		err = copyFile(originalPath, snapshotPath)
+	}
+	if err != nil {
+		cleanup()
+		return original, noop, err
+	}
+
+	// The original file could have changed before the snapshot was taken.
+	snapshotState, err := statFile(snapshotPath)
+	if err != nil {
+		cleanup()
+		return original, noop, fmt.Errorf("failed to get snapshot file info: %w", err)
+	}
+
+	return fileSnapshot{path: snapshotPath, originalPath: originalPath, method: method, state: snapshotState}, cleanup, nil
+}
+
+// copyFile copies the content of the source file to a new file at the destination path.
+func copyFile(sourcePath, destinationPath string) error {
+	originalFile, err := os.Open(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to open original file: %w", err)
+	}
+	defer func() {
+		if err := originalFile.Close(); err != nil {
+			log.Warnf("Failed to close original file: %s", err)
+		}
+	}()
+
+	tmpFile, err := os.Create(destinationPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err := tmpFile.Close(); err != nil {
+			log.Warnf("Failed to close temp file: %s", err)
+		}
+	}()
+
+	if _, err := io.Copy(tmpFile, originalFile); err != nil {
+		return fmt.Errorf("failed to copy contents: %w", err)
+	}
+
+	return nil
+}
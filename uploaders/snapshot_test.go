@@ -0,0 +1,102 @@
+package uploaders
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_createSnapshot(t *testing.T) {
+	originalPath := filepath.Join(t.TempDir(), "app.ipa")
+	require.NoError(t, os.WriteFile(originalPath, []byte("original content"), 0600))
+
+	snapshot, removeSnapshot, err := createSnapshot(originalPath)
+	require.NoError(t, err)
+
+	require.NotEqual(t, snapshotNone, snapshot.method)
+	require.NotEqual(t, originalPath, snapshot.path)
+	require.Equal(t, originalPath, snapshot.originalPath)
+	require.Equal(t, filepath.Base(originalPath), filepath.Base(snapshot.path))
+	require.Equal(t, int64(len("original content")), snapshot.state.size)
+
+	content, err := os.ReadFile(snapshot.path)
+	require.NoError(t, err)
+	require.Equal(t, "original content", string(content))
+
+	// Replacing the original file doesn't affect any kind of snapshot.
+	require.NoError(t, os.Remove(originalPath))
+	require.NoError(t, os.WriteFile(originalPath, []byte("new content"), 0600))
+	content, err = os.ReadFile(snapshot.path)
+	require.NoError(t, err)
+	require.Equal(t, "original content", string(content))
+
+	removeSnapshot()
+	require.NoFileExists(t, snapshot.path)
+}
+
+func Test_fileSnapshot_verify(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(t *testing.T, pth string, state fileState)
+		wantReason string
+	}{
+		{
+			name:   "unchanged file",
+			modify: func(t *testing.T, pth string, state fileState) {},
+		},
+		{
+			name: "appended file",
+			modify: func(t *testing.T, pth string, state fileState) {
+				require.NoError(t, os.WriteFile(pth, []byte("content and more"), 0600))
+			},
+			wantReason: "size changed from 7 to 16 bytes",
+		},
+		{
+			name: "touched file",
+			modify: func(t *testing.T, pth string, state fileState) {
+				require.NoError(t, os.Chtimes(pth, state.modTime, state.modTime.Add(time.Minute)))
+			},
+			wantReason: "modification time changed",
+		},
+		{
+			name: "rewritten file with the same size and modification time",
+			modify: func(t *testing.T, pth string, state fileState) {
+				require.NoError(t, os.WriteFile(pth, []byte("CONTENT"), 0600))
+				require.NoError(t, os.Chtimes(pth, state.modTime, state.modTime))
+			},
+			wantReason: "content changed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pth := filepath.Join(t.TempDir(), "artifact.txt")
+			require.NoError(t, os.WriteFile(pth, []byte("content"), 0600))
+			// Simulates a file system with a whole second modification time resolution.
+			modTime := time.Now().Truncate(time.Second)
+			require.NoError(t, os.Chtimes(pth, modTime, modTime))
+
+			state, err := statFile(pth)
+			require.NoError(t, err)
+			uploaded, err := calculateChecksums(pth)
+			require.NoError(t, err)
+
+			tt.modify(t, pth, state)
+
+			snapshot := fileSnapshot{path: pth, originalPath: pth, method: snapshotNone, state: state}
+			err = snapshot.verify(uploaded)
+			if tt.wantReason == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var changedErr FileChangedError
+			require.True(t, errors.As(err, &changedErr))
+			require.Equal(t, pth, changedErr.Path)
+			require.Equal(t, tt.wantReason, changedErr.Reason)
+		})
+	}
+}
@@ -32,9 +32,13 @@ func newTracker(envRepo env.Repository, logger log.Logger) tracker {
 
 func (t *tracker) logFileTransfer(transferType TransferType, details TransferDetails, err error, isArtifact, isIntermediateFile bool) {
 	properties := analytics.Properties{
-		"storage_host": details.Hostname,
-		"duration_ms":  details.Duration.Milliseconds(),
-		"size_bytes":   details.Size,
+		"storage_host":                details.Hostname,
+		"duration_ms":                 details.Duration.Milliseconds(),
+		"size_bytes":                  details.Size,
+		"throughput_bytes_per_second": details.Throughput,
+	}
+	if details.Checksums.SHA256 != "" {
+		properties["sha256"] = details.Checksums.SHA256
 	}
 	if err != nil {
 		properties["error"] = err.Error()
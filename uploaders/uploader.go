@@ -1,7 +1,15 @@
 package uploaders
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/go-units"
 
 	androidparser "github.com/bitrise-io/go-android/v2/metaparser"
 	"github.com/bitrise-io/go-utils/v2/env"
@@ -9,28 +17,74 @@ import (
 	"github.com/bitrise-io/go-utils/v2/log"
 	iosparser "github.com/bitrise-io/go-xcode/v2/metaparser"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
 )
 
+// maxChangedFileReuploads is the number of times a file is uploaded again if it was modified during the upload.
+const maxChangedFileReuploads = 2
+
+// AABParser parses the metadata of Android App Bundles, it is implemented by the bundletool based
+// androidparser.Parser and by aabparser.Parser which decodes the bundle's manifest natively.
+type AABParser interface {
+	ParseAABData(pth string) (*androidparser.ArtifactMetadata, error)
+}
+
 type Uploader struct {
 	logger        log.Logger
 	fileManager   fileutil.FileManager
 	androidParser *androidparser.Parser
+	aabParser     AABParser
 	iosParser     *iosparser.Parser
+	macosParser   *macosparser.Parser
+	client        ArtifactClient
 	tracker       tracker
+
+	reportsLock sync.Mutex
+	reports     map[string]UploadReport
+
+	orphansLock sync.Mutex
+	orphans     []OrphanedArtifact
+
+	// renewalUnsupported is set once the backend turned out not to renew upload URLs.
+	renewalUnsupported atomic.Bool
+
+	dryRun bool
+	plan   []PlannedUpload
+}
+
+// UploadReport describes the upload of a deployable item.
+type UploadReport struct {
+	FileSize int64
+	// UploadedPath is the deployed file, for compressed directories the zip of the directory.
+	UploadedPath string
+	// SnapshotMethod is how the file was snapshotted for the upload (clone, hard link, copy or none), empty in a dry run.
+	// The snapshot is removed after the upload.
+	SnapshotMethod string
+	Transfers      []TransferDetails
+	ArtifactURLs   []ArtifactURLs
+	// AppMetadata is the parsed metadata of app artifacts (like APKs and IPAs).
+	AppMetadata interface{}
 }
 
 func New(
 	logger log.Logger,
 	fileManager fileutil.FileManager,
 	androidParser *androidparser.Parser,
+	aabParser AABParser,
 	iosParser *iosparser.Parser,
+	macosParser *macosparser.Parser,
+	client ArtifactClient,
 ) *Uploader {
 	return &Uploader{
 		logger:        logger,
 		fileManager:   fileManager,
 		androidParser: androidParser,
+		aabParser:     aabParser,
 		iosParser:     iosParser,
+		macosParser:   macosParser,
+		client:        client,
 		tracker:       newTracker(env.NewRepository(), logger),
+		reports:       map[string]UploadReport{},
 	}
 }
 
@@ -38,10 +92,70 @@ func (u *Uploader) Wait() {
 	u.tracker.wait()
 }
 
-func (u *Uploader) upload(buildURL, token string, artifact ArtifactArgs, artifactType, contentType string, item *deployment.DeployableItem, buildArtifactMeta *AppDeploymentMetaData) ([]ArtifactURLs, error) {
-	uploadTasks, err := createArtifact(buildURL, token, artifact, artifactType, contentType, item.ArchiveAsArtifact, item.IntermediateFileMeta)
+// Report returns the details of the last upload of the deployable item with the given path.
+func (u *Uploader) Report(pth string) (UploadReport, bool) {
+	u.reportsLock.Lock()
+	defer u.reportsLock.Unlock()
+
+	report, ok := u.reports[pth]
+	return report, ok
+}
+
+func (u *Uploader) setReport(pth string, report UploadReport) {
+	u.reportsLock.Lock()
+	defer u.reportsLock.Unlock()
+
+	u.reports[pth] = report
+}
+
+func (u *Uploader) upload(ctx context.Context, buildURL, token string, artifact ArtifactArgs, artifactType, contentType string, item *deployment.DeployableItem, buildArtifactMeta *AppDeploymentMetaData) ([]ArtifactURLs, error) {
+	if len(item.CustomMetadata) > 0 || item.GeneratedFrom != "" {
+		meta := AppDeploymentMetaData{}
+		if buildArtifactMeta != nil {
+			meta = *buildArtifactMeta
+		}
+		meta.CustomMetadata = item.CustomMetadata
+		if item.GeneratedFrom != "" {
+			meta.GeneratedFrom = filepath.Base(item.GeneratedFrom)
+		}
+		buildArtifactMeta = &meta
+	}
+
+	if u.dryRun {
+		upload := newPlannedUpload(artifact, artifactType, contentType, item, buildArtifactMeta)
+		u.logger.Printf("Would upload %s", upload)
+		u.addPlannedUpload(upload)
+		u.setReport(item.Path, UploadReport{FileSize: artifact.FileSize, UploadedPath: item.Path, AppMetadata: appMetadata(buildArtifactMeta)})
+		return nil, nil
+	}
+
+	// The file could be modified by other processes during the upload, which could cause issues like:
+	// request body larger than specified content length at file upload.
+	snapshot, removeSnapshot, err := createSnapshot(artifact.Path)
+	if err != nil {
+		if snapshot.path == "" {
+			return nil, fmt.Errorf("failed to snapshot artifact (%s): %w", artifact.Path, err)
+		}
+		u.logger.Warnf("Failed to create snapshot of %s: %s", artifact.Path, err)
+	}
+	defer removeSnapshot()
+
+	if snapshot.method == snapshotNone {
+		u.logger.Printf("Deploying original file, it will be verified after the upload: %s", artifact.Path)
+	} else {
+		u.logger.Printf("Deploying snapshot (%s) of original file: %s", snapshot.method, artifact.Path)
+	}
+	artifact.Path = snapshot.path
+	artifact.FileSize = snapshot.state.size
+
+	report := UploadReport{FileSize: artifact.FileSize, UploadedPath: item.Path, SnapshotMethod: string(snapshot.method), AppMetadata: appMetadata(buildArtifactMeta)}
+	defer func() {
+		u.setReport(item.Path, report)
+	}()
+
+	uploadTasks, err := u.client.CreateArtifact(ctx, buildURL, token, artifact, artifactType, contentType, item.ArchiveAsArtifact, item.IntermediateFileMeta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create artifact (%s): %w", artifact.Path, err)
+		return nil, fmt.Errorf("failed to create artifact (%s): %w", snapshot.originalPath, err)
 	}
 
 	useIntermediateFileURLs := true
@@ -51,9 +165,20 @@ func (u *Uploader) upload(buildURL, token string, artifact ArtifactArgs, artifac
 		useIntermediateFileURLs = false
 	}
 
-	var artifactURLs []ArtifactURLs
+	var uploadURLs []string
 	for _, task := range uploadTasks {
-		details, err := UploadArtifact(task.URL, artifact, contentType)
+		uploadURLs = append(uploadURLs, task.URL)
+	}
+
+	transferDetails, uploadErrs := u.uploadSnapshot(ctx, uploadURLs, artifact, contentType, &snapshot)
+	u.renewExpiredUploads(ctx, buildURL, token, uploadTasks, transferDetails, uploadErrs, artifact, contentType, &snapshot)
+
+	var artifactURLs []ArtifactURLs
+	var errs []error
+	var failedTasks []UploadTask
+	for i, task := range uploadTasks {
+		details, err := transferDetails[i], uploadErrs[i]
+		report.Transfers = append(report.Transfers, details)
 
 		var transferType = Artifact
 		if task.IsIntermediate {
@@ -63,18 +188,124 @@ func (u *Uploader) upload(buildURL, token string, artifact ArtifactArgs, artifac
 		u.tracker.logFileTransfer(transferType, details, err, item.ArchiveAsArtifact, item.IsIntermediateFile())
 
 		if err != nil {
-			return nil, fmt.Errorf("failed to upload artifact (%s): %w", artifact.Path, err)
+			errs = append(errs, fmt.Errorf("failed to upload artifact (%s) for upload task %s: %w", snapshot.originalPath, task.Identifier(), err))
+			failedTasks = append(failedTasks, task)
+			continue
 		}
 
-		urls, err := finishArtifact(buildURL, token, task.Identifier(), buildArtifactMeta)
+		u.logger.Printf("%s: uploaded in %s (%.2f MiB/s)", filepath.Base(snapshot.originalPath), details.Duration.Round(time.Millisecond), details.Throughput/units.MiB)
+
+		urls, err := u.client.FinishArtifact(ctx, buildURL, token, task.Identifier(), buildArtifactMeta, details.Checksums)
 		if err != nil {
-			return nil, fmt.Errorf("failed to finish artifact upload (%s): %w", artifact.Path, err)
+			errs = append(errs, fmt.Errorf("failed to finish artifact upload (%s) for upload task %s: %w", snapshot.originalPath, task.Identifier(), err))
+			var unknownErr FinishOutcomeUnknownError
+			if errors.As(err, &unknownErr) {
+				u.logger.Warnf("Artifact record %s of %s is kept, as its upload might have been finished", task.Identifier(), filepath.Base(snapshot.originalPath))
+				continue
+			}
+			failedTasks = append(failedTasks, task)
+			continue
 		}
 
 		if !task.IsIntermediate || useIntermediateFileURLs {
 			artifactURLs = append(artifactURLs, urls)
+			report.ArtifactURLs = append(report.ArtifactURLs, urls)
 		}
 	}
 
+	if len(errs) > 0 {
+		u.abortArtifacts(ctx, buildURL, token, snapshot.originalPath, failedTasks)
+		return nil, errors.Join(errs...)
+	}
+
 	return artifactURLs, nil
 }
+
+// uploadSnapshot streams the snapshot once to every upload URL.
+// Snapshots which can be affected by changes of the original file are verified after the upload:
+// if the file changed but kept its size it is uploaded again, otherwise the uploads fail with a FileChangedError.
+func (u *Uploader) uploadSnapshot(ctx context.Context, uploadURLs []string, artifact ArtifactArgs, contentType string, snapshot *fileSnapshot) ([]TransferDetails, []error) {
+	for attempt := 0; ; attempt++ {
+		transferDetails, uploadErrs := u.client.UploadArtifacts(ctx, uploadURLs, artifact, contentType)
+		if !snapshot.needsVerification() {
+			return transferDetails, uploadErrs
+		}
+
+		var checksums Checksums
+		for i, details := range transferDetails {
+			if uploadErrs[i] == nil {
+				checksums = details.Checksums
+				break
+			}
+		}
+
+		err := snapshot.verify(checksums)
+		if err == nil {
+			return transferDetails, uploadErrs
+		}
+
+		var changedErr FileChangedError
+		if errors.As(err, &changedErr) && attempt < maxChangedFileReuploads && ctx.Err() == nil {
+			if state, statErr := statFile(snapshot.path); statErr == nil && state.size == snapshot.state.size {
+				u.logger.Warnf("%s, uploading it again", err)
+				snapshot.state = state
+				continue
+			}
+		}
+
+		for i := range uploadErrs {
+			if uploadErrs[i] == nil {
+				uploadErrs[i] = err
+			}
+		}
+		return transferDetails, uploadErrs
+	}
+}
+
+// renewExpiredUploads uploads the artifact again with renewed upload URLs, for the upload tasks whose URL expired during the upload.
+// The upload tasks, transfer details and errors are updated in place.
+// If the backend doesn't support the renewal, the expiry errors are kept and no further renewal is attempted.
+func (u *Uploader) renewExpiredUploads(ctx context.Context, buildURL, token string, uploadTasks []UploadTask, transferDetails []TransferDetails, uploadErrs []error, artifact ArtifactArgs, contentType string, snapshot *fileSnapshot) {
+	for i := range uploadTasks {
+		for renewal := 0; renewal < maxUploadURLRenewals && isUploadURLExpired(uploadErrs[i]) && ctx.Err() == nil && !u.renewalUnsupported.Load(); renewal++ {
+			u.logger.Warnf("Upload URL of upload task %s expired, requesting a new one", uploadTasks[i].Identifier())
+
+			task, err := u.client.RenewUploadTask(ctx, buildURL, token, uploadTasks[i])
+			if errors.Is(err, ErrRenewalUnsupported) {
+				u.logger.Printf("Upload URLs can't be renewed: %s", err)
+				u.renewalUnsupported.Store(true)
+				break
+			} else if err != nil {
+				uploadErrs[i] = fmt.Errorf("failed to renew expired upload URL (%s): %w", uploadErrs[i], err)
+				break
+			}
+			uploadTasks[i] = task
+
+			transferDetails[i], uploadErrs[i] = u.client.UploadArtifact(ctx, task.URL, artifact, contentType)
+			if uploadErrs[i] == nil && snapshot.needsVerification() {
+				uploadErrs[i] = snapshot.verify(transferDetails[i].Checksums)
+			}
+		}
+	}
+}
+
+// appMetadata returns the parsed metadata of app artifacts and debug symbols, or nil for other files.
+func appMetadata(buildArtifactMeta *AppDeploymentMetaData) interface{} {
+	if buildArtifactMeta == nil {
+		return nil
+	}
+	if buildArtifactMeta.AndroidArtifactInfo != nil {
+		return buildArtifactMeta.AndroidArtifactInfo
+	}
+	if buildArtifactMeta.IOSArtifactInfo != nil {
+		return buildArtifactMeta.IOSArtifactInfo
+	}
+	if buildArtifactMeta.MacOSArtifactInfo != nil {
+		return buildArtifactMeta.MacOSArtifactInfo
+	}
+	if buildArtifactMeta.DebugSymbolsInfo != nil {
+		return buildArtifactMeta.DebugSymbolsInfo
+	}
+
+	return nil
+}
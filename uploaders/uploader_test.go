@@ -0,0 +1,480 @@
+package uploaders_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-utils/v2/fileutil"
+	"github.com/bitrise-io/go-utils/v2/log"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/debugsymbols"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/macosparser"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/retrypolicy"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/uploaders/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	buildURL = "https://app.bitrise.io/build/build-slug"
+	token    = "build-api-token"
+)
+
+func TestDeployFile_uploadTasks(t *testing.T) {
+	artifactTask := uploaders.UploadTask{ID: 1, URL: "https://storage/artifact"}
+	intermediateTask := uploaders.UploadTask{ID: 2, URL: "https://storage/intermediate", IsIntermediate: true}
+	artifactURLs := uploaders.ArtifactURLs{PermanentDownloadURL: "https://app.bitrise.io/artifacts/1/download"}
+	intermediateURLs := uploaders.ArtifactURLs{PermanentDownloadURL: "https://app.bitrise.io/artifacts/2/download"}
+
+	tests := []struct {
+		name              string
+		archiveAsArtifact bool
+		intermediateMeta  *deployment.IntermediateFileMetaData
+		uploadTasks       []uploaders.UploadTask
+		want              []uploaders.ArtifactURLs
+	}{
+		{
+			name:              "Build Artifact",
+			archiveAsArtifact: true,
+			uploadTasks:       []uploaders.UploadTask{artifactTask},
+			want:              []uploaders.ArtifactURLs{artifactURLs},
+		},
+		{
+			name:             "Intermediate file",
+			intermediateMeta: &deployment.IntermediateFileMetaData{EnvKey: "FILE_PATH"},
+			uploadTasks:      []uploaders.UploadTask{intermediateTask},
+			want:             []uploaders.ArtifactURLs{intermediateURLs},
+		},
+		{
+			name:              "Build Artifact and intermediate file only returns the Build Artifact's URLs",
+			archiveAsArtifact: true,
+			intermediateMeta:  &deployment.IntermediateFileMetaData{EnvKey: "FILE_PATH"},
+			uploadTasks:       []uploaders.UploadTask{artifactTask, intermediateTask},
+			want:              []uploaders.ArtifactURLs{artifactURLs},
+		},
+		{
+			name:              "Build Artifact and intermediate file with the legacy single upload task",
+			archiveAsArtifact: true,
+			intermediateMeta:  &deployment.IntermediateFileMetaData{EnvKey: "FILE_PATH"},
+			uploadTasks:       []uploaders.UploadTask{intermediateTask},
+			want:              []uploaders.ArtifactURLs{intermediateURLs},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := mocks.NewArtifactClient(t)
+			client.On("CreateArtifact", mock.Anything, buildURL, token, mock.Anything, "file", "", tt.archiveAsArtifact, tt.intermediateMeta).Return(tt.uploadTasks, nil)
+			var uploadURLs []string
+			for _, task := range tt.uploadTasks {
+				urls := artifactURLs
+				if task.IsIntermediate {
+					urls = intermediateURLs
+				}
+				uploadURLs = append(uploadURLs, task.URL)
+				client.On("FinishArtifact", mock.Anything, buildURL, token, task.Identifier(), (*uploaders.AppDeploymentMetaData)(nil), uploaders.Checksums{}).Return(urls, nil).Once()
+			}
+			client.On("UploadArtifacts", mock.Anything, uploadURLs, mock.Anything, "").Return(make([]uploaders.TransferDetails, len(uploadURLs)), make([]error, len(uploadURLs))).Once()
+
+			item := deployment.DeployableItem{
+				Path:                 createFile(t),
+				ArchiveAsArtifact:    tt.archiveAsArtifact,
+				IntermediateFileMeta: tt.intermediateMeta,
+			}
+			got, err := newUploader(t, client).DeployFile(context.Background(), item, buildURL, token)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDeployFile_failedUpload(t *testing.T) {
+	abortErr := errors.New("service unavailable")
+
+	tests := []struct {
+		name   string
+		upload fileUpload
+		// cancelled cancels the deployment before it starts.
+		cancelled bool
+		// abort is whether the record is aborted, with abortErr as the result.
+		abort       bool
+		abortErr    error
+		wantErr     string
+		wantOrphans bool
+	}{
+		{
+			name:    "Upload failure aborts the record",
+			upload:  fileUpload{uploadErr: errors.New("connection reset")},
+			abort:   true,
+			wantErr: "connection reset",
+		},
+		{
+			name:        "Cleanup failure leaves an orphaned record, even if the deployment was cancelled",
+			upload:      fileUpload{finishErr: errors.New("bad gateway")},
+			cancelled:   true,
+			abort:       true,
+			abortErr:    abortErr,
+			wantErr:     "bad gateway",
+			wantOrphans: true,
+		},
+		{
+			name:     "Record which the backend can't clean up is not an orphan",
+			upload:   fileUpload{finishErr: errors.New("bad gateway")},
+			abort:    true,
+			abortErr: fmt.Errorf("%w, status code: 404", uploaders.ErrAbortUnsupported),
+			wantErr:  "bad gateway",
+		},
+		{
+			name:    "Record of a possibly finished upload is not aborted",
+			upload:  fileUpload{finishErr: uploaders.FinishOutcomeUnknownError{ArtifactID: "1", Err: errors.New("timeout awaiting response headers")}},
+			wantErr: "artifact 1 might have been finished: timeout awaiting response headers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFileUploadClient(t, tt.upload)
+			if tt.abort {
+				client.On("AbortArtifact", mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil }), buildURL, token, "1").Return(tt.abortErr).Once()
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+
+			item := deployment.DeployableItem{
+				Path:              createFile(t),
+				ArchiveAsArtifact: true,
+			}
+			uploader := newUploader(t, client)
+			_, err := uploader.DeployFile(ctx, item, buildURL, token)
+			require.ErrorContains(t, err, tt.wantErr)
+			if !tt.abort {
+				client.AssertNotCalled(t, "AbortArtifact", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if tt.wantOrphans {
+				require.Equal(t, []uploaders.OrphanedArtifact{{ArtifactID: "1", Path: item.Path, Err: tt.abortErr}}, uploader.OrphanedArtifacts())
+			} else {
+				require.Empty(t, uploader.OrphanedArtifacts())
+			}
+		})
+	}
+}
+
+func TestDeployFile_failurePerUploadTask(t *testing.T) {
+	intermediateMeta := &deployment.IntermediateFileMetaData{EnvKey: "FILE_PATH"}
+	uploadTasks := []uploaders.UploadTask{
+		{ID: 1, URL: "https://storage/artifact"},
+		{ID: 2, URL: "https://storage/intermediate", IsIntermediate: true},
+	}
+
+	client := mocks.NewArtifactClient(t)
+	client.On("CreateArtifact", mock.Anything, buildURL, token, mock.Anything, "file", "", true, intermediateMeta).Return(uploadTasks, nil)
+	client.On("UploadArtifacts", mock.Anything, []string{"https://storage/artifact", "https://storage/intermediate"}, mock.Anything, "").
+		Return([]uploaders.TransferDetails{{}, {}}, []error{nil, errors.New("connection reset")})
+	client.On("FinishArtifact", mock.Anything, buildURL, token, "1", (*uploaders.AppDeploymentMetaData)(nil), uploaders.Checksums{}).Return(uploaders.ArtifactURLs{}, nil).Once()
+	client.On("AbortArtifact", mock.Anything, buildURL, token, "2").Return(nil).Once()
+
+	item := deployment.DeployableItem{
+		Path:                 createFile(t),
+		ArchiveAsArtifact:    true,
+		IntermediateFileMeta: intermediateMeta,
+	}
+	uploader := newUploader(t, client)
+	_, err := uploader.DeployFile(context.Background(), item, buildURL, token)
+	require.ErrorContains(t, err, "for upload task 2: connection reset")
+
+	report, ok := uploader.Report(item.Path)
+	require.True(t, ok)
+	require.Len(t, report.Transfers, 2)
+	require.Len(t, report.ArtifactURLs, 1)
+	// The snapshot of the file is uploaded, but it is removed after the upload, so the deployed file is reported.
+	require.Equal(t, item.Path, report.UploadedPath)
+	require.Equal(t, "hard link", report.SnapshotMethod)
+}
+
+func TestDeployFile_customMetadata(t *testing.T) {
+	customMetadata := map[string]interface{}{"release_channel": "beta"}
+
+	client := newFileUploadClient(t, fileUpload{meta: &uploaders.AppDeploymentMetaData{CustomMetadata: customMetadata}})
+
+	item := deployment.DeployableItem{
+		Path:              createFile(t),
+		ArchiveAsArtifact: true,
+		CustomMetadata:    customMetadata,
+	}
+	_, err := newUploader(t, client).DeployFile(context.Background(), item, buildURL, token)
+	require.NoError(t, err)
+}
+
+func TestDeployFile_generatedFrom(t *testing.T) {
+	client := newFileUploadClient(t, fileUpload{meta: &uploaders.AppDeploymentMetaData{GeneratedFrom: "app-release.aab"}})
+
+	item := deployment.DeployableItem{
+		Path:              createFile(t),
+		ArchiveAsArtifact: true,
+		GeneratedFrom:     "/bitrise/src/app/build/outputs/bundle/release/app-release.aab",
+	}
+	_, err := newUploader(t, client).DeployFile(context.Background(), item, buildURL, token)
+	require.NoError(t, err)
+}
+
+func TestDeployFile_dryRun(t *testing.T) {
+	t.Setenv("ANALYTICS_DISABLED", "true")
+	uploader := uploaders.NewDryRun(log.NewLogger(), fileutil.NewFileManager(), nil, nil, nil, nil)
+
+	item := deployment.DeployableItem{
+		Path:                 createFile(t),
+		ArchiveAsArtifact:    true,
+		IntermediateFileMeta: &deployment.IntermediateFileMetaData{EnvKey: "FILE_PATH"},
+	}
+	urls, err := uploader.DeployFile(context.Background(), item, buildURL, token)
+	require.NoError(t, err)
+	require.Empty(t, urls)
+
+	require.Equal(t, []uploaders.PlannedUpload{
+		{
+			Path:                 item.Path,
+			FileSize:             int64(len("content")),
+			ArtifactType:         "file",
+			ArchiveAsArtifact:    true,
+			IntermediateFileMeta: item.IntermediateFileMeta,
+		},
+	}, uploader.Plan())
+	require.Equal(t, item.Path+" (7B) as file, Build Artifact and Pipeline intermediate file (FILE_PATH)", uploader.Plan()[0].String())
+}
+
+func TestDeployMacOS_unparsableArtifactIsDeployedAsFile(t *testing.T) {
+	urls := []uploaders.ArtifactURLs{{PermanentDownloadURL: "https://app.bitrise.io/artifacts/1/download"}}
+
+	tests := []struct {
+		name   string
+		file   string
+		deploy func(u *uploaders.Uploader, item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error)
+	}{
+		{
+			name: "xcarchive",
+			file: "Example.xcarchive.zip",
+			deploy: func(u *uploaders.Uploader, item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+				return u.DeployMacOSXcarchive(context.Background(), item, buildURL, token)
+			},
+		},
+		{
+			name: "app",
+			file: "Example.app.zip",
+			deploy: func(u *uploaders.Uploader, item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+				return u.DeployMacOSApp(context.Background(), item, buildURL, token, "", "", "", false)
+			},
+		},
+		{
+			name: "pkg",
+			file: "Example.pkg",
+			deploy: func(u *uploaders.Uploader, item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+				return u.DeployPKG(context.Background(), item, buildURL, token, "", "", "", false)
+			},
+		},
+		{
+			name: "dmg",
+			file: "Example.dmg",
+			deploy: func(u *uploaders.Uploader, item deployment.DeployableItem) ([]uploaders.ArtifactURLs, error) {
+				return u.DeployDMG(context.Background(), item, buildURL, token, "", "", "", false)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ANALYTICS_DISABLED", "true")
+			client := newFileUploadClient(t, fileUpload{urls: urls[0]})
+
+			pth := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(pth, []byte("content"), 0600))
+
+			logger := log.NewLogger()
+			fileManager := fileutil.NewFileManager()
+			uploader := uploaders.New(logger, fileManager, nil, nil, nil, macosparser.New(logger, fileManager), client)
+
+			got, err := tt.deploy(uploader, deployment.DeployableItem{Path: pth, ArchiveAsArtifact: true})
+			require.NoError(t, err)
+			require.Equal(t, urls, got)
+		})
+	}
+}
+
+func TestDeployDebugSymbols(t *testing.T) {
+	symbolsInfo := debugsymbols.Metadata{
+		SymbolsType:      debugsymbols.ProguardMapping,
+		ParentArtifactID: "1",
+		ParentFileName:   "app-release.aab",
+	}
+	wantSymbolsInfo := symbolsInfo
+	wantSymbolsInfo.FileSizeBytes = int64(len("content"))
+
+	client := mocks.NewArtifactClient(t)
+	client.On("CreateArtifact", mock.Anything, buildURL, token, mock.Anything, "debug-symbols", "text/plain", true, (*deployment.IntermediateFileMetaData)(nil)).Return([]uploaders.UploadTask{{ID: 2, URL: "https://storage/artifact"}}, nil)
+	client.On("UploadArtifacts", mock.Anything, []string{"https://storage/artifact"}, mock.Anything, "text/plain").Return([]uploaders.TransferDetails{{}}, []error{nil})
+	client.On("FinishArtifact", mock.Anything, buildURL, token, "2", &uploaders.AppDeploymentMetaData{DebugSymbolsInfo: &wantSymbolsInfo}, uploaders.Checksums{}).Return(uploaders.ArtifactURLs{ArtifactID: "2"}, nil)
+
+	item := deployment.DeployableItem{
+		Path:              createFile(t),
+		ArchiveAsArtifact: true,
+	}
+	got, err := newUploader(t, client).DeployDebugSymbols(context.Background(), item, symbolsInfo, buildURL, token)
+	require.NoError(t, err)
+	require.Equal(t, []uploaders.ArtifactURLs{{ArtifactID: "2"}}, got)
+}
+
+// expiringStorage is a local artifact API and storage, whose signed upload URLs expire after their validity:
+// the URL of the created artifact is valid for createValidity, the renewed URLs are valid for renewValidity.
+type expiringStorage struct {
+	server         *httptest.Server
+	createValidity time.Duration
+	renewValidity  time.Duration
+	// renewUnsupported makes the storage behave like a backend without the renewal endpoint.
+	renewUnsupported bool
+
+	lock     sync.Mutex
+	creates  int
+	renewals int
+	finishes int
+	aborts   int
+	content  string
+}
+
+func newExpiringStorage(t *testing.T, createValidity, renewValidity time.Duration) *expiringStorage {
+	storage := &expiringStorage{createValidity: createValidity, renewValidity: renewValidity}
+	storage.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storage.lock.Lock()
+		defer storage.lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/build/artifacts.json":
+			storage.creates++
+			_, _ = fmt.Fprintf(w, `[{"id":1,"upload_url":%q}]`, storage.signedURL(storage.createValidity))
+		case r.Method == http.MethodPost && r.URL.Path == "/build/artifacts/1/upload_url.json":
+			storage.renewals++
+			if storage.renewUnsupported {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"upload_url":%q}`, storage.signedURL(storage.renewValidity))
+		case r.Method == http.MethodPost && r.URL.Path == "/build/artifacts/1/finish_upload.json":
+			storage.finishes++
+			_, _ = io.WriteString(w, `{"permanent_download_url":"https://app.bitrise.io/artifacts/1/download"}`)
+		case r.Method == http.MethodPost && r.URL.Path == "/build/artifacts/1/abort_upload.json":
+			storage.aborts++
+		case r.Method == http.MethodPut && r.URL.Path == "/storage/1":
+			expiry, err := strconv.Atoi(r.URL.Query().Get("expiry"))
+			require.NoError(t, err)
+			if time.Now().Unix() >= int64(expiry) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Request has expired</Message></Error>`)
+				return
+			}
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			storage.content = string(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(storage.server.Close)
+
+	return storage
+}
+
+func (s *expiringStorage) signedURL(validity time.Duration) string {
+	return fmt.Sprintf("%s/storage/1?expiry=%d", s.server.URL, time.Now().Add(validity).Unix())
+}
+
+func TestDeployFile_expiredUploadURL(t *testing.T) {
+	client := uploaders.NewArtifactClient(&http.Client{}, retrypolicy.Policy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+	t.Run("renews the upload URL of the same artifact", func(t *testing.T) {
+		// The URL of the created artifact is already expired when it is used.
+		storage := newExpiringStorage(t, -time.Hour, time.Hour)
+
+		item := deployment.DeployableItem{Path: createFile(t), ArchiveAsArtifact: true}
+		got, err := newUploader(t, client).DeployFile(context.Background(), item, storage.server.URL+"/build", token)
+		require.NoError(t, err)
+		require.Equal(t, "https://app.bitrise.io/artifacts/1/download", got[0].PermanentDownloadURL)
+		require.Equal(t, 1, storage.creates)
+		require.Equal(t, 1, storage.renewals)
+		require.Equal(t, 1, storage.finishes)
+		require.Zero(t, storage.aborts)
+		require.Equal(t, "content", storage.content)
+	})
+
+	t.Run("fails after the renewed URLs expire too", func(t *testing.T) {
+		storage := newExpiringStorage(t, -time.Hour, -time.Hour)
+
+		item := deployment.DeployableItem{Path: createFile(t), ArchiveAsArtifact: true}
+		_, err := newUploader(t, client).DeployFile(context.Background(), item, storage.server.URL+"/build", token)
+		var expiredErr *uploaders.UploadURLExpiredError
+		require.ErrorAs(t, err, &expiredErr)
+		require.Equal(t, 1, storage.creates)
+		require.Equal(t, 2, storage.renewals)
+		require.Zero(t, storage.finishes)
+		require.Equal(t, 1, storage.aborts)
+	})
+
+	t.Run("fails fast without the renewal endpoint", func(t *testing.T) {
+		storage := newExpiringStorage(t, -time.Hour, time.Hour)
+		storage.renewUnsupported = true
+
+		uploader := newUploader(t, client)
+		for i := 0; i < 2; i++ {
+			item := deployment.DeployableItem{Path: createFile(t), ArchiveAsArtifact: true}
+			_, err := uploader.DeployFile(context.Background(), item, storage.server.URL+"/build", token)
+			var expiredErr *uploaders.UploadURLExpiredError
+			require.ErrorAs(t, err, &expiredErr)
+			require.NotErrorIs(t, err, uploaders.ErrRenewalUnsupported)
+		}
+		// The missing endpoint is not requested again.
+		require.Equal(t, 2, storage.creates)
+		require.Equal(t, 1, storage.renewals)
+		require.Zero(t, storage.finishes)
+		require.Equal(t, 2, storage.aborts)
+	})
+}
+
+// fileUpload is the mocked deployment of a Build Artifact file with a single upload task (ID 1):
+// the upload fails with uploadErr, or the artifact is finished with meta and the finish returns urls and finishErr.
+type fileUpload struct {
+	uploadErr error
+	meta      *uploaders.AppDeploymentMetaData
+	urls      uploaders.ArtifactURLs
+	finishErr error
+}
+
+func newFileUploadClient(t *testing.T, upload fileUpload) *mocks.ArtifactClient {
+	client := mocks.NewArtifactClient(t)
+	client.On("CreateArtifact", mock.Anything, buildURL, token, mock.Anything, "file", "", true, (*deployment.IntermediateFileMetaData)(nil)).Return([]uploaders.UploadTask{{ID: 1, URL: "https://storage/artifact"}}, nil)
+	client.On("UploadArtifacts", mock.Anything, []string{"https://storage/artifact"}, mock.Anything, "").Return([]uploaders.TransferDetails{{}}, []error{upload.uploadErr})
+	if upload.uploadErr == nil {
+		client.On("FinishArtifact", mock.Anything, buildURL, token, "1", upload.meta, uploaders.Checksums{}).Return(upload.urls, upload.finishErr).Once()
+	}
+	return client
+}
+
+func newUploader(t *testing.T, client uploaders.ArtifactClient) *uploaders.Uploader {
+	t.Setenv("ANALYTICS_DISABLED", "true")
+
+	return uploaders.New(log.NewLogger(), fileutil.NewFileManager(), nil, nil, nil, nil, client)
+}
+
+func createFile(t *testing.T) string {
+	pth := filepath.Join(t.TempDir(), "file.txt")
+	require.NoError(t, os.WriteFile(pth, []byte("content"), 0600))
+	return pth
+}
@@ -1,23 +1,25 @@
 package uploaders
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
 	"github.com/bitrise-io/go-xcode/v2/metaparser"
 	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/deployment"
+	"github.com/bitrise-steplib/steps-deploy-to-bitrise-io/failure"
 )
 
 // DeployXcarchive ...
-func (u *Uploader) DeployXcarchive(item deployment.DeployableItem, buildURL, token string) ([]ArtifactURLs, error) {
+func (u *Uploader) DeployXcarchive(ctx context.Context, item deployment.DeployableItem, buildURL, token string) ([]ArtifactURLs, error) {
 	pth := item.Path
 
 	xcarchiveInfo, err := u.iosParser.ParseXCArchiveData(pth)
 	if err != nil {
 		if errors.Is(err, metaparser.MacOSProjectIsNotSupported) {
-			return nil, fmt.Errorf("macOS archive deployment is not supported: %w", err)
+			return nil, failure.New(failure.Parse, fmt.Errorf("macOS archive deployment is not supported: %w", err))
 		} else {
-			return nil, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err)
+			return nil, failure.New(failure.Parse, fmt.Errorf("failed to parse deployment info for %s: %w", pth, err))
 		}
 	}
 
@@ -35,7 +37,7 @@ func (u *Uploader) DeployXcarchive(item deployment.DeployableItem, buildURL, tok
 		IsEnablePublicPage:     false,
 	}
 
-	urLs, err := u.upload(buildURL, token, artifact, "ios-xcarchive", "", &item, &buildArtifactMeta)
+	urLs, err := u.upload(ctx, buildURL, token, artifact, "ios-xcarchive", "", &item, &buildArtifactMeta)
 	if err != nil {
 		return nil, fmt.Errorf("failed xcarchive deploy: %w", err)
 	}